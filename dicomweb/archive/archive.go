@@ -0,0 +1,242 @@
+// Package archive implements a DICOM archive on the filesystem, holding DICOM
+// Part 10 instances in a study/series/instance directory layout. An Archive is
+// a server.Storage and can be served over DICOMweb with server.NewHandler.
+//
+// The archive indexes the instances by Study, Series and SOP Instance UID and
+// by the attributes QIDO-RS searches match, so that searches do not read the
+// instance files.
+package archive
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/toastcheng/dicomweb-go/dicomweb"
+	"github.com/toastcheng/dicomweb-go/dicomweb/dicom"
+	"github.com/toastcheng/dicomweb-go/dicomweb/server"
+	"github.com/toastcheng/dicomweb-go/dicomweb/uid"
+)
+
+// ext is the extension of the instance files.
+const ext = ".dcm"
+
+// Archive is a DICOM archive rooted at a directory. It is safe for concurrent use.
+type Archive struct {
+	dir string
+
+	mu    sync.RWMutex
+	index *index
+}
+
+// Open opens the archive rooted at dir, creating the directory if needed, and
+// indexes the instances it holds.
+func Open(dir string) (*Archive, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	a := &Archive{dir: dir, index: newIndex()}
+	files, err := filepath.Glob(a.path("*", "*", "*"))
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		ds, err := dicom.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		a.index.add(f, ds)
+	}
+	return a, nil
+}
+
+// Dir returns the root directory of the archive.
+func (a *Archive) Dir() string {
+	return a.dir
+}
+
+// Len returns the number of instances in the archive.
+func (a *Archive) Len() int {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return len(a.index.bySOP)
+}
+
+// path returns the path of the instance file, or of the study or series
+// directory when the following UIDs are empty.
+func (a *Archive) path(study, series, sop string) string {
+	p := filepath.Join(a.dir, study)
+	if series != "" {
+		p = filepath.Join(p, series)
+	}
+	if sop != "" {
+		p = filepath.Join(p, sop+ext)
+	}
+	return p
+}
+
+// Search answers the QIDO-RS request from the index of the archive.
+func (a *Archive) Search(ctx context.Context, req dicomweb.QIDORequest) ([]*dicom.Dataset, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	instances := []*dicom.Dataset{}
+	for _, r := range a.index.lookup(req.StudyInstanceUID, req.SeriesInstanceUID, req.SOPInstanceUID) {
+		instances = append(instances, r.ds)
+	}
+	return server.Search(req, instances), nil
+}
+
+// Query answers the QIDO request like Client.Query does, from the archive
+// instead of a DICOMweb server.
+func (a *Archive) Query(req dicomweb.QIDORequest) ([]dicomweb.QIDOResponse, error) {
+	datasets, err := a.Search(context.Background(), req)
+	if err != nil {
+		return nil, err
+	}
+	result := []dicomweb.QIDOResponse{}
+	for _, ds := range datasets {
+		b, err := json.Marshal(ds)
+		if err != nil {
+			return nil, err
+		}
+		resp := dicomweb.QIDOResponse{}
+		if err := json.Unmarshal(b, &resp); err != nil {
+			return nil, err
+		}
+		result = append(result, resp)
+	}
+	return result, nil
+}
+
+// Retrieve returns the instances of the study, series or instance of the request.
+func (a *Archive) Retrieve(ctx context.Context, req dicomweb.WADORequest) ([][]byte, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	records := a.index.lookup(req.StudyInstanceUID, req.SeriesInstanceUID, req.SOPInstanceUID)
+	if len(records) == 0 {
+		return nil, server.ErrNotFound
+	}
+	instances := [][]byte{}
+	for _, r := range records {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		data, err := ioutil.ReadFile(r.path)
+		if err != nil {
+			return nil, err
+		}
+		instances = append(instances, data)
+	}
+	return instances, nil
+}
+
+// Store writes the instances of the request to the archive, replacing the
+// instances of the same UIDs.
+func (a *Archive) Store(ctx context.Context, req dicomweb.STOWRequest) ([]error, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	errs := make([]error, len(req.Parts))
+	for i, data := range req.Parts {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		errs[i] = a.write(req.StudyInstanceUID, data)
+	}
+	return errs, nil
+}
+
+// Ingest writes the DICOM Part 10 instances, such as the result of
+// Client.Retrieve, to the archive. It stops at the first instance which cannot
+// be stored.
+func (a *Archive) Ingest(instances ...[]byte) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, data := range instances {
+		if err := a.write("", data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// IngestDir walks the directory and copies the DICOM Part 10 instances it holds
+// to the archive, skipping the files which are not instances, such as DICOMDIR.
+// It returns the number of ingested instances.
+func (a *Archive) IngestDir(dir string) (int, error) {
+	n := 0
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if _, err := server.CheckInstance("", data); err != nil {
+			return nil
+		}
+		if err := a.Ingest(data); err != nil {
+			return err
+		}
+		n++
+		return nil
+	})
+	return n, err
+}
+
+// write checks the instance, writes it to its file and indexes it.
+func (a *Archive) write(study string, data []byte) error {
+	ds, err := server.CheckInstance(study, data)
+	if err != nil {
+		return err
+	}
+	study = ds.String(dicom.StudyInstanceUID)
+	series := ds.String(dicom.SeriesInstanceUID)
+	sop := ds.String(dicom.SOPInstanceUID)
+	for _, u := range []string{study, series, sop} {
+		if !uid.Valid(u) {
+			return &server.StoreError{Reason: server.FailureProcessing, Err: errors.New("invalid UID " + u)}
+		}
+	}
+
+	dir := a.path(study, series, "")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	// write to a temporary file first so that readers never see a partial instance.
+	tmp, err := ioutil.TempFile(dir, ".tmp-")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	path := a.path(study, series, sop)
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	if old, ok := a.index.bySOP[sop]; ok && old.path != path {
+		// the instance moved to another study or series.
+		os.Remove(old.path)
+	}
+	a.index.add(path, ds)
+	return nil
+}
+
+// sortRecords sorts the records by path, that is by study, series and instance.
+func sortRecords(records []*record) {
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].path < records[j].path
+	})
+}
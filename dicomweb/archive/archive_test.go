@@ -0,0 +1,159 @@
+package archive_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/toastcheng/dicomweb-go/dicomweb"
+	"github.com/toastcheng/dicomweb-go/dicomweb/archive"
+	"github.com/toastcheng/dicomweb-go/dicomweb/dicomwebtest"
+	"github.com/toastcheng/dicomweb-go/dicomweb/server"
+)
+
+func newTestArchive(t *testing.T) (*archive.Archive, *dicomweb.Client, func()) {
+	dir, err := ioutil.TempDir("", "archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := archive.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(server.NewHandler(a))
+	c := dicomweb.NewClient(dicomweb.ClientOption{
+		QIDOEndpoint: ts.URL,
+		WADOEndpoint: ts.URL,
+		STOWEndpoint: ts.URL,
+	})
+	return a, c, func() {
+		ts.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestArchive(t *testing.T) {
+	a, c, cleanup := newTestArchive(t)
+	defer cleanup()
+
+	parts := [][]byte{}
+	for _, uid := range []string{"1.2.1.1.1", "1.2.1.1.2"} {
+		parts = append(parts, dicomwebtest.NewInstance(dicomwebtest.Instance{
+			StudyInstanceUID:  "1.2.1",
+			SeriesInstanceUID: "1.2.1.1",
+			SOPInstanceUID:    uid,
+			PatientID:         "PAT-1",
+			Modality:          "CT",
+		}))
+	}
+	_, err := c.Store(dicomweb.STOWRequest{Parts: parts})
+	assert.NoError(t, err)
+	assert.FileExists(t, filepath.Join(a.Dir(), "1.2.1", "1.2.1.1", "1.2.1.1.2.dcm"))
+
+	resp, err := c.Query(dicomweb.QIDORequest{Type: dicomweb.Study, PatientID: "PAT-1"})
+	assert.NoError(t, err)
+	if assert.Len(t, resp, 1) {
+		assert.Equal(t, []interface{}{float64(2)}, resp[0].NumberOfStudyRelatedInstances.Value)
+	}
+
+	retrieved, err := c.Retrieve(dicomweb.WADORequest{Type: dicomweb.StudyRaw, StudyInstanceUID: "1.2.1"})
+	assert.NoError(t, err)
+	assert.Equal(t, parts, retrieved)
+
+	// a list of UIDs matches any of them.
+	resp, err = a.Query(dicomweb.QIDORequest{Type: dicomweb.Instance, SOPInstanceUID: "1.2.1.1.2,1.2.1.1.1,1.2.9"})
+	assert.NoError(t, err)
+	assert.Len(t, resp, 2)
+	resp, err = a.Query(dicomweb.QIDORequest{Type: dicomweb.Study, StudyInstanceUID: "1.2.9\\1.2.1"})
+	assert.NoError(t, err)
+	assert.Len(t, resp, 1)
+
+	// the client refuses the malformed UID, and the archive does not resolve it.
+	_, err = c.Retrieve(dicomweb.WADORequest{Type: dicomweb.StudyRaw, StudyInstanceUID: ".."})
+	assert.IsType(t, dicomweb.ValidationErrors{}, err)
+	_, err = a.Retrieve(context.Background(), dicomweb.WADORequest{Type: dicomweb.StudyRaw, StudyInstanceUID: ".."})
+	assert.Equal(t, server.ErrNotFound, err)
+}
+
+func TestArchiveStoreInvalidUID(t *testing.T) {
+	a, c, cleanup := newTestArchive(t)
+	defer cleanup()
+
+	resp, err := c.Store(dicomweb.STOWRequest{Parts: [][]byte{
+		dicomwebtest.NewInstance(dicomwebtest.Instance{
+			StudyInstanceUID:  "../1.2",
+			SeriesInstanceUID: "1.2.1",
+			SOPInstanceUID:    "1.2.1.1",
+		}),
+	}})
+	assert.NoError(t, err)
+	assert.Contains(t, resp, "00081198")
+
+	files, _ := ioutil.ReadDir(a.Dir())
+	assert.Len(t, files, 0)
+}
+
+func TestArchiveIngest(t *testing.T) {
+	a, _, cleanup := newTestArchive(t)
+	defer cleanup()
+
+	src, err := ioutil.TempDir("", "ingest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(src)
+	os.MkdirAll(filepath.Join(src, "DICOM", "ST1"), 0755)
+	ioutil.WriteFile(filepath.Join(src, "README"), []byte("not dicom"), 0644)
+	for i, uid := range []string{"1.2.1.1.1", "1.2.1.2.1"} {
+		data := dicomwebtest.NewInstance(dicomwebtest.Instance{
+			StudyInstanceUID:  "1.2.1",
+			SeriesInstanceUID: uid[:7],
+			SOPInstanceUID:    uid,
+			Modality:          []string{"CT", "SR"}[i],
+			StudyDate:         "20200101",
+		})
+		ioutil.WriteFile(filepath.Join(src, "DICOM", "ST1", uid), data, 0644)
+	}
+
+	n, err := a.IngestDir(src)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	// instances of Retrieve results move when their series changes.
+	err = a.Ingest(dicomwebtest.NewInstance(dicomwebtest.Instance{
+		StudyInstanceUID:  "1.2.1",
+		SeriesInstanceUID: "1.2.1.3",
+		SOPInstanceUID:    "1.2.1.2.1",
+		Modality:          "MR",
+	}))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, a.Len())
+	_, err = os.Stat(filepath.Join(a.Dir(), "1.2.1", "1.2.1.2", "1.2.1.2.1.dcm"))
+	assert.True(t, os.IsNotExist(err))
+
+	// a reopened archive indexes the instances from disk.
+	reopened, err := archive.Open(a.Dir())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, reopened.Len())
+
+	resp, err := reopened.Query(dicomweb.QIDORequest{Type: dicomweb.Series, StudyInstanceUID: "1.2.1"})
+	assert.NoError(t, err)
+	if assert.Len(t, resp, 2) {
+		assert.Equal(t, []interface{}{"CT"}, resp[0].Modality.Value)
+		assert.Equal(t, []interface{}{"MR"}, resp[1].Modality.Value)
+	}
+
+	resp, err = reopened.Query(dicomweb.QIDORequest{Type: dicomweb.Study, StudyDate: "20200101"})
+	assert.NoError(t, err)
+	if assert.Len(t, resp, 1) {
+		assert.Equal(t, []interface{}{"CT", "MR"}, resp[0].ModalitiesInStudy.Value)
+	}
+
+	resp, err = reopened.Query(dicomweb.QIDORequest{Type: dicomweb.Instance, SOPInstanceUID: "1.2.1.1.1"})
+	assert.NoError(t, err)
+	assert.Len(t, resp, 1)
+}
@@ -0,0 +1,136 @@
+package archive
+
+import (
+	"strings"
+
+	"github.com/toastcheng/dicomweb-go/dicomweb/dicom"
+	"github.com/toastcheng/dicomweb-go/dicomweb/server"
+)
+
+// record is an indexed instance.
+type record struct {
+	path string
+	// ds holds the attributes of the instance searches use only.
+	ds *dicom.Dataset
+}
+
+func (r *record) study() string {
+	return r.ds.String(dicom.StudyInstanceUID)
+}
+
+func (r *record) series() string {
+	return r.ds.String(dicom.SeriesInstanceUID)
+}
+
+func (r *record) sop() string {
+	return r.ds.String(dicom.SOPInstanceUID)
+}
+
+// index holds the records of the instances by UID, each list sorted by path.
+type index struct {
+	attributes []dicom.Tag
+	byStudy    map[string][]*record
+	bySeries   map[string][]*record
+	bySOP      map[string]*record
+}
+
+func newIndex() *index {
+	return &index{
+		attributes: server.SearchAttributes(),
+		byStudy:    map[string][]*record{},
+		bySeries:   map[string][]*record{},
+		bySOP:      map[string]*record{},
+	}
+}
+
+// add indexes the instance file, replacing the record of the same SOP Instance UID.
+func (x *index) add(path string, ds *dicom.Dataset) {
+	indexed := &dicom.Dataset{}
+	for _, t := range x.attributes {
+		if e := ds.Get(t); e != nil {
+			indexed.Set(e.Copy())
+		}
+	}
+	r := &record{path: path, ds: indexed}
+	if old, ok := x.bySOP[r.sop()]; ok {
+		x.remove(old)
+	}
+
+	x.bySOP[r.sop()] = r
+	x.byStudy[r.study()] = append(x.byStudy[r.study()], r)
+	sortRecords(x.byStudy[r.study()])
+	x.bySeries[r.series()] = append(x.bySeries[r.series()], r)
+	sortRecords(x.bySeries[r.series()])
+}
+
+func (x *index) remove(r *record) {
+	delete(x.bySOP, r.sop())
+	x.byStudy[r.study()] = without(x.byStudy[r.study()], r)
+	if len(x.byStudy[r.study()]) == 0 {
+		delete(x.byStudy, r.study())
+	}
+	x.bySeries[r.series()] = without(x.bySeries[r.series()], r)
+	if len(x.bySeries[r.series()]) == 0 {
+		delete(x.bySeries, r.series())
+	}
+}
+
+func without(records []*record, r *record) []*record {
+	result := []*record{}
+	for _, v := range records {
+		if v != r {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// lookup returns the records of the study, series and instance, an empty UID
+// matching any. Each UID may be a list of UIDs separated by commas or
+// backslashes, as in a QIDO-RS query, matching any of them.
+func (x *index) lookup(study, series, sop string) []*record {
+	studies, seriesSet, sops := uidSet(study), uidSet(series), uidSet(sop)
+	var candidates []*record
+	switch {
+	case sops != nil:
+		for u := range sops {
+			if r, ok := x.bySOP[u]; ok {
+				candidates = append(candidates, r)
+			}
+		}
+	case seriesSet != nil:
+		for u := range seriesSet {
+			candidates = append(candidates, x.bySeries[u]...)
+		}
+	case studies != nil:
+		for u := range studies {
+			candidates = append(candidates, x.byStudy[u]...)
+		}
+	default:
+		for _, r := range x.bySOP {
+			candidates = append(candidates, r)
+		}
+	}
+	sortRecords(candidates)
+
+	result := []*record{}
+	for _, r := range candidates {
+		if (studies == nil || studies[r.study()]) && (seriesSet == nil || seriesSet[r.series()]) {
+			result = append(result, r)
+		}
+	}
+	return result
+}
+
+// uidSet returns the UIDs of the list, or nil if it is empty.
+func uidSet(list string) map[string]bool {
+	uids := strings.FieldsFunc(list, func(r rune) bool { return r == ',' || r == '\\' })
+	if len(uids) == 0 {
+		return nil
+	}
+	set := map[string]bool{}
+	for _, u := range uids {
+		set[u] = true
+	}
+	return set
+}
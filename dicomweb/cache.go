@@ -0,0 +1,399 @@
+package dicomweb
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheOption specifies the on-disk cache of WADO-RS responses.
+type CacheOption struct {
+	// Dir directory the responses are cached in. Created if needed
+	Dir string
+	// MaxSize maximum total size in bytes of the cached responses, the least recently used being evicted first. Unlimited otherwise
+	MaxSize int64
+	// MaxAge age until which a cached response is served without contacting the server. Past it, the response is
+	// revalidated with If-None-Match or If-Modified-Since if the server sent an ETag or Last-Modified, and fetched
+	// again otherwise. Never expires otherwise, as DICOM instances do not change once given a UID
+	MaxAge time.Duration
+}
+
+// WithCache configures the client to cache the responses of Retrieve on disk.
+func (c *Client) WithCache(option CacheOption) (*Client, error) {
+	mw, err := Cache(option)
+	if err != nil {
+		return nil, err
+	}
+	return c.WithMiddleware(mw), nil
+}
+
+// Cache returns a middleware caching the successful WADO-RS responses on disk,
+// keyed by the resource, i.e. the study, series, instance or frame UIDs, by
+// the Accept header, which negotiates the transfer syntax, and by the
+// Authorization header, only a hash of which is stored. Other requests pass
+// through. Once a Delete succeeds, the responses cached for the study of the
+// deleted resource are evicted, whatever their MaxAge.
+func Cache(option CacheOption) (Middleware, error) {
+	c := &cache{
+		dir:     option.Dir,
+		maxSize: option.MaxSize,
+		maxAge:  option.MaxAge,
+		entries: map[string]*cacheEntry{},
+	}
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			op, ok := OperationFromContext(r.Context())
+			if !ok || op.Service != WADOService {
+				return next.RoundTrip(r)
+			}
+			switch r.Method {
+			case http.MethodGet:
+				return c.roundTrip(next, r)
+			case http.MethodDelete:
+				resp, err := next.RoundTrip(r)
+				if err == nil && resp.StatusCode/100 == 2 {
+					c.invalidate(r.URL, op.StudyInstanceUID)
+				}
+				return resp, err
+			}
+			return next.RoundTrip(r)
+		})
+	}, nil
+}
+
+type cache struct {
+	dir     string
+	maxSize int64
+	maxAge  time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+	size    int64
+}
+
+// cacheEntry is the cached response, stored along with its body as JSON.
+type cacheEntry struct {
+	URL        string      `json:"url"`
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header"`
+	Size       int64       `json:"size"`
+	Stored     time.Time   `json:"stored"`
+
+	// used is the last time the entry was served, kept as the modification
+	// time of the body file.
+	used time.Time
+}
+
+// cacheKey identifies the resource of the request and its representation, for
+// the principal of its Authorization header, so that a cache shared by several
+// principals does not serve one's responses to another.
+func cacheKey(r *http.Request) string {
+	key := r.URL.String() + "\n" + r.Header.Get("Accept")
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		key += "\n" + auth
+	}
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func (c *cache) metaPath(key string) string {
+	return filepath.Join(c.dir, key+".json")
+}
+
+func (c *cache) bodyPath(key string) string {
+	return filepath.Join(c.dir, key+".body")
+}
+
+// load indexes the responses cached by previous runs.
+func (c *cache) load() error {
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return err
+	}
+	files, err := filepath.Glob(filepath.Join(c.dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		key := strings.TrimSuffix(filepath.Base(f), ".json")
+		b, err := ioutil.ReadFile(f)
+		if err != nil {
+			return err
+		}
+		e := &cacheEntry{}
+		info, err := os.Stat(c.bodyPath(key))
+		if json.Unmarshal(b, e) != nil || err != nil || info.Size() != e.Size {
+			c.removeFiles(key)
+			continue
+		}
+		e.used = info.ModTime()
+		c.entries[key] = e
+		c.size += e.Size
+	}
+	c.evict()
+	return nil
+}
+
+func (c *cache) roundTrip(next http.RoundTripper, r *http.Request) (*http.Response, error) {
+	key := cacheKey(r)
+	// refresh updates the entry under the lock, so its fields are copied
+	// under it too.
+	var stored time.Time
+	var etag, modified string
+	c.mu.Lock()
+	e := c.entries[key]
+	if e != nil {
+		stored = e.Stored
+		etag, modified = e.Header.Get("ETag"), e.Header.Get("Last-Modified")
+	}
+	c.mu.Unlock()
+
+	if e != nil && (c.maxAge <= 0 || time.Since(stored) < c.maxAge) {
+		if resp := c.response(r, key, e); resp != nil {
+			return resp, nil
+		}
+		e = nil
+	}
+
+	req := r
+	if e != nil {
+		if etag != "" || modified != "" {
+			req = r.WithContext(r.Context())
+			req.Header = http.Header{}
+			for k, v := range r.Header {
+				req.Header[k] = v
+			}
+			if etag != "" {
+				req.Header.Set("If-None-Match", etag)
+			}
+			if modified != "" {
+				req.Header.Set("If-Modified-Since", modified)
+			}
+		}
+	}
+
+	resp, err := next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if e != nil && resp.StatusCode == http.StatusNotModified {
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+		c.refresh(key, e)
+		if cached := c.response(r, key, e); cached != nil {
+			return cached, nil
+		}
+		// the entry was evicted meanwhile.
+		return next.RoundTrip(r)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return resp, nil
+	}
+
+	tmp, err := ioutil.TempFile(c.dir, ".tmp-")
+	if err != nil {
+		// caching is best effort.
+		return resp, nil
+	}
+	resp.Body = &cachingBody{
+		ReadCloser: resp.Body,
+		cache:      c,
+		key:        key,
+		tmp:        tmp,
+		entry: &cacheEntry{
+			URL:        r.URL.String(),
+			StatusCode: resp.StatusCode,
+			Header:     resp.Header,
+		},
+	}
+	return resp, nil
+}
+
+// response returns the cached response, or nil if its body is gone.
+func (c *cache) response(r *http.Request, key string, e *cacheEntry) *http.Response {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries[key] != e {
+		return nil
+	}
+	f, err := os.Open(c.bodyPath(key))
+	if err != nil {
+		c.remove(key)
+		return nil
+	}
+	now := time.Now()
+	e.used = now
+	os.Chtimes(c.bodyPath(key), now, now)
+
+	header := http.Header{}
+	for k, v := range e.Header {
+		header[k] = v
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode)),
+		StatusCode:    e.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          f,
+		ContentLength: e.Size,
+		Request:       r,
+	}
+}
+
+// invalidate evicts the entries of the study of the deleted resource, as the
+// responses of the study and of its series hold the deleted instances too.
+func (c *cache) invalidate(deleted *url.URL, study string) {
+	u := *deleted
+	u.RawQuery = ""
+	prefix := u.String()
+	if i := strings.Index(prefix, "/studies/"+study); study != "" && i >= 0 {
+		prefix = prefix[:i+len("/studies/"+study)]
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, e := range c.entries {
+		if hasPathPrefix(e.URL, prefix) {
+			c.remove(key)
+		}
+	}
+}
+
+// hasPathPrefix tells if the URL is the one of the prefix or of a resource
+// under it, i.e. if the prefix is followed by a slash, a query or nothing.
+func hasPathPrefix(u, prefix string) bool {
+	if !strings.HasPrefix(u, prefix) {
+		return false
+	}
+	rest := u[len(prefix):]
+	return rest == "" || rest[0] == '/' || rest[0] == '?'
+}
+
+// refresh marks the entry as fresh after the server validated it.
+func (c *cache) refresh(key string, e *cacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e.Stored = time.Now()
+	c.writeMeta(key, e)
+}
+
+// add commits the body written to tmp as the entry of the key.
+func (c *cache) add(key string, e *cacheEntry, tmp string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := os.Rename(tmp, c.bodyPath(key)); err != nil {
+		os.Remove(tmp)
+		return
+	}
+	if old, ok := c.entries[key]; ok {
+		c.size -= old.Size
+		delete(c.entries, key)
+	}
+	e.Stored = time.Now()
+	e.used = e.Stored
+	if c.writeMeta(key, e) != nil {
+		c.remove(key)
+		return
+	}
+	c.entries[key] = e
+	c.size += e.Size
+	c.evict()
+}
+
+func (c *cache) writeMeta(key string, e *cacheEntry) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(c.metaPath(key), b, 0644)
+}
+
+// evict removes the least recently used entries until the cache fits MaxSize.
+func (c *cache) evict() {
+	for c.maxSize > 0 && c.size > c.maxSize {
+		var oldest string
+		for key, e := range c.entries {
+			if oldest == "" || e.used.Before(c.entries[oldest].used) {
+				oldest = key
+			}
+		}
+		c.remove(oldest)
+	}
+}
+
+func (c *cache) remove(key string) {
+	if e, ok := c.entries[key]; ok {
+		c.size -= e.Size
+		delete(c.entries, key)
+	}
+	c.removeFiles(key)
+}
+
+func (c *cache) removeFiles(key string) {
+	os.Remove(c.metaPath(key))
+	os.Remove(c.bodyPath(key))
+}
+
+// cachingBody copies the response body to a temporary file as it is read, and
+// caches it once read entirely.
+type cachingBody struct {
+	io.ReadCloser
+	cache *cache
+	key   string
+	tmp   *os.File
+	entry *cacheEntry
+}
+
+func (b *cachingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 && b.tmp != nil {
+		if _, werr := b.tmp.Write(p[:n]); werr != nil {
+			b.discard()
+		}
+		b.entry.Size += int64(n)
+	}
+	if err == io.EOF && b.tmp != nil {
+		name := b.tmp.Name()
+		if b.tmp.Close() == nil {
+			b.cache.add(b.key, b.entry, name)
+		} else {
+			os.Remove(name)
+		}
+		b.tmp = nil
+	}
+	return n, err
+}
+
+func (b *cachingBody) Close() error {
+	// readers such as multipart.Reader stop at the closing boundary, which
+	// leaves the epilogue and EOF unread. A body abandoned further from its end
+	// is not cached.
+	if b.tmp != nil {
+		io.CopyN(ioutil.Discard, b, 4096)
+	}
+	b.discard()
+	return b.ReadCloser.Close()
+}
+
+func (b *cachingBody) discard() {
+	if b.tmp != nil {
+		b.tmp.Close()
+		os.Remove(b.tmp.Name())
+		b.tmp = nil
+	}
+}
@@ -0,0 +1,223 @@
+package dicomweb
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newCacheTestServer serves a single part multipart response holding the path
+// of the request, with an ETag.
+func newCacheTestServer(requests *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+		etag := `"` + r.URL.Path + `"`
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Content-Type", "multipart/related; type=\"application/dicom\"; boundary=b")
+		fmt.Fprintf(w, "--b\r\nContent-Type: application/dicom\r\n\r\n%s\r\n--b--\r\n", r.URL.Path)
+	}))
+}
+
+func newCacheTestClient(t *testing.T, url string, option CacheOption) (*Client, func()) {
+	dir, err := ioutil.TempDir("", "cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if option.Dir == "" {
+		option.Dir = dir
+	}
+	c, err := NewClient(ClientOption{WADOEndpoint: url}).WithCache(option)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, func() { os.RemoveAll(dir) }
+}
+
+func seriesRequest(uid string) WADORequest {
+	return WADORequest{Type: SeriesRaw, StudyInstanceUID: "1.2", SeriesInstanceUID: uid}
+}
+
+func TestCacheHit(t *testing.T) {
+	var requests int32
+	ts := newCacheTestServer(&requests)
+	defer ts.Close()
+	c, cleanup := newCacheTestClient(t, ts.URL, CacheOption{})
+	defer cleanup()
+
+	for i := 0; i < 3; i++ {
+		parts, err := c.Retrieve(seriesRequest("1.2.3"))
+		assert.NoError(t, err)
+		assert.Equal(t, [][]byte{[]byte("/studies/1.2/series/1.2.3")}, parts)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+
+	// another transfer syntax is another representation.
+	c.optionFuncs = &[]OptionFunc{func(r *http.Request) error {
+		r.Header.Set("Accept", `multipart/related; type="application/dicom"; transfer-syntax=1.2.840.10008.1.2.4.50`)
+		return nil
+	}}
+	_, err := c.Retrieve(seriesRequest("1.2.3"))
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+}
+
+func TestCacheAuthorization(t *testing.T) {
+	var requests int32
+	ts := newCacheTestServer(&requests)
+	defer ts.Close()
+	c, cleanup := newCacheTestClient(t, ts.URL, CacheOption{})
+	defer cleanup()
+
+	for _, token := range []string{"alice", "bob", "alice"} {
+		c.authorization = "Bearer " + token
+		parts, err := c.Retrieve(seriesRequest("1.2.3"))
+		assert.NoError(t, err)
+		assert.Len(t, parts, 1)
+	}
+	// each principal fetches the series once.
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+}
+
+func TestCacheDelete(t *testing.T) {
+	var requests int32
+	ts := newCacheTestServer(&requests)
+	defer ts.Close()
+	c, cleanup := newCacheTestClient(t, ts.URL, CacheOption{})
+	defer cleanup()
+
+	c.Retrieve(seriesRequest("1.2.3"))
+	c.Retrieve(WADORequest{Type: StudyRaw, StudyInstanceUID: "1.20"})
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+
+	// deleting an instance evicts the responses of its study only.
+	err := c.Delete(DeleteRequest{Type: DeleteInstance, StudyInstanceUID: "1.2", SeriesInstanceUID: "1.2.3", SOPInstanceUID: "1.2.3.4"})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&requests))
+	c.Retrieve(seriesRequest("1.2.3"))
+	c.Retrieve(WADORequest{Type: StudyRaw, StudyInstanceUID: "1.20"})
+	assert.Equal(t, int32(4), atomic.LoadInt32(&requests))
+}
+
+func TestHasPathPrefix(t *testing.T) {
+	assert.True(t, hasPathPrefix("http://pacs/dicom-web", "http://pacs/dicom-web"))
+	assert.True(t, hasPathPrefix("http://pacs/dicom-web/studies", "http://pacs/dicom-web"))
+	assert.True(t, hasPathPrefix("http://pacs/dicom-web?limit=1", "http://pacs/dicom-web"))
+	assert.False(t, hasPathPrefix("http://pacs/dicom-web2/studies", "http://pacs/dicom-web"))
+	assert.False(t, hasPathPrefix("http://pacs/studies", "http://pacs/dicom-web"))
+}
+
+func TestCacheRevalidate(t *testing.T) {
+	var requests int32
+	ts := newCacheTestServer(&requests)
+	defer ts.Close()
+	c, cleanup := newCacheTestClient(t, ts.URL, CacheOption{MaxAge: time.Nanosecond})
+	defer cleanup()
+
+	for i := 0; i < 2; i++ {
+		parts, err := c.Retrieve(seriesRequest("1.2.3"))
+		assert.NoError(t, err)
+		assert.Equal(t, [][]byte{[]byte("/studies/1.2/series/1.2.3")}, parts)
+	}
+	// the second request was answered with 304 Not Modified.
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+}
+
+func TestCacheRefreshConcurrently(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	c := &cache{dir: dir, maxAge: time.Nanosecond, entries: map[string]*cacheEntry{}}
+
+	r, _ := http.NewRequest("GET", "http://pacs/studies/1.2", nil)
+	resp, err := c.roundTrip(RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: ioutil.NopCloser(strings.NewReader("study"))}, nil
+	}), r)
+	assert.NoError(t, err)
+	ioutil.ReadAll(resp.Body)
+	key := cacheKey(r)
+	e := c.entries[key]
+	if !assert.NotNil(t, e) {
+		return
+	}
+
+	// a revalidation refreshes the entry while another request reads it, see
+	// go test -race.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			c.refresh(key, e)
+		}
+	}()
+	offline := RoundTripperFunc(func(*http.Request) (*http.Response, error) {
+		return nil, errors.New("offline")
+	})
+	for i := 0; i < 100; i++ {
+		c.roundTrip(offline, r)
+	}
+	<-done
+}
+
+func TestCacheEviction(t *testing.T) {
+	var requests int32
+	ts := newCacheTestServer(&requests)
+	defer ts.Close()
+	dir, err := ioutil.TempDir("", "cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// each response is 74 bytes long, room for two of them.
+	c, cleanup := newCacheTestClient(t, ts.URL, CacheOption{Dir: dir, MaxSize: 200})
+	defer cleanup()
+	for _, uid := range []string{"1.2.1", "1.2.2", "1.2.1", "1.2.3"} {
+		_, err := c.Retrieve(seriesRequest(uid))
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, int32(3), atomic.LoadInt32(&requests))
+
+	// a client reopening the cache finds 1.2.1 and 1.2.3, 1.2.2 being the least recently used.
+	c, cleanup = newCacheTestClient(t, ts.URL, CacheOption{Dir: dir, MaxSize: 200})
+	defer cleanup()
+	for _, uid := range []string{"1.2.1", "1.2.3"} {
+		_, err := c.Retrieve(seriesRequest(uid))
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, int32(3), atomic.LoadInt32(&requests))
+	_, err = c.Retrieve(seriesRequest("1.2.2"))
+	assert.NoError(t, err)
+	assert.Equal(t, int32(4), atomic.LoadInt32(&requests))
+}
+
+func TestCacheSkipsErrors(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+	c, cleanup := newCacheTestClient(t, ts.URL, CacheOption{})
+	defer cleanup()
+
+	for i := 0; i < 2; i++ {
+		_, err := c.Retrieve(seriesRequest("1.2.3"))
+		assert.EqualError(t, err, "503 Service Unavailable")
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+}
@@ -0,0 +1,347 @@
+package dicomweb
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Capabilities describes the resources an endpoint supports, as given by its
+// capabilities description, see PS3.18 Section 8.9.
+type Capabilities struct {
+	// Resources the resources of the endpoint, with their path relative to the
+	// endpoint, e.g. "studies/{study}/series". The segments between braces
+	// are templates matching any value.
+	Resources []ResourceCapability
+}
+
+// ResourceCapability describes the methods a resource supports.
+type ResourceCapability struct {
+	// Path path of the resource relative to the endpoint.
+	Path string
+	// Methods methods of the resource.
+	Methods []MethodCapability
+}
+
+// MethodCapability describes a method of a resource.
+type MethodCapability struct {
+	// Name HTTP method, e.g. "GET".
+	Name string
+	// ID name of the transaction, e.g. "SearchForStudies".
+	ID string
+	// Params query parameters the method accepts, e.g. "fuzzymatching".
+	Params []string
+	// MediaTypes media types the method responds with.
+	MediaTypes []string
+}
+
+// Capabilities retrieves the capabilities description of the endpoint of the
+// service with an OPTIONS request. The description is either a WADL document
+// or its JSON equivalent, whose objects and arrays follow the WADL elements.
+func (c *Client) Capabilities(service Service) (*Capabilities, error) {
+	endpoint := c.endpoint(service)
+	if endpoint == "" {
+		return nil, fmt.Errorf("no endpoint for %s", service)
+	}
+	r, err := http.NewRequest("OPTIONS", endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	r.Header.Set("Accept", "application/vnd.sun.wadl+xml, application/json;q=0.9")
+	resp, err := c.do(&Operation{Service: service}, r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return nil, errors.New(resp.Status)
+	}
+
+	app := wadlApplication{}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if strings.HasSuffix(mediaType, "json") {
+		err = json.NewDecoder(resp.Body).Decode(&app)
+	} else {
+		err = xml.NewDecoder(resp.Body).Decode(&app)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse capabilities: %v", err)
+	}
+
+	capabilities := &Capabilities{}
+	for _, rs := range app.Resources {
+		for _, res := range rs.Resource {
+			capabilities.add("", res)
+		}
+	}
+	return capabilities, nil
+}
+
+// WithCapabilities makes the client refuse the requests to the endpoint of the
+// service that its capabilities do not support, without sending them, e.g.
+// with the capabilities returned by Capabilities.
+func (c *Client) WithCapabilities(service Service, capabilities *Capabilities) *Client {
+	if c.capabilities == nil {
+		c.capabilities = map[Service]*Capabilities{}
+	}
+	c.capabilities[service] = capabilities
+	return c
+}
+
+// Lookup returns the method of the resource at the given path, relative to the
+// endpoint, if supported.
+func (c *Capabilities) Lookup(method, path string) (MethodCapability, bool) {
+	segments := splitPath(path)
+	for _, res := range c.Resources {
+		if !matchPath(splitPath(res.Path), segments) {
+			continue
+		}
+		for _, m := range res.Methods {
+			if strings.EqualFold(m.Name, method) {
+				return m, true
+			}
+		}
+	}
+	return MethodCapability{}, false
+}
+
+// Supports tells if the method of the resource at the given path is supported,
+// e.g. Supports("GET", "studies/{study}/rendered").
+func (c *Capabilities) Supports(method, path string) bool {
+	_, ok := c.Lookup(method, path)
+	return ok
+}
+
+// SupportsParam tells if the method of the resource accepts the query
+// parameter, e.g. SupportsParam("GET", "studies", "fuzzymatching").
+func (c *Capabilities) SupportsParam(method, path, param string) bool {
+	m, ok := c.Lookup(method, path)
+	if !ok {
+		return false
+	}
+	for _, p := range m.Params {
+		if strings.EqualFold(p, param) {
+			return true
+		}
+	}
+	return false
+}
+
+// SupportsMediaType tells if the method of the resource responds with the
+// media type, e.g. SupportsMediaType("GET", "studies/{study}/thumbnail", "image/jpeg").
+// The parameters of the media types, e.g. the type of multipart/related, are
+// not compared.
+func (c *Capabilities) SupportsMediaType(method, path, mediaType string) bool {
+	m, ok := c.Lookup(method, path)
+	if !ok {
+		return false
+	}
+	for _, t := range m.MediaTypes {
+		if strings.EqualFold(baseMediaType(t), baseMediaType(mediaType)) {
+			return true
+		}
+	}
+	return false
+}
+
+// baseMediaType returns the media type without its parameters.
+func baseMediaType(v string) string {
+	if i := strings.Index(v, ";"); i >= 0 {
+		v = v[:i]
+	}
+	return strings.TrimSpace(v)
+}
+
+// UnsupportedError is returned when a request is refused because the
+// capabilities of its endpoint do not support it.
+type UnsupportedError struct {
+	// Service service of the endpoint.
+	Service Service
+	// Method HTTP method of the request.
+	Method string
+	// Path path of the request relative to the endpoint.
+	Path string
+	// Param query parameter of the request which is not supported, if any.
+	Param string
+	// MediaType media type accepted by the request which is not supported, if any.
+	MediaType string
+}
+
+func (e *UnsupportedError) Error() string {
+	switch {
+	case e.Param != "":
+		return fmt.Sprintf("query parameter %s of %s %s not supported by the %s endpoint", e.Param, e.Method, e.Path, e.Service)
+	case e.MediaType != "":
+		return fmt.Sprintf("media type %s of %s %s not supported by the %s endpoint", e.MediaType, e.Method, e.Path, e.Service)
+	}
+	return fmt.Sprintf("%s %s not supported by the %s endpoint", e.Method, e.Path, e.Service)
+}
+
+// checkCapabilities returns an UnsupportedError if the capabilities of the
+// endpoint of the service are known and do not support the request: its method
+// and path, and, when the capabilities list them, its query parameters and the
+// media types of its Accept header. Attribute matching parameters, keyed by
+// tag or keyword, are not checked. Requests outside of the endpoint, e.g. to a
+// retrieve URL, are not checked.
+func (c *Client) checkCapabilities(service Service, r *http.Request) error {
+	capabilities := c.capabilities[service]
+	if capabilities == nil || r.Method == "OPTIONS" {
+		return nil
+	}
+	endpoint, err := url.Parse(c.endpoint(service))
+	if err != nil || endpoint.Host != r.URL.Host {
+		return nil
+	}
+	base := strings.TrimSuffix(endpoint.Path, "/")
+	if r.URL.Path != base && !strings.HasPrefix(r.URL.Path, base+"/") {
+		return nil
+	}
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, base), "/")
+	m, ok := capabilities.Lookup(r.Method, path)
+	if !ok {
+		return &UnsupportedError{Service: service, Method: r.Method, Path: path}
+	}
+
+	if len(m.Params) > 0 {
+		for param := range r.URL.Query() {
+			// attributes start with a digit or an upper case letter, e.g.
+			// 00100020 or PatientID, unlike the other parameters.
+			if param == "" || param[0] >= '0' && param[0] <= '9' || param[0] >= 'A' && param[0] <= 'Z' {
+				continue
+			}
+			if !capabilities.SupportsParam(r.Method, path, param) {
+				return &UnsupportedError{Service: service, Method: r.Method, Path: path, Param: param}
+			}
+		}
+	}
+	if accept := r.Header.Get("Accept"); len(m.MediaTypes) > 0 && accept != "" {
+		for _, t := range strings.Split(accept, ",") {
+			t = strings.ToLower(baseMediaType(t))
+			if t == "*/*" || capabilities.SupportsMediaType(r.Method, path, t) {
+				return nil
+			}
+			if !strings.HasSuffix(t, "/*") {
+				continue
+			}
+			// e.g. image/* accepting image/jpeg.
+			for _, supported := range m.MediaTypes {
+				if strings.HasPrefix(strings.ToLower(supported), strings.TrimSuffix(t, "*")) {
+					return nil
+				}
+			}
+		}
+		return &UnsupportedError{Service: service, Method: r.Method, Path: path, MediaType: accept}
+	}
+	return nil
+}
+
+// endpoint returns the endpoint of the service.
+func (c *Client) endpoint(service Service) string {
+	switch service {
+	case QIDOService:
+		return c.qidoEndpoint
+	case WADOService:
+		return c.wadoEndpoint
+	case STOWService:
+		return c.stowEndpoint
+	case UPSService:
+		return c.upsEndpoint
+	}
+	return ""
+}
+
+// add adds the resource and its nested resources under the parent path.
+func (c *Capabilities) add(parent string, res wadlResource) {
+	path := strings.Trim(res.Path, "/")
+	if parent != "" {
+		path = parent + "/" + path
+	}
+	rc := ResourceCapability{Path: path}
+	for _, m := range res.Method {
+		mc := MethodCapability{Name: strings.ToUpper(m.Name), ID: m.ID}
+		for _, p := range append(append([]wadlParam{}, res.Param...), m.Request.Param...) {
+			// the path templates and the headers are not query parameters.
+			if p.Style == "" || p.Style == "query" {
+				mc.Params = append(mc.Params, p.Name)
+			}
+		}
+		for _, resp := range m.Response {
+			for _, rep := range resp.Representation {
+				mc.MediaTypes = append(mc.MediaTypes, rep.MediaType)
+			}
+		}
+		rc.Methods = append(rc.Methods, mc)
+	}
+	if len(rc.Methods) > 0 {
+		c.Resources = append(c.Resources, rc)
+	}
+	for _, sub := range res.Resource {
+		c.add(path, sub)
+	}
+}
+
+// splitPath returns the segments of the path.
+func splitPath(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
+
+// matchPath tells if the segments match the template, whose segments between
+// braces match any segment.
+func matchPath(template, segments []string) bool {
+	if len(template) != len(segments) {
+		return false
+	}
+	for i, t := range template {
+		if strings.HasPrefix(t, "{") && strings.HasSuffix(t, "}") {
+			continue
+		}
+		if t != segments[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// wadlApplication is the root of a WADL document, see
+// https://www.w3.org/Submission/wadl/. The json tags read the JSON equivalent.
+type wadlApplication struct {
+	Resources []struct {
+		Base     string         `xml:"base,attr" json:"base"`
+		Resource []wadlResource `xml:"resource" json:"resource"`
+	} `xml:"resources" json:"resources"`
+}
+
+type wadlResource struct {
+	Path     string         `xml:"path,attr" json:"path"`
+	Param    []wadlParam    `xml:"param" json:"param"`
+	Method   []wadlMethod   `xml:"method" json:"method"`
+	Resource []wadlResource `xml:"resource" json:"resource"`
+}
+
+type wadlMethod struct {
+	Name    string `xml:"name,attr" json:"name"`
+	ID      string `xml:"id,attr" json:"id"`
+	Request struct {
+		Param []wadlParam `xml:"param" json:"param"`
+	} `xml:"request" json:"request"`
+	Response []struct {
+		Representation []struct {
+			MediaType string `xml:"mediaType,attr" json:"mediaType"`
+		} `xml:"representation" json:"representation"`
+	} `xml:"response" json:"response"`
+}
+
+type wadlParam struct {
+	Name  string `xml:"name,attr" json:"name"`
+	Style string `xml:"style,attr" json:"style"`
+}
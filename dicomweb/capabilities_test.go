@@ -0,0 +1,132 @@
+package dicomweb
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testWADL = `<?xml version="1.0" encoding="UTF-8"?>
+<application xmlns="http://wadl.dev.java.net/2009/02">
+  <resources base="http://pacs/dicom-web/">
+    <resource path="studies">
+      <method name="GET" id="SearchForStudies">
+        <request>
+          <param name="limit" style="query"/>
+          <param name="fuzzymatching" style="query"/>
+        </request>
+        <response status="200">
+          <representation mediaType="application/dicom+json"/>
+        </response>
+      </method>
+      <method name="POST" id="StoreInstances"/>
+      <resource path="{study}">
+        <param name="study" style="template"/>
+        <method name="GET" id="RetrieveStudy">
+          <response status="200">
+            <representation mediaType="multipart/related; type=&quot;application/dicom&quot;"/>
+          </response>
+        </method>
+        <resource path="series">
+          <method name="GET" id="SearchForSeries"/>
+        </resource>
+      </resource>
+    </resource>
+  </resources>
+</application>`
+
+const testCapabilitiesJSON = `{"resources": [{"base": "http://pacs/dicom-web/", "resource": [
+  {"path": "studies", "method": [{"name": "GET", "id": "SearchForStudies", "request": {"param": [{"name": "limit", "style": "query"}]}}],
+   "resource": [{"path": "{study}/rendered", "method": [{"name": "GET", "response": [{"representation": [{"mediaType": "image/jpeg"}]}]}]}]}
+]}]}`
+
+func TestCapabilities(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "OPTIONS", r.Method)
+		switch r.URL.Path {
+		case "/wadl":
+			w.Header().Set("Content-Type", "application/vnd.sun.wadl+xml")
+			w.Write([]byte(testWADL))
+		case "/json":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(testCapabilitiesJSON))
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+	defer ts.Close()
+	c := NewClient(ClientOption{
+		QIDOEndpoint: ts.URL + "/wadl",
+		WADOEndpoint: ts.URL + "/json",
+		STOWEndpoint: ts.URL + "/none",
+	})
+
+	caps, err := c.Capabilities(QIDOService)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"studies", "studies/{study}", "studies/{study}/series"}, []string{caps.Resources[0].Path, caps.Resources[1].Path, caps.Resources[2].Path})
+	assert.True(t, caps.Supports("GET", "/studies/1.2.3/series"))
+	assert.True(t, caps.Supports("post", "studies"))
+	assert.False(t, caps.Supports("GET", "studies/1.2.3/rendered"))
+	assert.True(t, caps.SupportsParam("GET", "studies", "fuzzymatching"))
+	assert.False(t, caps.SupportsParam("GET", "studies/1.2.3", "study"))
+	assert.True(t, caps.SupportsMediaType("GET", "studies/{study}", `multipart/related; type="application/dicom"`))
+	m, ok := caps.Lookup("GET", "studies/1.2.3")
+	assert.True(t, ok)
+	assert.Equal(t, "RetrieveStudy", m.ID)
+
+	caps, err = c.Capabilities(WADOService)
+	assert.NoError(t, err)
+	assert.True(t, caps.SupportsMediaType("GET", "studies/1.2.3/rendered", "image/jpeg"))
+	assert.True(t, caps.SupportsParam("GET", "studies", "limit"))
+
+	_, err = c.Capabilities(STOWService)
+	assert.EqualError(t, err, "405 Method Not Allowed")
+	_, err = c.Capabilities(UPSService)
+	assert.EqualError(t, err, "no endpoint for UPS")
+}
+
+func TestClientWithCapabilities(t *testing.T) {
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte("[]"))
+	}))
+	defer ts.Close()
+	caps := &Capabilities{Resources: []ResourceCapability{
+		{Path: "studies", Methods: []MethodCapability{{Name: "GET"}}},
+	}}
+	c := NewClient(ClientOption{QIDOEndpoint: ts.URL + "/dicom-web", WADOEndpoint: ts.URL + "/dicom-web"}).
+		WithCapabilities(QIDOService, caps).
+		WithCapabilities(WADOService, caps)
+
+	_, err := c.Query(QIDORequest{Type: Study})
+	assert.NoError(t, err)
+	_, err = c.Query(QIDORequest{Type: Series, StudyInstanceUID: "1.2.3"})
+	assert.EqualError(t, err, "GET studies/1.2.3/series not supported by the QIDO endpoint")
+	_, err = c.Retrieve(WADORequest{Type: StudyRendered, StudyInstanceUID: "1.2.3"})
+	assert.Equal(t, &UnsupportedError{Service: WADOService, Method: "GET", Path: "studies/1.2.3/rendered"}, err)
+	assert.Equal(t, 1, requests)
+
+	// the query parameters and the media types, once listed.
+	caps.Resources[0].Methods[0].Params = []string{"limit", "offset"}
+	caps.Resources[0].Methods[0].MediaTypes = []string{"application/dicom+json"}
+	_, err = c.Query(QIDORequest{Type: Study, PatientID: "PAT-1", Limit: 10})
+	assert.NoError(t, err)
+	_, err = c.Query(QIDORequest{Type: Study, Filters: map[string]string{"fuzzymatching": "true"}})
+	assert.EqualError(t, err, "query parameter fuzzymatching of GET studies not supported by the QIDO endpoint")
+	c.optionFuncs = &[]OptionFunc{func(r *http.Request) error {
+		r.Header.Set("Accept", "application/json")
+		return nil
+	}}
+	_, err = c.Query(QIDORequest{Type: Study})
+	assert.EqualError(t, err, "media type application/json of GET studies not supported by the QIDO endpoint")
+	c.optionFuncs = &[]OptionFunc{func(r *http.Request) error {
+		r.Header.Set("Accept", "application/json, application/*;q=0.5")
+		return nil
+	}}
+	_, err = c.Query(QIDORequest{Type: Study})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, requests)
+}
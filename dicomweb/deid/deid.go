@@ -0,0 +1,298 @@
+// Package deid de-identifies DICOM instances following the Basic Application
+// Level Confidentiality Profile of PS3.15 Annex E, with the Retain Longitudinal
+// Temporal Information with Full Dates, Retain UIDs and Clean Descriptors
+// options, e.g. before storing research copies of a study with STOW-RS.
+//
+// The profile removes the private attributes, the curves, the overlay comments
+// and the person names in addition to the attributes of PS3.15 Table E.1-1.
+// Burned in annotations of the pixel data are not handled.
+package deid
+
+import (
+	"strings"
+
+	"github.com/toastcheng/dicomweb-go/dicomweb/dicom"
+	"github.com/toastcheng/dicomweb-go/dicomweb/uid"
+)
+
+// Attributes recording the de-identification.
+const (
+	PatientIdentityRemoved                  dicom.Tag = 0x00120062
+	DeidentificationMethod                  dicom.Tag = 0x00120063
+	DeidentificationMethodCodeSequence      dicom.Tag = 0x00120064
+	LongitudinalTemporalInformationModified dicom.Tag = 0x00280303
+)
+
+// Option specifies the options of the profile.
+type Option struct {
+	// RetainDates keeps the dates and times, the Retain Longitudinal Temporal Information with Full Dates Option.
+	RetainDates bool
+	// RetainUIDs keeps the UIDs, the Retain UIDs Option. They are replaced otherwise
+	RetainUIDs bool
+	// CleanDescriptors keeps the descriptions and comments without the identifying values of the instance, the Clean Descriptors Option. They are removed otherwise
+	CleanDescriptors bool
+	// PatientName replaces the patient name, e.g. a pseudonym. Emptied otherwise
+	PatientName string
+	// PatientID replaces the patient ID, e.g. a pseudonym. Emptied otherwise
+	PatientID string
+	// UIDs mapping of the replaced UIDs, e.g. loaded from a previous run or deterministic. Uses a random mapping otherwise
+	UIDs *uid.Mapping
+}
+
+// Deidentifier de-identifies instances. The UIDs it replaces are mapped
+// consistently across the instances, so that the instances of a study
+// de-identified by the same Deidentifier still refer to each other. It is safe
+// for concurrent use.
+type Deidentifier struct {
+	option Option
+	uids   *uid.Mapping
+}
+
+// New creates a Deidentifier.
+func New(option Option) *Deidentifier {
+	uids := option.UIDs
+	if uids == nil {
+		uids = uid.NewMapping(nil, nil)
+	}
+	return &Deidentifier{option: option, uids: uids}
+}
+
+// UID returns the UID replacing the given one, generating it on first use.
+func (d *Deidentifier) UID(old string) string {
+	return d.uids.Map(old)
+}
+
+// UIDs returns the UIDs replaced so far, mapped to their replacement.
+func (d *Deidentifier) UIDs() map[string]string {
+	return d.uids.UIDs()
+}
+
+// Part10 de-identifies the DICOM Part 10 file.
+func (d *Deidentifier) Part10(b []byte) ([]byte, error) {
+	ds, err := dicom.Parse(b)
+	if err != nil {
+		return nil, err
+	}
+	d.Deidentify(ds)
+	return dicom.Encode(ds)
+}
+
+// Parts de-identifies the DICOM Part 10 files, e.g. the Parts of a STOWRequest.
+func (d *Deidentifier) Parts(parts [][]byte) ([][]byte, error) {
+	deidentified := make([][]byte, len(parts))
+	for i, p := range parts {
+		b, err := d.Part10(p)
+		if err != nil {
+			return nil, err
+		}
+		deidentified[i] = b
+	}
+	return deidentified, nil
+}
+
+// Deidentify de-identifies the data set in place, including its file meta
+// information if any, and records the profile and options applied.
+func (d *Deidentifier) Deidentify(ds *dicom.Dataset) {
+	words := identifyingWords(ds)
+	d.dataset(ds, words)
+
+	if !d.option.RetainUIDs {
+		if e := ds.Get(dicom.MediaStorageSOPInstanceUID); e != nil {
+			d.replaceUIDs(e)
+		}
+	}
+	if d.option.PatientName != "" {
+		ds.Set(dicom.NewString(dicom.PatientName, "PN", d.option.PatientName))
+	}
+	if d.option.PatientID != "" {
+		ds.Set(dicom.NewString(dicom.PatientID, "LO", d.option.PatientID))
+	}
+
+	codes := []string{codeBasicProfile}
+	if d.option.CleanDescriptors {
+		codes = append(codes, codeCleanDescriptors)
+	}
+	if d.option.RetainDates {
+		codes = append(codes, codeRetainDates)
+	}
+	if d.option.RetainUIDs {
+		codes = append(codes, codeRetainUIDs)
+	}
+	methods := []string{}
+	items := []*dicom.Dataset{}
+	for _, code := range codes {
+		methods = append(methods, codeMeanings[code])
+		item := &dicom.Dataset{}
+		item.Set(dicom.NewString(0x00080100, "SH", code))
+		item.Set(dicom.NewString(0x00080102, "SH", "DCM"))
+		item.Set(dicom.NewString(0x00080104, "LO", codeMeanings[code]))
+		items = append(items, item)
+	}
+	ds.Set(dicom.NewString(PatientIdentityRemoved, "CS", "YES"))
+	ds.Set(dicom.NewString(DeidentificationMethod, "LO", methods...))
+	ds.Set(dicom.NewSequence(DeidentificationMethodCodeSequence, items...))
+	if d.option.RetainDates {
+		ds.Set(dicom.NewString(LongitudinalTemporalInformationModified, "CS", "UNMODIFIED"))
+	} else {
+		ds.Set(dicom.NewString(LongitudinalTemporalInformationModified, "CS", "REMOVED"))
+	}
+}
+
+// dataset applies the profile to the elements of the data set, and to the
+// items of its sequences.
+func (d *Deidentifier) dataset(ds *dicom.Dataset, words []string) {
+	elements := append([]*dicom.Element{}, ds.Elements...)
+	for _, e := range elements {
+		if e.Tag.Group() == 0x0002 {
+			continue
+		}
+		switch d.action(e) {
+		case remove:
+			ds.Remove(e.Tag)
+			continue
+		case empty:
+			e.Value, e.Items, e.Fragments = nil, nil, nil
+		case dummy:
+			if e.VR == "SQ" {
+				e.Items = nil
+			} else {
+				e.Value = dicom.NewString(e.Tag, e.VR, dummyValue(e.VR)).Value
+			}
+		case clean:
+			e.Value = dicom.NewString(e.Tag, e.VR, cleanValue(values(e), words)...).Value
+		case replaceUID:
+			d.replaceUIDs(e)
+		}
+		for _, item := range e.Items {
+			d.dataset(item, words)
+		}
+	}
+}
+
+// action returns the action on the element, given the options.
+func (d *Deidentifier) action(e *dicom.Element) action {
+	t := e.Tag
+	switch {
+	case t.IsPrivate():
+		return remove
+	case t.Group()&0xFF00 == 0x5000:
+		// curves, retired.
+		return remove
+	case t.Group()&0xFF00 == 0x6000 && (t.Element() == 0x3000 || t.Element() == 0x4000):
+		// overlay data and overlay comments.
+		return remove
+	}
+
+	vr := e.VR
+	if vr == "UN" {
+		vr = dicom.LookupVR(t)
+	}
+	a, ok := basicProfile[t]
+	if !ok {
+		// person names identify someone whether or not the table lists them.
+		if vr == "PN" {
+			return remove
+		}
+		return keep
+	}
+	switch {
+	case a == replaceUID && d.option.RetainUIDs:
+		return keep
+	case d.option.RetainDates && (vr == "DA" || vr == "DT" || vr == "TM") && t.Group() != 0x0010:
+		// the dates of the patient, e.g. the birth date, are not longitudinal.
+		return keep
+	case d.option.CleanDescriptors && descriptors[t]:
+		return clean
+	}
+	return a
+}
+
+// replaceUIDs replaces each UID value of the element by its mapped UID.
+func (d *Deidentifier) replaceUIDs(e *dicom.Element) {
+	uids := values(e)
+	for i, v := range uids {
+		if v != "" {
+			uids[i] = d.UID(v)
+		}
+	}
+	e.Value = dicom.NewString(e.Tag, "UI", uids...).Value
+}
+
+// values returns the values of a string element, including an element of an
+// unknown VR read from an implicit VR data set.
+func values(e *dicom.Element) []string {
+	if e.IsString() {
+		return e.Strings()
+	}
+	s := strings.TrimRight(string(e.Value), " \x00")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\\")
+}
+
+// identifyingWords returns the words of the identifying values of the data set,
+// to be removed from the descriptors.
+func identifyingWords(ds *dicom.Dataset) []string {
+	words := []string{}
+	for _, t := range identifying {
+		e := ds.Get(t)
+		if e == nil {
+			continue
+		}
+		for _, v := range values(e) {
+			// the components of person names are separated by carets, their
+			// groups by equal signs.
+			for _, w := range strings.FieldsFunc(v, func(r rune) bool {
+				return r == '^' || r == '=' || r == ' '
+			}) {
+				if len(w) > 1 {
+					words = append(words, strings.ToLower(w))
+				}
+			}
+		}
+	}
+	return words
+}
+
+// cleanValue removes the identifying words from the values, ignoring case.
+func cleanValue(values []string, words []string) []string {
+	cleaned := make([]string, len(values))
+	for i, v := range values {
+		for _, w := range words {
+			for {
+				lower := strings.ToLower(v)
+				if len(lower) != len(v) {
+					// the offsets in lower would not match v.
+					lower = v
+				}
+				j := strings.Index(lower, w)
+				if j < 0 {
+					break
+				}
+				v = v[:j] + v[j+len(w):]
+			}
+		}
+		cleaned[i] = strings.Join(strings.Fields(v), " ")
+	}
+	return cleaned
+}
+
+// dummyValue returns a value of the VR carrying no information.
+func dummyValue(vr string) string {
+	switch vr {
+	case "DA":
+		return "19000101"
+	case "TM":
+		return "000000"
+	case "DT":
+		return "19000101000000"
+	case "AS":
+		return "000Y"
+	case "DS", "IS":
+		return "0"
+	case "CS", "LO", "LT", "PN", "SH", "ST", "UC", "UT":
+		return "ANONYMIZED"
+	}
+	return ""
+}
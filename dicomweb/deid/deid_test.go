@@ -0,0 +1,181 @@
+package deid
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/toastcheng/dicomweb-go/dicomweb/dicom"
+	"github.com/toastcheng/dicomweb-go/dicomweb/uid"
+)
+
+func newTestDataset(sop string) *dicom.Dataset {
+	ds := &dicom.Dataset{}
+	ds.Set(dicom.NewString(dicom.TransferSyntaxUID, "UI", dicom.ExplicitVRLittleEndian))
+	ds.Set(dicom.NewString(dicom.MediaStorageSOPInstanceUID, "UI", sop))
+	ds.Set(dicom.NewString(dicom.SOPClassUID, "UI", "1.2.840.10008.5.1.4.1.1.7"))
+	ds.Set(dicom.NewString(dicom.SOPInstanceUID, "UI", sop))
+	ds.Set(dicom.NewString(dicom.StudyDate, "DA", "20200102"))
+	ds.Set(dicom.NewString(dicom.SeriesDate, "DA", "20200102"))
+	ds.Set(dicom.NewString(0x00080023, "DA", "20200102"))
+	ds.Set(dicom.NewString(dicom.AccessionNumber, "SH", "ACC42"))
+	ds.Set(dicom.NewString(dicom.Modality, "CS", "CT"))
+	ds.Set(dicom.NewString(0x00080080, "LO", "General Hospital"))
+	ds.Set(dicom.NewString(dicom.StudyDescription, "LO", "CT head DOE john General hospital"))
+	ds.Set(dicom.NewSequence(0x00081140, &dicom.Dataset{Elements: []*dicom.Element{
+		dicom.NewString(0x00081150, "UI", "1.2.840.10008.5.1.4.1.1.7"),
+		dicom.NewString(0x00081155, "UI", "1.2.1.1.1"),
+	}}))
+	ds.Set(dicom.NewString(dicom.PatientName, "PN", "Doe^John"))
+	ds.Set(dicom.NewString(dicom.PatientID, "LO", "PAT-1"))
+	ds.Set(dicom.NewString(dicom.PatientBirthDate, "DA", "19700101"))
+	ds.Set(dicom.NewString(0x00101010, "AS", "050Y"))
+	ds.Set(dicom.NewString(0x00091010, "LO", "private"))
+	ds.Set(dicom.NewString(dicom.StudyInstanceUID, "UI", "1.2.1"))
+	ds.Set(dicom.NewString(dicom.SeriesInstanceUID, "UI", "1.2.1.1"))
+	ds.Set(dicom.NewString(0x60004000, "LT", "overlay by Doe"))
+	ds.Set(dicom.NewBytes(dicom.PixelData, "OW", []byte{1, 2, 3, 4}))
+	return ds
+}
+
+func TestDeidentify(t *testing.T) {
+	d := New(Option{})
+	ds := newTestDataset("1.2.1.1.1")
+	d.Deidentify(ds)
+
+	assert.Equal(t, "", ds.String(dicom.PatientName))
+	assert.NotNil(t, ds.Get(dicom.PatientName))
+	assert.Equal(t, "", ds.String(dicom.PatientID))
+	assert.Equal(t, "", ds.String(dicom.PatientBirthDate))
+	assert.Equal(t, "", ds.String(dicom.StudyDate))
+	assert.Equal(t, "", ds.String(dicom.AccessionNumber))
+	assert.Equal(t, "19000101", ds.String(0x00080023))
+	assert.Equal(t, "CT", ds.String(dicom.Modality))
+	for _, tag := range []dicom.Tag{dicom.SeriesDate, 0x00080080, dicom.StudyDescription, 0x00101010, 0x00091010, 0x60004000} {
+		assert.Nil(t, ds.Get(tag), tag.String())
+	}
+	assert.Equal(t, []byte{1, 2, 3, 4}, ds.Get(dicom.PixelData).Value)
+
+	uids := d.UIDs()
+	assert.Len(t, uids, 3)
+	assert.Equal(t, uids["1.2.1"], ds.String(dicom.StudyInstanceUID))
+	assert.Equal(t, uids["1.2.1.1"], ds.String(dicom.SeriesInstanceUID))
+	assert.Equal(t, uids["1.2.1.1.1"], ds.String(dicom.SOPInstanceUID))
+	assert.Equal(t, uids["1.2.1.1.1"], ds.String(dicom.MediaStorageSOPInstanceUID))
+	assert.Equal(t, "1.2.840.10008.5.1.4.1.1.7", ds.String(dicom.SOPClassUID))
+	assert.Regexp(t, `^2\.25\.[0-9]+$`, uids["1.2.1"])
+
+	assert.Equal(t, "YES", ds.String(PatientIdentityRemoved))
+	assert.Equal(t, "Basic Application Confidentiality Profile", ds.String(DeidentificationMethod))
+	assert.Equal(t, "113100", ds.Get(DeidentificationMethodCodeSequence).Items[0].String(0x00080100))
+	assert.Equal(t, "REMOVED", ds.String(LongitudinalTemporalInformationModified))
+
+	// the UIDs are mapped consistently across the instances.
+	other := newTestDataset("1.2.1.1.2")
+	d.Deidentify(other)
+	assert.Equal(t, ds.String(dicom.StudyInstanceUID), other.String(dicom.StudyInstanceUID))
+	assert.Equal(t, ds.String(dicom.SeriesInstanceUID), other.String(dicom.SeriesInstanceUID))
+	assert.NotEqual(t, ds.String(dicom.SOPInstanceUID), other.String(dicom.SOPInstanceUID))
+	assert.Equal(t, ds.String(dicom.SOPInstanceUID), other.Get(0x00081140).Items[0].String(0x00081155))
+}
+
+func TestDeidentifyOptions(t *testing.T) {
+	d := New(Option{
+		RetainDates:      true,
+		RetainUIDs:       true,
+		CleanDescriptors: true,
+		PatientName:      "Subject^001",
+		PatientID:        "SUBJ-001",
+	})
+	ds := newTestDataset("1.2.1.1.1")
+	d.Deidentify(ds)
+
+	assert.Equal(t, "Subject^001", ds.String(dicom.PatientName))
+	assert.Equal(t, "SUBJ-001", ds.String(dicom.PatientID))
+	assert.Equal(t, "20200102", ds.String(dicom.StudyDate))
+	assert.Equal(t, "20200102", ds.String(dicom.SeriesDate))
+	assert.Equal(t, "", ds.String(dicom.PatientBirthDate))
+	assert.Equal(t, "CT head", ds.String(dicom.StudyDescription))
+	assert.Equal(t, "1.2.1", ds.String(dicom.StudyInstanceUID))
+	assert.Equal(t, "1.2.1.1.1", ds.String(dicom.MediaStorageSOPInstanceUID))
+	assert.Empty(t, d.UIDs())
+
+	assert.Equal(t, []string{
+		"Basic Application Confidentiality Profile",
+		"Clean Descriptors Option",
+		"Retain Longitudinal Temporal Information Full Dates Option",
+		"Retain UIDs Option",
+	}, ds.Get(DeidentificationMethod).Strings())
+	assert.Equal(t, "UNMODIFIED", ds.String(LongitudinalTemporalInformationModified))
+}
+
+func TestPart10(t *testing.T) {
+	b, err := dicom.Encode(newTestDataset("1.2.1.1.1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := New(Option{})
+	parts, err := d.Parts([][]byte{b})
+	assert.NoError(t, err)
+
+	ds, err := dicom.Parse(parts[0])
+	assert.NoError(t, err)
+	assert.Equal(t, d.UID("1.2.1.1.1"), ds.String(dicom.MediaStorageSOPInstanceUID))
+	assert.Equal(t, d.UID("1.2.1.1.1"), ds.String(dicom.SOPInstanceUID))
+	assert.Equal(t, "", ds.String(dicom.PatientName))
+
+	_, err = d.Part10([]byte("not dicom"))
+	assert.Equal(t, dicom.ErrNotPart10, err)
+}
+
+func TestDeidentifyPersonNames(t *testing.T) {
+	ds := newTestDataset("1.2.1.1.1")
+	ds.Set(dicom.NewString(0x0008009C, "PN", "Doe^Jane"))
+	ds.Set(dicom.NewString(0x00102297, "PN", "Doe^Jim"))
+	ds.Set(dicom.NewString(0x00380050, "LO", "wheelchair"))
+	ds.Set(dicom.NewSequence(0x0040A073, &dicom.Dataset{Elements: []*dicom.Element{
+		dicom.NewString(0x0040A075, "PN", "Roe^Richard"),
+	}}))
+	// a person name the table does not list.
+	ds.Set(dicom.NewString(0x00189999, "PN", "Roe^Jane"))
+	New(Option{}).Deidentify(ds)
+
+	assert.NotNil(t, ds.Get(0x0008009C))
+	assert.Equal(t, "", ds.String(0x0008009C))
+	for _, tag := range []dicom.Tag{0x00102297, 0x00380050, 0x00189999} {
+		assert.Nil(t, ds.Get(tag), tag.String())
+	}
+	assert.Empty(t, ds.Get(0x0040A073).Items)
+}
+
+func TestDeidentifyUIDAttributes(t *testing.T) {
+	d := New(Option{})
+	ds := newTestDataset("1.2.1.1.1")
+	ds.Set(dicom.NewString(0x00083010, "UI", "1.2.1.9"))
+	ds.Set(dicom.NewString(0x00200242, "UI", "1.2.1.1.0"))
+	ds.Set(dicom.NewSequence(0x30060010, &dicom.Dataset{Elements: []*dicom.Element{
+		dicom.NewString(0x00200052, "UI", "1.2.1.8"),
+	}}))
+	ds.Set(dicom.NewSequence(0x30060080, &dicom.Dataset{Elements: []*dicom.Element{
+		dicom.NewString(0x30060024, "UI", "1.2.1.8"),
+	}}))
+	d.Deidentify(ds)
+
+	uids := d.UIDs()
+	assert.Equal(t, uids["1.2.1.9"], ds.String(0x00083010))
+	assert.Equal(t, uids["1.2.1.1.0"], ds.String(0x00200242))
+	assert.Equal(t, uids["1.2.1.8"], ds.Get(0x30060010).Items[0].String(0x00200052))
+	assert.Equal(t, uids["1.2.1.8"], ds.Get(0x30060080).Items[0].String(0x30060024))
+	assert.NotEmpty(t, uids["1.2.1.8"])
+}
+
+func TestDeidentifyUIDMapping(t *testing.T) {
+	g, err := uid.NewGenerator("1.2.999")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ds1, ds2 := newTestDataset("1.2.1.1.1"), newTestDataset("1.2.1.1.1")
+	New(Option{UIDs: uid.NewMapping(g, []byte("key"))}).Deidentify(ds1)
+	New(Option{UIDs: uid.NewMapping(g, []byte("key"))}).Deidentify(ds2)
+	assert.Equal(t, ds1.String(dicom.SOPInstanceUID), ds2.String(dicom.SOPInstanceUID))
+	assert.Regexp(t, `^1\.2\.999\.`, ds1.String(dicom.StudyInstanceUID))
+}
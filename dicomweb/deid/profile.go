@@ -0,0 +1,451 @@
+package deid
+
+import "github.com/toastcheng/dicomweb-go/dicomweb/dicom"
+
+// action is an action of the profile on an attribute, see PS3.15 Table E.1-1.
+type action int
+
+const (
+	// keep K, the attribute is kept.
+	keep action = iota
+	// remove X, the attribute is removed.
+	remove
+	// empty Z, the attribute is replaced by a zero length value.
+	empty
+	// dummy D, the attribute is replaced by a dummy value of its VR.
+	dummy
+	// clean C, the identifying information is removed from the value.
+	clean
+	// replaceUID U, the UIDs are replaced by UIDs mapped consistently.
+	replaceUID
+)
+
+// basicProfile actions of the Basic Application Level Confidentiality Profile.
+// Where the standard leaves a choice, e.g. X/Z/D, the strictest one which keeps
+// the instances valid is taken. Attributes which are not listed are kept,
+// except for the person names, which are removed.
+var basicProfile = map[dicom.Tag]action{
+	0x00041511: replaceUID, // ReferencedSOPInstanceUIDInFile
+
+	0x00080012: remove,     // InstanceCreationDate
+	0x00080013: remove,     // InstanceCreationTime
+	0x00080014: replaceUID, // InstanceCreatorUID
+	0x00080015: remove,     // InstanceCoercionDateTime
+	0x00080017: replaceUID, // AcquisitionUID
+	0x00080018: replaceUID, // SOPInstanceUID
+	0x00080019: replaceUID, // PyramidUID
+	0x00080020: empty,      // StudyDate
+	0x00080021: remove,     // SeriesDate
+	0x00080022: remove,     // AcquisitionDate
+	0x00080023: dummy,      // ContentDate
+	0x00080024: remove,     // OverlayDate
+	0x00080025: remove,     // CurveDate
+	0x0008002A: remove,     // AcquisitionDateTime
+	0x00080030: empty,      // StudyTime
+	0x00080031: remove,     // SeriesTime
+	0x00080032: remove,     // AcquisitionTime
+	0x00080033: dummy,      // ContentTime
+	0x00080034: remove,     // OverlayTime
+	0x00080035: remove,     // CurveTime
+	0x00080050: empty,      // AccessionNumber
+	0x00080058: replaceUID, // FailedSOPInstanceUIDList
+	0x00080080: remove,     // InstitutionName
+	0x00080081: remove,     // InstitutionAddress
+	0x00080082: remove,     // InstitutionCodeSequence
+	0x00080090: empty,      // ReferringPhysicianName
+	0x00080092: remove,     // ReferringPhysicianAddress
+	0x00080094: remove,     // ReferringPhysicianTelephoneNumbers
+	0x00080096: remove,     // ReferringPhysicianIdentificationSequence
+	0x0008009C: empty,      // ConsultingPhysicianName
+	0x0008009D: remove,     // ConsultingPhysicianIdentificationSequence
+	0x0008010D: replaceUID, // ContextGroupExtensionCreatorUID
+	0x00080201: remove,     // TimezoneOffsetFromUTC
+	0x00081010: remove,     // StationName
+	0x00081030: remove,     // StudyDescription
+	0x0008103E: remove,     // SeriesDescription
+	0x00081040: remove,     // InstitutionalDepartmentName
+	0x00081041: remove,     // InstitutionalDepartmentTypeCodeSequence
+	0x00081048: remove,     // PhysiciansOfRecord
+	0x00081049: remove,     // PhysiciansOfRecordIdentificationSequence
+	0x00081050: remove,     // PerformingPhysicianName
+	0x00081052: remove,     // PerformingPhysicianIdentificationSequence
+	0x00081060: remove,     // NameOfPhysiciansReadingStudy
+	0x00081062: remove,     // PhysiciansReadingStudyIdentificationSequence
+	0x00081070: remove,     // OperatorsName
+	0x00081072: remove,     // OperatorIdentificationSequence
+	0x00081080: remove,     // AdmittingDiagnosesDescription
+	0x00081084: remove,     // AdmittingDiagnosesCodeSequence
+	0x00081110: remove,     // ReferencedStudySequence
+	0x00081111: remove,     // ReferencedPerformedProcedureStepSequence
+	0x00081120: remove,     // ReferencedPatientSequence
+	0x00081155: replaceUID, // ReferencedSOPInstanceUID
+	0x00081195: replaceUID, // TransactionUID
+	0x00082111: remove,     // DerivationDescription
+	0x00083010: replaceUID, // IrradiationEventUID
+	0x00084000: remove,     // IdentifyingComments
+	0x00089123: replaceUID, // CreatorVersionUID
+
+	0x00100010: empty,  // PatientName
+	0x00100020: empty,  // PatientID
+	0x00100021: remove, // IssuerOfPatientID
+	0x00100022: remove, // TypeOfPatientID
+	0x00100024: remove, // IssuerOfPatientIDQualifiersSequence
+	0x00100030: empty,  // PatientBirthDate
+	0x00100032: remove, // PatientBirthTime
+	0x00100033: remove, // PatientBirthDateInAlternativeCalendar
+	0x00100034: remove, // PatientDeathDateInAlternativeCalendar
+	0x00100035: remove, // PatientAlternativeCalendar
+	0x00100040: empty,  // PatientSex
+	0x00100050: remove, // PatientInsurancePlanCodeSequence
+	0x00100101: remove, // PatientPrimaryLanguageCodeSequence
+	0x00100102: remove, // PatientPrimaryLanguageModifierCodeSequence
+	0x00101000: remove, // OtherPatientIDs
+	0x00101001: remove, // OtherPatientNames
+	0x00101002: remove, // OtherPatientIDsSequence
+	0x00101005: remove, // PatientBirthName
+	0x00101010: remove, // PatientAge
+	0x00101020: remove, // PatientSize
+	0x00101030: remove, // PatientWeight
+	0x00101040: remove, // PatientAddress
+	0x00101050: remove, // InsurancePlanIdentification
+	0x00101060: remove, // PatientMotherBirthName
+	0x00101080: remove, // MilitaryRank
+	0x00101081: remove, // BranchOfService
+	0x00101090: remove, // MedicalRecordLocator
+	0x00101100: remove, // ReferencedPatientPhotoSequence
+	0x00102000: remove, // MedicalAlerts
+	0x00102110: remove, // Allergies
+	0x00102150: remove, // CountryOfResidence
+	0x00102152: remove, // RegionOfResidence
+	0x00102154: remove, // PatientTelephoneNumbers
+	0x00102155: remove, // PatientTelecomInformation
+	0x00102160: remove, // EthnicGroup
+	0x00102180: remove, // Occupation
+	0x001021A0: remove, // SmokingStatus
+	0x001021B0: remove, // AdditionalPatientHistory
+	0x001021C0: remove, // PregnancyStatus
+	0x001021D0: remove, // LastMenstrualDate
+	0x001021F0: remove, // PatientReligiousPreference
+	0x00102203: remove, // PatientSexNeutered
+	0x00102297: remove, // ResponsiblePerson
+	0x00102299: remove, // ResponsibleOrganization
+	0x00104000: remove, // PatientComments
+
+	0x00120010: dummy,  // ClinicalTrialSponsorName
+	0x00120020: dummy,  // ClinicalTrialProtocolID
+	0x00120021: empty,  // ClinicalTrialProtocolName
+	0x00120030: empty,  // ClinicalTrialSiteID
+	0x00120031: empty,  // ClinicalTrialSiteName
+	0x00120040: dummy,  // ClinicalTrialSubjectID
+	0x00120042: dummy,  // ClinicalTrialSubjectReadingID
+	0x00120050: empty,  // ClinicalTrialTimePointID
+	0x00120051: remove, // ClinicalTrialTimePointDescription
+	0x00120060: empty,  // ClinicalTrialCoordinatingCenterName
+	0x00120071: remove, // ClinicalTrialSeriesID
+	0x00120072: remove, // ClinicalTrialSeriesDescription
+	0x00120081: dummy,  // ClinicalTrialProtocolEthicsCommitteeName
+	0x00120082: remove, // ClinicalTrialProtocolEthicsCommitteeApprovalNumber
+
+	0x00180010: empty,      // ContrastBolusAgent
+	0x00181000: remove,     // DeviceSerialNumber
+	0x00181002: replaceUID, // DeviceUID
+	0x00181004: remove,     // PlateID
+	0x00181005: remove,     // GeneratorID
+	0x00181007: remove,     // CassetteID
+	0x00181008: remove,     // GantryID
+	0x00181009: remove,     // UniqueDeviceIdentifier
+	0x0018100A: remove,     // UDISequence
+	0x0018100B: replaceUID, // ManufacturerDeviceClassUID
+	0x00181012: remove,     // DateOfSecondaryCapture
+	0x00181014: remove,     // TimeOfSecondaryCapture
+	0x00181030: remove,     // ProtocolName
+	0x00181200: remove,     // DateOfLastCalibration
+	0x00181201: remove,     // TimeOfLastCalibration
+	0x00181202: remove,     // DateTimeOfLastCalibration
+	0x00181400: remove,     // AcquisitionDeviceProcessingDescription
+	0x00184000: remove,     // AcquisitionComments
+	0x0018700A: remove,     // DetectorID
+	0x00189074: remove,     // FrameAcquisitionDateTime
+	0x00189151: remove,     // FrameReferenceDateTime
+	0x00189424: remove,     // AcquisitionProtocolDescription
+	0x00189516: remove,     // StartAcquisitionDateTime
+	0x00189517: remove,     // EndAcquisitionDateTime
+	0x00189804: remove,     // ExclusionStartDateTime
+	0x0018A002: remove,     // ContributionDateTime
+	0x0018A003: remove,     // ContributionDescription
+
+	0x0020000D: replaceUID, // StudyInstanceUID
+	0x0020000E: replaceUID, // SeriesInstanceUID
+	0x00200010: empty,      // StudyID
+	0x00200027: remove,     // PyramidLabel
+	0x00200052: replaceUID, // FrameOfReferenceUID
+	0x00200200: replaceUID, // SynchronizationFrameOfReferenceUID
+	0x00200242: replaceUID, // SOPInstanceUIDOfConcatenationSource
+	0x00203401: remove,     // ModifyingDeviceID
+	0x00203403: remove,     // ModifiedImageDate
+	0x00203404: remove,     // ModifyingDeviceManufacturer
+	0x00203405: remove,     // ModifiedImageTime
+	0x00203406: remove,     // ModifiedImageDescription
+	0x00204000: remove,     // ImageComments
+	0x00209158: remove,     // FrameComments
+	0x00209161: replaceUID, // ConcatenationUID
+	0x00209164: replaceUID, // DimensionOrganizationUID
+
+	0x00281199: replaceUID, // PaletteColorLookupTableUID
+	0x00281214: replaceUID, // LargePaletteColorLookupTableUID
+	0x00284000: remove,     // ImagePresentationComments
+
+	0x00320012: remove, // StudyIDIssuer
+	0x00320032: remove, // StudyVerifiedDate
+	0x00320033: remove, // StudyVerifiedTime
+	0x00320034: remove, // StudyReadDate
+	0x00320035: remove, // StudyReadTime
+	0x00321000: remove, // ScheduledStudyStartDate
+	0x00321001: remove, // ScheduledStudyStartTime
+	0x00321010: remove, // ScheduledStudyStopDate
+	0x00321011: remove, // ScheduledStudyStopTime
+	0x00321020: remove, // ScheduledStudyLocation
+	0x00321021: remove, // ScheduledStudyLocationAETitle
+	0x00321030: remove, // ReasonForStudy
+	0x00321031: remove, // RequestingPhysicianIdentificationSequence
+	0x00321032: remove, // RequestingPhysician
+	0x00321033: remove, // RequestingService
+	0x00321040: remove, // StudyArrivalDate
+	0x00321041: remove, // StudyArrivalTime
+	0x00321050: remove, // StudyCompletionDate
+	0x00321051: remove, // StudyCompletionTime
+	0x00321060: remove, // RequestedProcedureDescription
+	0x00321064: remove, // RequestedProcedureCodeSequence
+	0x00321066: remove, // ReasonForVisit
+	0x00321067: remove, // ReasonForVisitCodeSequence
+	0x00321070: remove, // RequestedContrastAgent
+	0x00324000: remove, // StudyComments
+
+	0x00380004: remove, // ReferencedPatientAliasSequence
+	0x00380010: remove, // AdmissionID
+	0x00380011: remove, // IssuerOfAdmissionID
+	0x00380014: remove, // IssuerOfAdmissionIDSequence
+	0x0038001A: remove, // ScheduledAdmissionDate
+	0x0038001B: remove, // ScheduledAdmissionTime
+	0x0038001C: remove, // ScheduledDischargeDate
+	0x0038001D: remove, // ScheduledDischargeTime
+	0x0038001E: remove, // ScheduledPatientInstitutionResidence
+	0x00380020: remove, // AdmittingDate
+	0x00380021: remove, // AdmittingTime
+	0x00380030: remove, // DischargeDate
+	0x00380032: remove, // DischargeTime
+	0x00380040: remove, // DischargeDiagnosisDescription
+	0x00380050: remove, // SpecialNeeds
+	0x00380060: remove, // ServiceEpisodeID
+	0x00380061: remove, // IssuerOfServiceEpisodeID
+	0x00380062: remove, // ServiceEpisodeDescription
+	0x00380064: remove, // IssuerOfServiceEpisodeIDSequence
+	0x00380300: remove, // CurrentPatientLocation
+	0x00380400: remove, // PatientInstitutionResidence
+	0x00380500: remove, // PatientState
+	0x00384000: remove, // VisitComments
+
+	0x003A0310: replaceUID, // MultiplexGroupUID
+
+	0x00400001: remove,     // ScheduledStationAETitle
+	0x00400002: remove,     // ScheduledProcedureStepStartDate
+	0x00400003: remove,     // ScheduledProcedureStepStartTime
+	0x00400004: remove,     // ScheduledProcedureStepEndDate
+	0x00400005: remove,     // ScheduledProcedureStepEndTime
+	0x00400006: remove,     // ScheduledPerformingPhysicianName
+	0x00400007: remove,     // ScheduledProcedureStepDescription
+	0x00400009: remove,     // ScheduledProcedureStepID
+	0x0040000B: remove,     // ScheduledPerformingPhysicianIdentificationSequence
+	0x00400010: remove,     // ScheduledStationName
+	0x00400011: remove,     // ScheduledProcedureStepLocation
+	0x00400012: remove,     // PreMedication
+	0x00400241: remove,     // PerformedStationAETitle
+	0x00400242: remove,     // PerformedStationName
+	0x00400243: remove,     // PerformedLocation
+	0x00400244: remove,     // PerformedProcedureStepStartDate
+	0x00400245: remove,     // PerformedProcedureStepStartTime
+	0x00400250: remove,     // PerformedProcedureStepEndDate
+	0x00400251: remove,     // PerformedProcedureStepEndTime
+	0x00400253: remove,     // PerformedProcedureStepID
+	0x00400254: remove,     // PerformedProcedureStepDescription
+	0x00400255: remove,     // PerformedProcedureTypeDescription
+	0x00400275: remove,     // RequestAttributesSequence
+	0x00400280: remove,     // CommentsOnThePerformedProcedureStep
+	0x00400310: remove,     // CommentsOnRadiationDose
+	0x00400555: remove,     // AcquisitionContextSequence
+	0x00401001: remove,     // RequestedProcedureID
+	0x00401002: remove,     // ReasonForTheRequestedProcedure
+	0x00401004: remove,     // PatientTransportArrangements
+	0x00401005: remove,     // RequestedProcedureLocation
+	0x00401010: remove,     // NamesOfIntendedRecipientsOfResults
+	0x00401011: remove,     // IntendedRecipientsOfResultsIdentificationSequence
+	0x00401101: remove,     // PersonIdentificationCodeSequence
+	0x00401102: remove,     // PersonAddress
+	0x00401103: remove,     // PersonTelephoneNumbers
+	0x00401104: remove,     // PersonTelecomInformation
+	0x00401400: remove,     // RequestedProcedureComments
+	0x00402001: remove,     // ReasonForTheImagingServiceRequest
+	0x00402008: remove,     // OrderEnteredBy
+	0x00402009: remove,     // OrderEntererLocation
+	0x00402010: remove,     // OrderCallbackPhoneNumber
+	0x00402011: remove,     // OrderCallbackTelecomInformation
+	0x00402016: empty,      // PlacerOrderNumberImagingServiceRequest
+	0x00402017: empty,      // FillerOrderNumberImagingServiceRequest
+	0x00402400: remove,     // ImagingServiceRequestComments
+	0x00404023: replaceUID, // ReferencedGeneralPurposeScheduledProcedureStepTransactionUID
+	0x00404025: remove,     // ScheduledStationNameCodeSequence
+	0x00404027: remove,     // ScheduledStationGeographicLocationCodeSequence
+	0x00404028: remove,     // PerformedStationNameCodeSequence
+	0x00404030: remove,     // PerformedStationGeographicLocationCodeSequence
+	0x00404034: remove,     // ScheduledHumanPerformersSequence
+	0x00404035: remove,     // ActualHumanPerformersSequence
+	0x00404036: remove,     // HumanPerformerOrganization
+	0x00404037: remove,     // HumanPerformerName
+	0x0040A027: remove,     // VerifyingOrganization
+	0x0040A030: dummy,      // VerificationDateTime
+	0x0040A032: dummy,      // ObservationDateTime
+	0x0040A073: dummy,      // VerifyingObserverSequence
+	0x0040A075: dummy,      // VerifyingObserverName
+	0x0040A078: remove,     // AuthorObserverSequence
+	0x0040A07A: remove,     // ParticipantSequence
+	0x0040A07C: remove,     // CustodialOrganizationSequence
+	0x0040A088: empty,      // VerifyingObserverIdentificationCodeSequence
+	0x0040A121: dummy,      // Date
+	0x0040A122: dummy,      // Time
+	0x0040A123: dummy,      // PersonName
+	0x0040A124: replaceUID, // UID
+	0x0040A13A: dummy,      // ReferencedDateTime
+	0x0040A171: replaceUID, // ObservationUID
+	0x0040A172: replaceUID, // ReferencedObservationUIDTrial
+	0x0040A192: remove,     // ObservationDateTrial
+	0x0040A193: remove,     // ObservationTimeTrial
+	0x0040A307: remove,     // CurrentObserverTrial
+	0x0040A352: remove,     // VerbalSourceTrial
+	0x0040A353: remove,     // AddressTrial
+	0x0040A354: remove,     // TelephoneNumberTrial
+	0x0040A358: remove,     // VerbalSourceIdentifierCodeSequenceTrial
+	0x0040A730: remove,     // ContentSequence
+	0x0040DB0C: replaceUID, // TemplateExtensionOrganizationUID
+	0x0040DB0D: replaceUID, // TemplateExtensionCreatorUID
+
+	0x00620021: replaceUID, // TrackingUID
+
+	0x00700001: dummy,      // GraphicAnnotationSequence
+	0x00700084: empty,      // ContentCreatorName
+	0x00700086: remove,     // ContentCreatorIdentificationCodeSequence
+	0x0070031A: replaceUID, // FiducialUID
+
+	0x00880140: replaceUID, // StorageMediaFileSetUID
+	0x00880200: remove,     // IconImageSequence
+	0x00880904: remove,     // TopicTitle
+	0x00880906: remove,     // TopicSubject
+	0x00880910: remove,     // TopicAuthor
+	0x00880912: remove,     // TopicKeywords
+
+	0x04000100: remove, // DigitalSignatureUID
+	0x04000402: remove, // ReferencedDigitalSignatureSequence
+	0x04000403: remove, // ReferencedSOPInstanceMACSequence
+	0x04000404: remove, // MAC
+	0x04000550: remove, // ModifiedAttributesSequence
+	0x04000561: remove, // OriginalAttributesSequence
+
+	0x20300020: remove, // TextString
+
+	0x30060002: dummy,      // StructureSetLabel
+	0x30060004: remove,     // StructureSetName
+	0x30060006: remove,     // StructureSetDescription
+	0x30060008: remove,     // StructureSetDate
+	0x30060009: remove,     // StructureSetTime
+	0x30060024: replaceUID, // ReferencedFrameOfReferenceUID
+	0x30060026: remove,     // ROIName
+	0x30060028: remove,     // ROIDescription
+	0x30060038: remove,     // ROIGenerationDescription
+	0x30060085: remove,     // ROIObservationLabel
+	0x300600A6: empty,      // ROIInterpreter
+	0x300600C2: replaceUID, // RelatedFrameOfReferenceUID
+
+	0x300A0002: dummy,      // RTPlanLabel
+	0x300A0003: remove,     // RTPlanName
+	0x300A0004: remove,     // RTPlanDescription
+	0x300A0006: remove,     // RTPlanDate
+	0x300A0007: remove,     // RTPlanTime
+	0x300A000E: remove,     // PrescriptionDescription
+	0x300A0013: replaceUID, // DoseReferenceUID
+	0x300A0016: remove,     // DoseReferenceDescription
+
+	0x300E0004: remove, // ReviewDate
+	0x300E0005: remove, // ReviewTime
+	0x300E0008: remove, // ReviewerName
+
+	0x40000010: remove, // Arbitrary
+	0x40004000: remove, // TextComments
+
+	0x40080042: remove, // ResultsIDIssuer
+	0x40080102: remove, // InterpretationRecorder
+	0x4008010A: remove, // InterpretationTranscriber
+	0x4008010B: remove, // InterpretationText
+	0x4008010C: remove, // InterpretationAuthor
+	0x40080111: remove, // InterpretationApproverSequence
+	0x40080114: remove, // PhysicianApprovingInterpretation
+	0x40080115: remove, // InterpretationDiagnosisDescription
+	0x40080118: remove, // ResultsDistributionListSequence
+	0x40080119: remove, // DistributionName
+	0x4008011A: remove, // DistributionAddress
+	0x40080202: remove, // InterpretationIDIssuer
+	0x40080300: remove, // Impressions
+	0x40084000: remove, // ResultsComments
+
+	0xFFFAFFFA: remove, // DigitalSignaturesSequence
+}
+
+// descriptors attributes cleaned instead of removed by the Clean Descriptors Option.
+var descriptors = map[dicom.Tag]bool{
+	0x00081030: true, // StudyDescription
+	0x0008103E: true, // SeriesDescription
+	0x00081080: true, // AdmittingDiagnosesDescription
+	0x00082111: true, // DerivationDescription
+	0x001021B0: true, // AdditionalPatientHistory
+	0x00104000: true, // PatientComments
+	0x00180010: true, // ContrastBolusAgent
+	0x00181030: true, // ProtocolName
+	0x00181400: true, // AcquisitionDeviceProcessingDescription
+	0x00184000: true, // AcquisitionComments
+	0x00189424: true, // AcquisitionProtocolDescription
+	0x00204000: true, // ImageComments
+	0x00321060: true, // RequestedProcedureDescription
+	0x00324000: true, // StudyComments
+	0x00380500: true, // PatientState
+	0x00384000: true, // VisitComments
+	0x00400007: true, // ScheduledProcedureStepDescription
+	0x00400254: true, // PerformedProcedureStepDescription
+	0x00401400: true, // RequestedProcedureComments
+	0x00402400: true, // ImagingServiceRequestComments
+}
+
+// identifying attributes whose values are removed from the descriptors when
+// they are cleaned.
+var identifying = []dicom.Tag{
+	dicom.PatientName,
+	dicom.PatientID,
+	dicom.PatientBirthDate,
+	dicom.AccessionNumber,
+	0x00080080, // InstitutionName
+	0x00080090, // ReferringPhysicianName
+	0x00101000, // OtherPatientIDs
+	0x00101001, // OtherPatientNames
+}
+
+// Codes of the profile and its options in DICOM Controlled Terminology (CID 7050).
+const (
+	codeBasicProfile     = "113100"
+	codeCleanDescriptors = "113105"
+	codeRetainDates      = "113106"
+	codeRetainUIDs       = "113110"
+)
+
+var codeMeanings = map[string]string{
+	codeBasicProfile:     "Basic Application Confidentiality Profile",
+	codeCleanDescriptors: "Clean Descriptors Option",
+	codeRetainDates:      "Retain Longitudinal Temporal Information Full Dates Option",
+	codeRetainUIDs:       "Retain UIDs Option",
+}
@@ -0,0 +1,74 @@
+package dicomweb
+
+import "net/http"
+
+// DeleteType defines the resource to delete.
+type DeleteType int
+
+const (
+	// DeleteStudy deletes a study, with all its series and instances.
+	DeleteStudy DeleteType = iota + 1
+	// DeleteSeries deletes a series, with all its instances.
+	DeleteSeries
+	// DeleteInstance deletes an instance.
+	DeleteInstance
+)
+
+// String returns the name of the delete type.
+func (t DeleteType) String() string {
+	switch t {
+	case DeleteStudy:
+		return "DeleteStudy"
+	case DeleteSeries:
+		return "DeleteSeries"
+	case DeleteInstance:
+		return "DeleteInstance"
+	}
+	return "unknown"
+}
+
+// DeleteRequest defines the resource to delete, following the hierarchy of
+// the WADO resources.
+type DeleteRequest struct {
+	Type              DeleteType
+	StudyInstanceUID  string
+	SeriesInstanceUID string
+	SOPInstanceUID    string
+}
+
+// Delete deletes the study, series or instance with a DELETE request to the
+// WADO endpoint, which most archives support although it is not part of the
+// DICOMweb standard. It succeeds once the server deleted the resource, or
+// accepted to delete it later with 202 Accepted, after which the responses
+// cached by WithCache for the study of the resource, and all those cached by
+// WithQueryCache, are evicted.
+func (c *Client) Delete(req DeleteRequest) error {
+	if err := req.Validate(); err != nil {
+		return err
+	}
+	path, err := resourcePath(req.StudyInstanceUID, req.SeriesInstanceUID, req.SOPInstanceUID)
+	if err != nil {
+		return err
+	}
+
+	r, err := http.NewRequest("DELETE", c.wadoEndpoint+path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.do(&Operation{
+		Service:           WADOService,
+		DeleteType:        req.Type,
+		StudyInstanceUID:  req.StudyInstanceUID,
+		SeriesInstanceUID: req.SeriesInstanceUID,
+		SOPInstanceUID:    req.SOPInstanceUID,
+	}, r)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return statusError(resp)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package dicomweb_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/toastcheng/dicomweb-go/dicomweb"
+)
+
+func TestDelete(t *testing.T) {
+	s := newParallelTestServer()
+	defer s.Close()
+	c := s.Client()
+
+	err := c.Delete(dicomweb.DeleteRequest{Type: dicomweb.DeleteInstance, StudyInstanceUID: "1.2.1", SeriesInstanceUID: "1.2.1.1", SOPInstanceUID: "1.2.1.1.1"})
+	assert.NoError(t, err)
+	assert.Equal(t, 4, s.Len())
+
+	err = c.Delete(dicomweb.DeleteRequest{Type: dicomweb.DeleteSeries, StudyInstanceUID: "1.2.1", SeriesInstanceUID: "1.2.1.3"})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, s.Len())
+
+	err = c.Delete(dicomweb.DeleteRequest{Type: dicomweb.DeleteSeries, StudyInstanceUID: "1.2.1", SeriesInstanceUID: "1.2.1.3"})
+	assert.EqualError(t, err, "404 Not Found")
+
+	err = c.Delete(dicomweb.DeleteRequest{Type: dicomweb.DeleteStudy, StudyInstanceUID: "1.2.1"})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, s.Len())
+
+	err = c.Delete(dicomweb.DeleteRequest{Type: dicomweb.DeleteSeries, StudyInstanceUID: "1.2.1"})
+	assert.EqualError(t, err, "invalid SeriesInstanceUID: required by DeleteSeries")
+	err = c.Delete(dicomweb.DeleteRequest{Type: dicomweb.DeleteStudy, StudyInstanceUID: "1.2.1", SOPInstanceUID: "1.2.1.1.1"})
+	assert.EqualError(t, err, `invalid SOPInstanceUID "1.2.1.1.1": not allowed by DeleteStudy`)
+	err = c.Delete(dicomweb.DeleteRequest{})
+	assert.EqualError(t, err, `invalid Type "0": unknown delete type`)
+}
+
+func TestDeleteStatus(t *testing.T) {
+	status := http.StatusAccepted
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "DELETE", r.Method)
+		assert.Equal(t, "/studies/1.2.3", r.URL.Path)
+		if status == http.StatusConflict {
+			w.Header().Set("Warning", `299 pacs: "study is locked"`)
+		}
+		w.WriteHeader(status)
+	}))
+	defer ts.Close()
+	c := dicomweb.NewClient(dicomweb.ClientOption{WADOEndpoint: ts.URL})
+	req := dicomweb.DeleteRequest{Type: dicomweb.DeleteStudy, StudyInstanceUID: "1.2.3"}
+
+	assert.NoError(t, c.Delete(req))
+	status = http.StatusConflict
+	assert.EqualError(t, c.Delete(req), `409 Conflict: 299 pacs: "study is locked"`)
+	status = http.StatusMethodNotAllowed
+	assert.EqualError(t, c.Delete(req), "405 Method Not Allowed")
+}
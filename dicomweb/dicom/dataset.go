@@ -0,0 +1,261 @@
+package dicom
+
+import (
+	"encoding/binary"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Element defines a data element. Values are kept encoded in little endian,
+// as they are in the file, except sequences which hold their items, and
+// encapsulated pixel data which holds its fragments.
+type Element struct {
+	Tag Tag
+	VR  string
+	// Value encoded value, padded to an even length.
+	Value []byte
+	// Items items of a sequence (SQ).
+	Items []*Dataset
+	// Fragments fragments of encapsulated pixel data, the first one being the basic offset table.
+	Fragments [][]byte
+}
+
+// Dataset defines a data set, its elements are kept sorted by tag.
+type Dataset struct {
+	Elements []*Element
+}
+
+// Get returns the element of the given tag, or nil.
+func (ds *Dataset) Get(t Tag) *Element {
+	i := ds.search(t)
+	if i < len(ds.Elements) && ds.Elements[i].Tag == t {
+		return ds.Elements[i]
+	}
+	return nil
+}
+
+// Set adds the element to the data set, replacing the element of the same tag if any.
+func (ds *Dataset) Set(e *Element) {
+	i := ds.search(e.Tag)
+	if i < len(ds.Elements) && ds.Elements[i].Tag == e.Tag {
+		ds.Elements[i] = e
+		return
+	}
+	ds.Elements = append(ds.Elements, nil)
+	copy(ds.Elements[i+1:], ds.Elements[i:])
+	ds.Elements[i] = e
+}
+
+// Remove removes the element of the given tag, if any.
+func (ds *Dataset) Remove(t Tag) {
+	i := ds.search(t)
+	if i < len(ds.Elements) && ds.Elements[i].Tag == t {
+		ds.Elements = append(ds.Elements[:i], ds.Elements[i+1:]...)
+	}
+}
+
+// String returns the string value of the element of the given tag, or "".
+func (ds *Dataset) String(t Tag) string {
+	e := ds.Get(t)
+	if e == nil {
+		return ""
+	}
+	return e.String()
+}
+
+// Int returns the first integer value of the element of the given tag, or 0.
+func (ds *Dataset) Int(t Tag) int {
+	e := ds.Get(t)
+	if e == nil {
+		return 0
+	}
+	ints := e.Ints()
+	if len(ints) == 0 {
+		return 0
+	}
+	return int(ints[0])
+}
+
+// Copy returns a deep copy of the data set.
+func (ds *Dataset) Copy() *Dataset {
+	copied := &Dataset{Elements: make([]*Element, len(ds.Elements))}
+	for i, e := range ds.Elements {
+		copied.Elements[i] = e.Copy()
+	}
+	return copied
+}
+
+// Meta returns the file meta information (group 0002) of the data set.
+func (ds *Dataset) Meta() *Dataset {
+	meta := &Dataset{}
+	for _, e := range ds.Elements {
+		if e.Tag.Group() == 0x0002 {
+			meta.Elements = append(meta.Elements, e)
+		}
+	}
+	return meta
+}
+
+func (ds *Dataset) search(t Tag) int {
+	return sort.Search(len(ds.Elements), func(i int) bool {
+		return ds.Elements[i].Tag >= t
+	})
+}
+
+// Copy returns a deep copy of the element.
+func (e *Element) Copy() *Element {
+	copied := &Element{Tag: e.Tag, VR: e.VR}
+	if e.Value != nil {
+		copied.Value = append([]byte{}, e.Value...)
+	}
+	for _, item := range e.Items {
+		copied.Items = append(copied.Items, item.Copy())
+	}
+	for _, f := range e.Fragments {
+		copied.Fragments = append(copied.Fragments, append([]byte{}, f...))
+	}
+	return copied
+}
+
+// IsString reports whether the VR of the element holds character strings.
+func (e *Element) IsString() bool {
+	switch e.VR {
+	case "AE", "AS", "CS", "DA", "DS", "DT", "IS", "LO", "LT", "PN", "SH", "ST", "TM", "UC", "UI", "UR", "UT":
+		return true
+	}
+	return false
+}
+
+// String returns the value of a string element without its padding. Multiple
+// values are kept separated by backslashes.
+func (e *Element) String() string {
+	if !e.IsString() {
+		return ""
+	}
+	return strings.TrimRight(string(e.Value), " \x00")
+}
+
+// Strings returns the values of a string element.
+func (e *Element) Strings() []string {
+	s := e.String()
+	if s == "" {
+		return nil
+	}
+	if e.VR == "LT" || e.VR == "ST" || e.VR == "UT" || e.VR == "UR" {
+		return []string{s}
+	}
+	values := strings.Split(s, "\\")
+	for i, v := range values {
+		values[i] = strings.TrimSpace(v)
+	}
+	return values
+}
+
+// Ints returns the values of an integer element (IS, SS, US, SL, UL, SV, UV).
+func (e *Element) Ints() []int64 {
+	values := []int64{}
+	switch e.VR {
+	case "IS":
+		for _, s := range e.Strings() {
+			v, err := strconv.ParseInt(s, 10, 64)
+			if err == nil {
+				values = append(values, v)
+			}
+		}
+	case "US":
+		for i := 0; i+2 <= len(e.Value); i += 2 {
+			values = append(values, int64(binary.LittleEndian.Uint16(e.Value[i:])))
+		}
+	case "SS":
+		for i := 0; i+2 <= len(e.Value); i += 2 {
+			values = append(values, int64(int16(binary.LittleEndian.Uint16(e.Value[i:]))))
+		}
+	case "UL":
+		for i := 0; i+4 <= len(e.Value); i += 4 {
+			values = append(values, int64(binary.LittleEndian.Uint32(e.Value[i:])))
+		}
+	case "SL":
+		for i := 0; i+4 <= len(e.Value); i += 4 {
+			values = append(values, int64(int32(binary.LittleEndian.Uint32(e.Value[i:]))))
+		}
+	case "SV", "UV":
+		for i := 0; i+8 <= len(e.Value); i += 8 {
+			values = append(values, int64(binary.LittleEndian.Uint64(e.Value[i:])))
+		}
+	}
+	return values
+}
+
+// Floats returns the values of a decimal element (DS, FL, FD).
+func (e *Element) Floats() []float64 {
+	values := []float64{}
+	switch e.VR {
+	case "DS":
+		for _, s := range e.Strings() {
+			v, err := strconv.ParseFloat(s, 64)
+			if err == nil {
+				values = append(values, v)
+			}
+		}
+	case "FL":
+		for i := 0; i+4 <= len(e.Value); i += 4 {
+			values = append(values, float64(math.Float32frombits(binary.LittleEndian.Uint32(e.Value[i:]))))
+		}
+	case "FD":
+		for i := 0; i+8 <= len(e.Value); i += 8 {
+			values = append(values, math.Float64frombits(binary.LittleEndian.Uint64(e.Value[i:])))
+		}
+	}
+	return values
+}
+
+// NewString creates a string element, joining multiple values with backslashes.
+// The VR is looked up in the data dictionary when empty.
+func NewString(t Tag, vr string, values ...string) *Element {
+	if vr == "" {
+		vr = LookupVR(t)
+	}
+	v := []byte(strings.Join(values, "\\"))
+	if len(v)%2 == 1 {
+		if vr == "UI" {
+			v = append(v, 0)
+		} else {
+			v = append(v, ' ')
+		}
+	}
+	return &Element{Tag: t, VR: vr, Value: v}
+}
+
+// NewUint16 creates a US element.
+func NewUint16(t Tag, values ...uint16) *Element {
+	v := make([]byte, 2*len(values))
+	for i, value := range values {
+		binary.LittleEndian.PutUint16(v[2*i:], value)
+	}
+	return &Element{Tag: t, VR: "US", Value: v}
+}
+
+// NewUint32 creates a UL element.
+func NewUint32(t Tag, values ...uint32) *Element {
+	v := make([]byte, 4*len(values))
+	for i, value := range values {
+		binary.LittleEndian.PutUint32(v[4*i:], value)
+	}
+	return &Element{Tag: t, VR: "UL", Value: v}
+}
+
+// NewBytes creates a binary element, padding the value to an even length.
+func NewBytes(t Tag, vr string, value []byte) *Element {
+	v := append([]byte{}, value...)
+	if len(v)%2 == 1 {
+		v = append(v, 0)
+	}
+	return &Element{Tag: t, VR: vr, Value: v}
+}
+
+// NewSequence creates a sequence element.
+func NewSequence(t Tag, items ...*Dataset) *Element {
+	return &Element{Tag: t, VR: "SQ", Items: items}
+}
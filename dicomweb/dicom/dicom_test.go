@@ -0,0 +1,222 @@
+package dicom
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestDataset(ts string) *Dataset {
+	ds := &Dataset{}
+	ds.Set(NewString(TransferSyntaxUID, "UI", ts))
+	ds.Set(NewString(SOPClassUID, "UI", "1.2.840.10008.5.1.4.1.1.7"))
+	ds.Set(NewString(SOPInstanceUID, "UI", "1.2.3.4.5"))
+	ds.Set(NewString(PatientName, "PN", "Doe^John"))
+	ds.Set(NewString(StudyInstanceUID, "UI", "1.2.3"))
+	ds.Set(NewString(SeriesInstanceUID, "UI", "1.2.3.4"))
+	ds.Set(NewString(InstanceNumber, "IS", "7"))
+	ds.Set(NewUint16(Rows, 2))
+	ds.Set(NewUint16(Columns, 2))
+	ds.Set(NewSequence(0x00081115,
+		&Dataset{Elements: []*Element{NewString(SeriesInstanceUID, "UI", "9.8.7")}},
+		&Dataset{Elements: []*Element{NewString(SeriesInstanceUID, "UI", "9.8.7.6")}},
+	))
+	ds.Set(NewBytes(PixelData, "OW", []byte{1, 2, 3, 4, 5, 6, 7, 8}))
+	return ds
+}
+
+func TestEncodeParseExplicit(t *testing.T) {
+	b, err := Encode(newTestDataset(ExplicitVRLittleEndian))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "DICM", string(b[128:132]))
+
+	ds, err := Parse(b)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "1.2.3.4.5", ds.String(MediaStorageSOPInstanceUID))
+	assert.Equal(t, "1.2.840.10008.5.1.4.1.1.7", ds.String(MediaStorageSOPClassUID))
+	assert.Equal(t, "Doe^John", ds.String(PatientName))
+	assert.Equal(t, "1.2.3.4.5", ds.String(SOPInstanceUID))
+	assert.Equal(t, 7, ds.Int(InstanceNumber))
+	assert.Equal(t, 2, ds.Int(Rows))
+	seq := ds.Get(0x00081115)
+	if assert.NotNil(t, seq) && assert.Len(t, seq.Items, 2) {
+		assert.Equal(t, "9.8.7.6", seq.Items[1].String(SeriesInstanceUID))
+	}
+	assert.Equal(t, []byte{1, 2, 3, 4, 5, 6, 7, 8}, ds.Get(PixelData).Value)
+
+	again, err := Encode(ds)
+	assert.NoError(t, err)
+	assert.Equal(t, b, again)
+}
+
+func TestEncodeParseImplicit(t *testing.T) {
+	b, err := Encode(newTestDataset(ImplicitVRLittleEndian))
+	if !assert.NoError(t, err) {
+		return
+	}
+	ds, err := Parse(b)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "PN", ds.Get(PatientName).VR)
+	assert.Equal(t, "Doe^John", ds.String(PatientName))
+	assert.Len(t, ds.Get(0x00081115).Items, 2)
+	assert.Equal(t, 2, ds.Int(Columns))
+}
+
+func TestParseEncapsulated(t *testing.T) {
+	ds := newTestDataset("1.2.840.10008.1.2.4.50")
+	ds.Set(&Element{Tag: PixelData, VR: "OB", Fragments: [][]byte{{}, {0xFF, 0xD8}, {0xFF, 0xD9}}})
+	b, err := Encode(ds)
+	if !assert.NoError(t, err) {
+		return
+	}
+	parsed, err := Parse(b)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, [][]byte{{}, {0xFF, 0xD8}, {0xFF, 0xD9}}, parsed.Get(PixelData).Fragments)
+}
+
+func TestParseInvalid(t *testing.T) {
+	_, err := Parse([]byte("not dicom"))
+	assert.Equal(t, ErrNotPart10, err)
+
+	b, _ := Encode(newTestDataset(ExplicitVRLittleEndian))
+	_, err = Parse(b[:len(b)-3])
+	assert.Error(t, err)
+
+	ds := newTestDataset(ExplicitVRBigEndian)
+	_, err = Encode(ds)
+	assert.EqualError(t, err, "dicom: unsupported transfer syntax 1.2.840.10008.1.2.2")
+}
+
+func TestReadFileMeta(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dicom")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	name := filepath.Join(dir, "test.dcm")
+	assert.NoError(t, WriteFile(name, newTestDataset(ExplicitVRLittleEndian)))
+
+	meta, err := ReadFileMeta(name)
+	assert.NoError(t, err)
+	assert.Equal(t, "1.2.3.4.5", meta.String(MediaStorageSOPInstanceUID))
+	assert.Nil(t, meta.Get(PatientName))
+
+	// a group length past the end of the file.
+	b, err := ioutil.ReadFile(name)
+	assert.NoError(t, err)
+	binary.LittleEndian.PutUint32(b[140:], 0xFFFFFFF0)
+	assert.NoError(t, ioutil.WriteFile(name, b, 0644))
+	_, err = ReadFileMeta(name)
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+}
+
+func TestDatasetJSON(t *testing.T) {
+	b, err := json.Marshal(newTestDataset(ExplicitVRLittleEndian))
+	if !assert.NoError(t, err) {
+		return
+	}
+	m := map[string]interface{}{}
+	assert.NoError(t, json.Unmarshal(b, &m))
+
+	assert.NotContains(t, m, "00020010")
+	assert.Equal(t, map[string]interface{}{
+		"vr":    "PN",
+		"Value": []interface{}{map[string]interface{}{"Alphabetic": "Doe^John"}},
+	}, m["00100010"])
+	assert.Equal(t, map[string]interface{}{
+		"vr":    "IS",
+		"Value": []interface{}{float64(7)},
+	}, m["00200013"])
+	assert.Equal(t, map[string]interface{}{"vr": "OW"}, m["7FE00010"])
+	seq := m["00081115"].(map[string]interface{})["Value"].([]interface{})
+	assert.Len(t, seq, 2)
+}
+
+func TestTag(t *testing.T) {
+	tag, err := ParseTag("0020000d")
+	assert.NoError(t, err)
+	assert.Equal(t, StudyInstanceUID, tag)
+	assert.Equal(t, "0020000D", tag.Hex())
+	assert.Equal(t, "(0020,000D)", tag.String())
+	assert.Equal(t, "StudyInstanceUID", Keyword(tag))
+	kw, ok := LookupKeyword("PatientID")
+	assert.True(t, ok)
+	assert.Equal(t, PatientID, kw)
+	assert.Equal(t, "UN", LookupVR(0x00091001))
+	assert.True(t, Tag(0x00091001).IsPrivate())
+
+	_, err = ParseTag("0020")
+	assert.Error(t, err)
+}
+
+func TestEncodeDICOMDIR(t *testing.T) {
+	files := []DirectoryFile{}
+	for _, uids := range [][3]string{
+		{"1.2.3", "1.2.3.4", "1.2.3.4.5"},
+		{"1.2.3", "1.2.3.4", "1.2.3.4.6"},
+		{"1.2.3", "1.2.3.7", "1.2.3.7.1"},
+	} {
+		ds := newTestDataset(ExplicitVRLittleEndian)
+		ds.Set(NewString(StudyInstanceUID, "UI", uids[0]))
+		ds.Set(NewString(SeriesInstanceUID, "UI", uids[1]))
+		ds.Set(NewString(SOPInstanceUID, "UI", uids[2]))
+		ds.Set(NewString(MediaStorageSOPInstanceUID, "UI", uids[2]))
+		files = append(files, DirectoryFile{ID: []string{uids[1], uids[2]}, Dataset: ds})
+	}
+
+	b, err := EncodeDICOMDIR("set", files)
+	assert.NoError(t, err)
+	ds, err := Parse(b)
+	assert.NoError(t, err)
+	assert.Equal(t, MediaStorageDirectoryStorage, ds.String(MediaStorageSOPClassUID))
+	assert.Equal(t, "SET", ds.String(FileSetID))
+
+	// every offset points to the item tag of the record it refers to.
+	records := ds.Get(DirectoryRecordSequence).Items
+	for _, r := range records {
+		for _, tag := range []Tag{OffsetOfTheNextDirectoryRecord, OffsetOfReferencedLowerLevelDirectoryEntity} {
+			if offset := r.Int(tag); offset != 0 {
+				assert.Equal(t, []byte{0xFE, 0xFF, 0x00, 0xE0}, b[offset:offset+4])
+			}
+		}
+	}
+	first := ds.Int(OffsetOfTheFirstDirectoryRecord)
+	assert.Equal(t, []byte{0xFE, 0xFF, 0x00, 0xE0}, b[first:first+4])
+	assert.Equal(t, ds.Int(OffsetOfTheFirstDirectoryRecord), ds.Int(OffsetOfTheLastDirectoryRecord))
+
+	types := []string{}
+	for _, r := range records {
+		types = append(types, r.String(DirectoryRecordType))
+	}
+	assert.Equal(t, []string{"PATIENT", "STUDY", "SERIES", "IMAGE", "IMAGE", "SERIES", "IMAGE"}, types)
+	assert.Equal(t, []string{"1.2.3.4", "1.2.3.4.6"}, records[4].Get(ReferencedFileID).Strings())
+	// the first series refers to the second one, and to its first image.
+	assert.Equal(t, "1.2.3.7", recordAt(b, records, records[2].Int(OffsetOfTheNextDirectoryRecord)).String(SeriesInstanceUID))
+	assert.Equal(t, "1.2.3.4.5", recordAt(b, records, records[2].Int(OffsetOfReferencedLowerLevelDirectoryEntity)).String(ReferencedSOPInstanceUIDInFile))
+}
+
+// recordAt returns the record encoded at the offset of the file.
+func recordAt(b []byte, records []*Dataset, offset int) *Dataset {
+	for _, r := range records {
+		content, _ := EncodeDataset(r)
+		if offset+8+len(content) <= len(b) && string(b[offset+8:offset+8+len(content)]) == string(content) {
+			return r
+		}
+	}
+	return &Dataset{}
+}
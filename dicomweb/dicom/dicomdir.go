@@ -0,0 +1,154 @@
+package dicom
+
+import (
+	"strings"
+
+	"github.com/toastcheng/dicomweb-go/dicomweb/uid"
+)
+
+// MediaStorageDirectoryStorage is the SOP Class UID of a DICOMDIR.
+const MediaStorageDirectoryStorage = "1.2.840.10008.1.3.10"
+
+// Tags of the DICOMDIR.
+const (
+	FileSetID                                   Tag = 0x00041130
+	OffsetOfTheFirstDirectoryRecord             Tag = 0x00041200
+	OffsetOfTheLastDirectoryRecord              Tag = 0x00041202
+	FileSetConsistencyFlag                      Tag = 0x00041212
+	DirectoryRecordSequence                     Tag = 0x00041220
+	OffsetOfTheNextDirectoryRecord              Tag = 0x00041400
+	RecordInUseFlag                             Tag = 0x00041410
+	OffsetOfReferencedLowerLevelDirectoryEntity Tag = 0x00041420
+	DirectoryRecordType                         Tag = 0x00041430
+	ReferencedFileID                            Tag = 0x00041500
+	ReferencedSOPClassUIDInFile                 Tag = 0x00041510
+	ReferencedSOPInstanceUIDInFile              Tag = 0x00041511
+	ReferencedTransferSyntaxUIDInFile           Tag = 0x00041512
+)
+
+// Attributes copied from the instances to the directory records of each type.
+var (
+	patientRecordAttributes = []Tag{SpecificCharacterSet, PatientName, PatientID, PatientBirthDate, PatientSex}
+	studyRecordAttributes   = []Tag{SpecificCharacterSet, StudyDate, StudyTime, AccessionNumber, StudyDescription, StudyInstanceUID, StudyID}
+	seriesRecordAttributes  = []Tag{SpecificCharacterSet, Modality, SeriesDescription, SeriesInstanceUID, SeriesNumber}
+	imageRecordAttributes   = []Tag{SpecificCharacterSet, InstanceNumber, Rows, Columns, NumberOfFrames}
+)
+
+// DirectoryFile is an instance file of a file set.
+type DirectoryFile struct {
+	// ID path components of the file, relative to the DICOMDIR.
+	ID []string
+	// Dataset data set of the instance, at least its file meta information and
+	// the attributes of the directory records.
+	Dataset *Dataset
+}
+
+// record is a directory record with its lower level records.
+type record struct {
+	ds       *Dataset
+	key      string
+	children []*record
+}
+
+func (r *record) child(key, recordType string, from *Dataset, tags []Tag) *record {
+	for _, c := range r.children {
+		if c.key == key {
+			return c
+		}
+	}
+	c := &record{ds: &Dataset{}, key: key}
+	c.ds.Set(NewString(DirectoryRecordType, "CS", recordType))
+	for _, t := range tags {
+		if e := from.Get(t); e != nil {
+			c.ds.Set(e.Copy())
+		}
+	}
+	r.children = append(r.children, c)
+	return c
+}
+
+// EncodeDICOMDIR encodes the DICOMDIR of the file set as a DICOM Part 10 file,
+// with a PATIENT, STUDY, SERIES and IMAGE record hierarchy over the files.
+//
+// PS3.10 restricts file ID components to 8 characters out of uppercase
+// letters, digits and underscore; the components are written as given.
+func EncodeDICOMDIR(fileSetID string, files []DirectoryFile) ([]byte, error) {
+	root := &record{}
+	for _, f := range files {
+		ds := f.Dataset
+		patient := root.child(ds.String(PatientID), "PATIENT", ds, patientRecordAttributes)
+		study := patient.child(ds.String(StudyInstanceUID), "STUDY", ds, studyRecordAttributes)
+		series := study.child(ds.String(SeriesInstanceUID), "SERIES", ds, seriesRecordAttributes)
+		image := series.child(ds.String(SOPInstanceUID), "IMAGE", ds, imageRecordAttributes)
+		image.ds.Set(NewString(ReferencedFileID, "CS", f.ID...))
+		image.ds.Set(NewString(ReferencedSOPClassUIDInFile, "UI", ds.String(MediaStorageSOPClassUID)))
+		image.ds.Set(NewString(ReferencedSOPInstanceUIDInFile, "UI", ds.String(MediaStorageSOPInstanceUID)))
+		image.ds.Set(NewString(ReferencedTransferSyntaxUIDInFile, "UI", ds.String(TransferSyntaxUID)))
+	}
+
+	// records are listed depth first, each referring to its next sibling and
+	// to its first child by the offset of the item in the file.
+	records := []*record{}
+	var walk func(r *record)
+	walk = func(r *record) {
+		for _, c := range r.children {
+			records = append(records, c)
+			walk(c)
+		}
+	}
+	walk(root)
+
+	items := []*Dataset{}
+	for _, r := range records {
+		r.ds.Set(NewUint32(OffsetOfTheNextDirectoryRecord, 0))
+		r.ds.Set(NewUint16(RecordInUseFlag, 0xFFFF))
+		r.ds.Set(NewUint32(OffsetOfReferencedLowerLevelDirectoryEntity, 0))
+		items = append(items, r.ds)
+	}
+	ds := &Dataset{}
+	ds.Set(NewString(MediaStorageSOPClassUID, "UI", MediaStorageDirectoryStorage))
+	ds.Set(NewString(MediaStorageSOPInstanceUID, "UI", uid.New()))
+	ds.Set(NewString(TransferSyntaxUID, "UI", ExplicitVRLittleEndian))
+	ds.Set(NewString(FileSetID, "CS", strings.ToUpper(fileSetID)))
+	ds.Set(NewUint32(OffsetOfTheFirstDirectoryRecord, 0))
+	ds.Set(NewUint32(OffsetOfTheLastDirectoryRecord, 0))
+	ds.Set(NewUint16(FileSetConsistencyFlag, 0))
+	ds.Set(NewSequence(DirectoryRecordSequence, items...))
+
+	// the offsets have a fixed size, so that the items of a first encoding
+	// keep their position once the offsets are set. The record sequence is
+	// the last element of the file.
+	b, err := Encode(ds)
+	if err != nil {
+		return nil, err
+	}
+	offsets := map[*record]uint32{}
+	end := len(b)
+	for i := len(records) - 1; i >= 0; i-- {
+		content, err := EncodeDataset(records[i].ds)
+		if err != nil {
+			return nil, err
+		}
+		end -= 8 + len(content)
+		offsets[records[i]] = uint32(end)
+	}
+
+	var link func(r *record)
+	link = func(r *record) {
+		for i, c := range r.children {
+			if i+1 < len(r.children) {
+				c.ds.Set(NewUint32(OffsetOfTheNextDirectoryRecord, offsets[r.children[i+1]]))
+			}
+			if len(c.children) > 0 {
+				c.ds.Set(NewUint32(OffsetOfReferencedLowerLevelDirectoryEntity, offsets[c.children[0]]))
+			}
+			link(c)
+		}
+	}
+	link(root)
+	if len(root.children) > 0 {
+		ds.Set(NewUint32(OffsetOfTheFirstDirectoryRecord, offsets[root.children[0]]))
+		ds.Set(NewUint32(OffsetOfTheLastDirectoryRecord, offsets[root.children[len(root.children)-1]]))
+	}
+	return Encode(ds)
+}
@@ -0,0 +1,272 @@
+package dicom
+
+type dictEntry struct {
+	VR      string
+	Keyword string
+}
+
+// dictionary holds the attributes of the data dictionary (PS3.6) the package
+// needs to know the VR of when reading implicit VR data sets. It is not
+// complete: attributes which are not listed are read as UN.
+var dictionary = map[Tag]dictEntry{
+	0x00020000: {"UL", "FileMetaInformationGroupLength"},
+	0x00020001: {"OB", "FileMetaInformationVersion"},
+	0x00020002: {"UI", "MediaStorageSOPClassUID"},
+	0x00020003: {"UI", "MediaStorageSOPInstanceUID"},
+	0x00020010: {"UI", "TransferSyntaxUID"},
+	0x00020012: {"UI", "ImplementationClassUID"},
+	0x00020013: {"SH", "ImplementationVersionName"},
+	0x00020016: {"AE", "SourceApplicationEntityTitle"},
+
+	0x00041130: {"CS", "FileSetID"},
+	0x00041200: {"UL", "OffsetOfTheFirstDirectoryRecordOfTheRootDirectoryEntity"},
+	0x00041202: {"UL", "OffsetOfTheLastDirectoryRecordOfTheRootDirectoryEntity"},
+	0x00041212: {"US", "FileSetConsistencyFlag"},
+	0x00041220: {"SQ", "DirectoryRecordSequence"},
+	0x00041400: {"UL", "OffsetOfTheNextDirectoryRecord"},
+	0x00041410: {"US", "RecordInUseFlag"},
+	0x00041420: {"UL", "OffsetOfReferencedLowerLevelDirectoryEntity"},
+	0x00041430: {"CS", "DirectoryRecordType"},
+	0x00041500: {"CS", "ReferencedFileID"},
+	0x00041510: {"UI", "ReferencedSOPClassUIDInFile"},
+	0x00041511: {"UI", "ReferencedSOPInstanceUIDInFile"},
+	0x00041512: {"UI", "ReferencedTransferSyntaxUIDInFile"},
+
+	0x00080005: {"CS", "SpecificCharacterSet"},
+	0x00080008: {"CS", "ImageType"},
+	0x00080012: {"DA", "InstanceCreationDate"},
+	0x00080013: {"TM", "InstanceCreationTime"},
+	0x00080014: {"UI", "InstanceCreatorUID"},
+	0x00080016: {"UI", "SOPClassUID"},
+	0x00080018: {"UI", "SOPInstanceUID"},
+	0x00080020: {"DA", "StudyDate"},
+	0x00080021: {"DA", "SeriesDate"},
+	0x00080022: {"DA", "AcquisitionDate"},
+	0x00080023: {"DA", "ContentDate"},
+	0x0008002A: {"DT", "AcquisitionDateTime"},
+	0x00080030: {"TM", "StudyTime"},
+	0x00080031: {"TM", "SeriesTime"},
+	0x00080032: {"TM", "AcquisitionTime"},
+	0x00080033: {"TM", "ContentTime"},
+	0x00080050: {"SH", "AccessionNumber"},
+	0x00080052: {"CS", "QueryRetrieveLevel"},
+	0x00080054: {"AE", "RetrieveAETitle"},
+	0x00080056: {"CS", "InstanceAvailability"},
+	0x00080060: {"CS", "Modality"},
+	0x00080061: {"CS", "ModalitiesInStudy"},
+	0x00080062: {"UI", "SOPClassesInStudy"},
+	0x00080070: {"LO", "Manufacturer"},
+	0x00080080: {"LO", "InstitutionName"},
+	0x00080081: {"ST", "InstitutionAddress"},
+	0x00080082: {"SQ", "InstitutionCodeSequence"},
+	0x00080090: {"PN", "ReferringPhysicianName"},
+	0x00080092: {"ST", "ReferringPhysicianAddress"},
+	0x00080094: {"SH", "ReferringPhysicianTelephoneNumbers"},
+	0x00080096: {"SQ", "ReferringPhysicianIdentificationSequence"},
+	0x00080100: {"SH", "CodeValue"},
+	0x00080102: {"SH", "CodingSchemeDesignator"},
+	0x00080104: {"LO", "CodeMeaning"},
+	0x00080201: {"SH", "TimezoneOffsetFromUTC"},
+	0x00081010: {"SH", "StationName"},
+	0x00081030: {"LO", "StudyDescription"},
+	0x00081032: {"SQ", "ProcedureCodeSequence"},
+	0x0008103E: {"LO", "SeriesDescription"},
+	0x00081040: {"LO", "InstitutionalDepartmentName"},
+	0x00081048: {"PN", "PhysiciansOfRecord"},
+	0x00081050: {"PN", "PerformingPhysicianName"},
+	0x00081060: {"PN", "NameOfPhysiciansReadingStudy"},
+	0x00081070: {"PN", "OperatorsName"},
+	0x00081080: {"LO", "AdmittingDiagnosesDescription"},
+	0x00081090: {"LO", "ManufacturerModelName"},
+	0x00081110: {"SQ", "ReferencedStudySequence"},
+	0x00081111: {"SQ", "ReferencedPerformedProcedureStepSequence"},
+	0x00081115: {"SQ", "ReferencedSeriesSequence"},
+	0x00081120: {"SQ", "ReferencedPatientSequence"},
+	0x00081140: {"SQ", "ReferencedImageSequence"},
+	0x00081150: {"UI", "ReferencedSOPClassUID"},
+	0x00081155: {"UI", "ReferencedSOPInstanceUID"},
+	0x00081190: {"UR", "RetrieveURL"},
+	0x00081195: {"UI", "TransactionUID"},
+	0x00081197: {"US", "FailureReason"},
+	0x00081198: {"SQ", "FailedSOPSequence"},
+	0x00081199: {"SQ", "ReferencedSOPSequence"},
+	0x00082111: {"ST", "DerivationDescription"},
+	0x00084000: {"LT", "IdentifyingComments"},
+
+	0x00100010: {"PN", "PatientName"},
+	0x00100020: {"LO", "PatientID"},
+	0x00100021: {"LO", "IssuerOfPatientID"},
+	0x00100030: {"DA", "PatientBirthDate"},
+	0x00100032: {"TM", "PatientBirthTime"},
+	0x00100040: {"CS", "PatientSex"},
+	0x00100050: {"SQ", "PatientInsurancePlanCodeSequence"},
+	0x00101000: {"LO", "OtherPatientIDs"},
+	0x00101001: {"PN", "OtherPatientNames"},
+	0x00101002: {"SQ", "OtherPatientIDsSequence"},
+	0x00101005: {"PN", "PatientBirthName"},
+	0x00101010: {"AS", "PatientAge"},
+	0x00101020: {"DS", "PatientSize"},
+	0x00101030: {"DS", "PatientWeight"},
+	0x00101040: {"LO", "PatientAddress"},
+	0x00101060: {"PN", "PatientMotherBirthName"},
+	0x00101090: {"LO", "MedicalRecordLocator"},
+	0x00102000: {"LO", "MedicalAlerts"},
+	0x00102110: {"LO", "Allergies"},
+	0x00102150: {"LO", "CountryOfResidence"},
+	0x00102152: {"LO", "RegionOfResidence"},
+	0x00102154: {"SH", "PatientTelephoneNumbers"},
+	0x00102160: {"SH", "EthnicGroup"},
+	0x00102180: {"SH", "Occupation"},
+	0x001021A0: {"CS", "SmokingStatus"},
+	0x001021B0: {"LT", "AdditionalPatientHistory"},
+	0x001021C0: {"US", "PregnancyStatus"},
+	0x001021D0: {"DA", "LastMenstrualDate"},
+	0x001021F0: {"LO", "PatientReligiousPreference"},
+	0x00102203: {"CS", "PatientSexNeutered"},
+	0x00104000: {"LT", "PatientComments"},
+
+	0x00120062: {"CS", "PatientIdentityRemoved"},
+	0x00120063: {"LO", "DeidentificationMethod"},
+	0x00120064: {"SQ", "DeidentificationMethodCodeSequence"},
+
+	0x00180010: {"LO", "ContrastBolusAgent"},
+	0x00180015: {"CS", "BodyPartExamined"},
+	0x00180050: {"DS", "SliceThickness"},
+	0x00180088: {"DS", "SpacingBetweenSlices"},
+	0x00181000: {"LO", "DeviceSerialNumber"},
+	0x00181004: {"LO", "PlateID"},
+	0x00181005: {"LO", "GeneratorID"},
+	0x00181007: {"LO", "CassetteID"},
+	0x00181008: {"LO", "GantryID"},
+	0x00181020: {"LO", "SoftwareVersions"},
+	0x00181030: {"LO", "ProtocolName"},
+	0x00181063: {"DS", "FrameTime"},
+	0x00181400: {"LO", "AcquisitionDeviceProcessingDescription"},
+	0x0018700A: {"SH", "DetectorID"},
+	0x00184000: {"LT", "AcquisitionComments"},
+	0x00189424: {"LT", "AcquisitionProtocolDescription"},
+
+	0x0020000D: {"UI", "StudyInstanceUID"},
+	0x0020000E: {"UI", "SeriesInstanceUID"},
+	0x00200010: {"SH", "StudyID"},
+	0x00200011: {"IS", "SeriesNumber"},
+	0x00200012: {"IS", "AcquisitionNumber"},
+	0x00200013: {"IS", "InstanceNumber"},
+	0x00200032: {"DS", "ImagePositionPatient"},
+	0x00200037: {"DS", "ImageOrientationPatient"},
+	0x00200052: {"UI", "FrameOfReferenceUID"},
+	0x00200200: {"UI", "SynchronizationFrameOfReferenceUID"},
+	0x00201206: {"IS", "NumberOfStudyRelatedSeries"},
+	0x00201208: {"IS", "NumberOfStudyRelatedInstances"},
+	0x00201209: {"IS", "NumberOfSeriesRelatedInstances"},
+	0x00204000: {"LT", "ImageComments"},
+	0x00209161: {"UI", "ConcatenationUID"},
+
+	0x00280002: {"US", "SamplesPerPixel"},
+	0x00280004: {"CS", "PhotometricInterpretation"},
+	0x00280006: {"US", "PlanarConfiguration"},
+	0x00280008: {"IS", "NumberOfFrames"},
+	0x00280010: {"US", "Rows"},
+	0x00280011: {"US", "Columns"},
+	0x00280030: {"DS", "PixelSpacing"},
+	0x00280100: {"US", "BitsAllocated"},
+	0x00280101: {"US", "BitsStored"},
+	0x00280102: {"US", "HighBit"},
+	0x00280103: {"US", "PixelRepresentation"},
+	0x00281050: {"DS", "WindowCenter"},
+	0x00281051: {"DS", "WindowWidth"},
+	0x00281052: {"DS", "RescaleIntercept"},
+	0x00281053: {"DS", "RescaleSlope"},
+
+	0x00321032: {"PN", "RequestingPhysician"},
+	0x00321033: {"LO", "RequestingService"},
+	0x00321041: {"TM", "StudyArrivalTime"},
+	0x00321051: {"TM", "StudyCompletionTime"},
+	0x00321060: {"LO", "RequestedProcedureDescription"},
+	0x00321064: {"SQ", "RequestedProcedureCodeSequence"},
+	0x00324000: {"LT", "StudyComments"},
+
+	0x00380010: {"LO", "AdmissionID"},
+	0x00380060: {"LO", "ServiceEpisodeID"},
+	0x00380300: {"LO", "CurrentPatientLocation"},
+	0x00380400: {"LO", "PatientInstitutionResidence"},
+	0x00380500: {"LO", "PatientState"},
+	0x00384000: {"LT", "VisitComments"},
+
+	0x00400001: {"AE", "ScheduledStationAETitle"},
+	0x00400002: {"DA", "ScheduledProcedureStepStartDate"},
+	0x00400003: {"TM", "ScheduledProcedureStepStartTime"},
+	0x00400006: {"PN", "ScheduledPerformingPhysicianName"},
+	0x00400007: {"LO", "ScheduledProcedureStepDescription"},
+	0x00400009: {"SH", "ScheduledProcedureStepID"},
+	0x00400010: {"SH", "ScheduledStationName"},
+	0x00400011: {"SH", "ScheduledProcedureStepLocation"},
+	0x00400100: {"SQ", "ScheduledProcedureStepSequence"},
+	0x00400241: {"AE", "PerformedStationAETitle"},
+	0x00400242: {"SH", "PerformedStationName"},
+	0x00400243: {"SH", "PerformedLocation"},
+	0x00400244: {"DA", "PerformedProcedureStepStartDate"},
+	0x00400245: {"TM", "PerformedProcedureStepStartTime"},
+	0x00400253: {"SH", "PerformedProcedureStepID"},
+	0x00400254: {"LO", "PerformedProcedureStepDescription"},
+	0x00400275: {"SQ", "RequestAttributesSequence"},
+	0x00401001: {"SH", "RequestedProcedureID"},
+	0x00401400: {"LT", "RequestedProcedureComments"},
+	0x00402400: {"LT", "ImagingServiceRequestComments"},
+	0x00404005: {"DT", "ScheduledProcedureStepStartDateTime"},
+	0x00404041: {"CS", "InputReadinessState"},
+	0x0040A124: {"UI", "UID"},
+	0x0040A730: {"SQ", "ContentSequence"},
+
+	0x00700084: {"PN", "ContentCreatorName"},
+
+	0x00741000: {"CS", "ProcedureStepState"},
+	0x00741002: {"SQ", "ProgressInformationSequence"},
+	0x00741004: {"DS", "ProcedureStepProgress"},
+	0x00741006: {"ST", "ProcedureStepProgressDescription"},
+	0x0074100E: {"SQ", "ProcedureStepDiscontinuationReasonCodeSequence"},
+	0x00741200: {"CS", "ScheduledProcedureStepPriority"},
+	0x00741202: {"LO", "WorklistLabel"},
+	0x00741204: {"LO", "ProcedureStepLabel"},
+	0x00741238: {"LT", "ReasonForCancellation"},
+
+	0x00880130: {"SH", "StorageMediaFileSetID"},
+	0x00880140: {"UI", "StorageMediaFileSetUID"},
+
+	0x04000561: {"SQ", "OriginalAttributesSequence"},
+
+	0x7FE00010: {"OW", "PixelData"},
+	0xFFFCFFFC: {"OB", "DataSetTrailingPadding"},
+}
+
+var keywords map[string]Tag
+
+func init() {
+	keywords = make(map[string]Tag, len(dictionary))
+	for t, e := range dictionary {
+		keywords[e.Keyword] = t
+	}
+}
+
+// LookupVR returns the VR of the tag as listed in the data dictionary. Group
+// lengths are UL; unknown and private tags are UN.
+func LookupVR(t Tag) string {
+	if e, ok := dictionary[t]; ok {
+		return e.VR
+	}
+	if t.Element() == 0x0000 {
+		return "UL"
+	}
+	return "UN"
+}
+
+// Keyword returns the keyword of the tag, e.g. "PatientName", or "" when the tag is not in the data dictionary.
+func Keyword(t Tag) string {
+	return dictionary[t].Keyword
+}
+
+// LookupKeyword returns the tag of the given keyword.
+func LookupKeyword(keyword string) (Tag, bool) {
+	t, ok := keywords[keyword]
+	return t, ok
+}
@@ -0,0 +1,87 @@
+package dicom
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+)
+
+// Attribute defines an attribute in the DICOM JSON model, see PS3.18 F.2.
+type Attribute struct {
+	VR           string        `json:"vr"`
+	Value        []interface{} `json:"Value,omitempty"`
+	InlineBinary string        `json:"InlineBinary,omitempty"`
+	BulkDataURI  string        `json:"BulkDataURI,omitempty"`
+}
+
+// JSON returns the data set in the DICOM JSON model, keyed by tag. The file
+// meta information is left out, and so is the value of the pixel data, which
+// is bulk data.
+func (ds *Dataset) JSON() map[string]Attribute {
+	m := make(map[string]Attribute, len(ds.Elements))
+	for _, e := range ds.Elements {
+		if e.Tag.Group() == 0x0002 {
+			continue
+		}
+		m[e.Tag.Hex()] = e.JSON()
+	}
+	return m
+}
+
+// MarshalJSON encodes the data set in the DICOM JSON model.
+func (ds *Dataset) MarshalJSON() ([]byte, error) {
+	return json.Marshal(ds.JSON())
+}
+
+// JSON returns the element as an attribute of the DICOM JSON model.
+func (e *Element) JSON() Attribute {
+	a := Attribute{VR: e.VR}
+	switch {
+	case e.VR == "SQ":
+		for _, item := range e.Items {
+			a.Value = append(a.Value, item.JSON())
+		}
+	case e.Tag == PixelData:
+	case e.VR == "PN":
+		for _, v := range e.Strings() {
+			a.Value = append(a.Value, personName(v))
+		}
+	case e.VR == "IS":
+		for _, v := range e.Ints() {
+			a.Value = append(a.Value, v)
+		}
+	case e.VR == "DS", e.VR == "FL", e.VR == "FD":
+		for _, v := range e.Floats() {
+			a.Value = append(a.Value, v)
+		}
+	case e.VR == "US", e.VR == "SS", e.VR == "UL", e.VR == "SL", e.VR == "SV", e.VR == "UV":
+		for _, v := range e.Ints() {
+			a.Value = append(a.Value, v)
+		}
+	case e.VR == "AT":
+		for i := 0; i+4 <= len(e.Value); i += 4 {
+			t := NewTag(uint16(e.Value[i])|uint16(e.Value[i+1])<<8, uint16(e.Value[i+2])|uint16(e.Value[i+3])<<8)
+			a.Value = append(a.Value, t.Hex())
+		}
+	case e.IsString():
+		for _, v := range e.Strings() {
+			a.Value = append(a.Value, v)
+		}
+	default:
+		if len(e.Value) > 0 {
+			a.InlineBinary = base64.StdEncoding.EncodeToString(e.Value)
+		}
+	}
+	return a
+}
+
+func personName(v string) map[string]string {
+	groups := strings.Split(v, "=")
+	name := map[string]string{}
+	for i, key := range []string{"Alphabetic", "Ideographic", "Phonetic"} {
+		if i < len(groups) && groups[i] != "" {
+			name[key] = groups[i]
+		}
+	}
+	return name
+}
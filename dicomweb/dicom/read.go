@@ -0,0 +1,300 @@
+package dicom
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+const undefinedLength = 0xFFFFFFFF
+
+// ErrNotPart10 is returned when the data does not start with the DICOM Part 10 preamble.
+var ErrNotPart10 = errors.New("dicom: not a DICOM Part 10 file")
+
+// Parse parses a DICOM Part 10 file. The returned data set includes the file
+// meta information (group 0002); its values share the memory of b.
+func Parse(b []byte) (*Dataset, error) {
+	if len(b) < 132 || string(b[128:132]) != "DICM" {
+		return nil, ErrNotPart10
+	}
+	r := &reader{b: b, pos: 132, explicit: true}
+
+	ds := &Dataset{}
+	for r.pos < len(b) {
+		if r.pos+4 > len(b) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		if r.peekTag().Group() != 0x0002 {
+			break
+		}
+		e, err := r.element()
+		if err != nil {
+			return nil, err
+		}
+		ds.Elements = append(ds.Elements, e)
+	}
+
+	ts := ds.String(TransferSyntaxUID)
+	switch ts {
+	case ImplicitVRLittleEndian:
+		r.explicit = false
+	case DeflatedExplicitVRLittleEndian, ExplicitVRBigEndian:
+		return nil, fmt.Errorf("dicom: unsupported transfer syntax %s", ts)
+	}
+
+	body, err := r.dataset(len(b))
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range body.Elements {
+		ds.Set(e)
+	}
+	return ds, nil
+}
+
+// ReadFile parses the DICOM Part 10 file of the given name.
+func ReadFile(name string) (*Dataset, error) {
+	b, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(b)
+}
+
+// ParseDataset parses a data set encoded in the given transfer syntax, without
+// preamble nor file meta information.
+func ParseDataset(b []byte, transferSyntax string) (*Dataset, error) {
+	r := &reader{b: b, explicit: transferSyntax != ImplicitVRLittleEndian}
+	return r.dataset(len(b))
+}
+
+type reader struct {
+	b        []byte
+	pos      int
+	explicit bool
+}
+
+func (r *reader) uint16() (uint16, error) {
+	if r.pos+2 > len(r.b) {
+		return 0, io.ErrUnexpectedEOF
+	}
+	v := binary.LittleEndian.Uint16(r.b[r.pos:])
+	r.pos += 2
+	return v, nil
+}
+
+func (r *reader) uint32() (uint32, error) {
+	if r.pos+4 > len(r.b) {
+		return 0, io.ErrUnexpectedEOF
+	}
+	v := binary.LittleEndian.Uint32(r.b[r.pos:])
+	r.pos += 4
+	return v, nil
+}
+
+func (r *reader) tag() (Tag, error) {
+	group, err := r.uint16()
+	if err != nil {
+		return 0, err
+	}
+	element, err := r.uint16()
+	if err != nil {
+		return 0, err
+	}
+	return NewTag(group, element), nil
+}
+
+func (r *reader) bytes(n int) ([]byte, error) {
+	if n < 0 || r.pos+n > len(r.b) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	v := r.b[r.pos : r.pos+n]
+	r.pos += n
+	return v, nil
+}
+
+func (r *reader) peekTag() Tag {
+	if r.pos+4 > len(r.b) {
+		return 0
+	}
+	return NewTag(binary.LittleEndian.Uint16(r.b[r.pos:]), binary.LittleEndian.Uint16(r.b[r.pos+2:]))
+}
+
+// dataset reads elements until end, or until an item delimitation item.
+func (r *reader) dataset(end int) (*Dataset, error) {
+	ds := &Dataset{}
+	for r.pos < end {
+		if r.peekTag() == ItemDelimitationItem {
+			r.pos += 8
+			return ds, nil
+		}
+		e, err := r.element()
+		if err != nil {
+			return nil, err
+		}
+		if e.Tag == DataSetTrailingPadding {
+			continue
+		}
+		ds.Elements = append(ds.Elements, e)
+	}
+	return ds, nil
+}
+
+func hasLongLength(vr string) bool {
+	switch vr {
+	case "OB", "OD", "OF", "OL", "OV", "OW", "SQ", "SV", "UC", "UN", "UR", "UT", "UV":
+		return true
+	}
+	return false
+}
+
+func (r *reader) element() (*Element, error) {
+	t, err := r.tag()
+	if err != nil {
+		return nil, err
+	}
+
+	var vr string
+	var length uint32
+	if r.explicit {
+		b, err := r.bytes(2)
+		if err != nil {
+			return nil, err
+		}
+		vr = string(b)
+		if hasLongLength(vr) {
+			if _, err := r.bytes(2); err != nil {
+				return nil, err
+			}
+			length, err = r.uint32()
+		} else {
+			var l uint16
+			l, err = r.uint16()
+			length = uint32(l)
+		}
+	} else {
+		vr = LookupVR(t)
+		length, err = r.uint32()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	e := &Element{Tag: t, VR: vr}
+	switch {
+	case vr == "SQ" || (vr == "UN" && length == undefinedLength):
+		e.VR = "SQ"
+		e.Items, err = r.items(length)
+	case t == PixelData && length == undefinedLength:
+		e.Fragments, err = r.fragments()
+	case length == undefinedLength:
+		err = fmt.Errorf("dicom: undefined length of %s %s", t, vr)
+	default:
+		e.Value, err = r.bytes(int(length))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("dicom: failed to read %s: %v", t, err)
+	}
+	return e, nil
+}
+
+func (r *reader) items(length uint32) ([]*Dataset, error) {
+	end := len(r.b)
+	if length != undefinedLength {
+		end = r.pos + int(length)
+		if end > len(r.b) {
+			return nil, io.ErrUnexpectedEOF
+		}
+	}
+
+	items := []*Dataset{}
+	for r.pos < end {
+		t, err := r.tag()
+		if err != nil {
+			return nil, err
+		}
+		itemLength, err := r.uint32()
+		if err != nil {
+			return nil, err
+		}
+		if t == SequenceDelimitation {
+			return items, nil
+		}
+		if t != Item {
+			return nil, fmt.Errorf("unexpected %s in sequence", t)
+		}
+		itemEnd := end
+		if itemLength != undefinedLength {
+			itemEnd = r.pos + int(itemLength)
+			if itemEnd > len(r.b) {
+				return nil, io.ErrUnexpectedEOF
+			}
+		}
+		item, err := r.dataset(itemEnd)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	if length == undefinedLength {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return items, nil
+}
+
+func (r *reader) fragments() ([][]byte, error) {
+	fragments := [][]byte{}
+	for {
+		t, err := r.tag()
+		if err != nil {
+			return nil, err
+		}
+		length, err := r.uint32()
+		if err != nil {
+			return nil, err
+		}
+		if t == SequenceDelimitation {
+			return fragments, nil
+		}
+		if t != Item {
+			return nil, fmt.Errorf("unexpected %s in encapsulated pixel data", t)
+		}
+		b, err := r.bytes(int(length))
+		if err != nil {
+			return nil, err
+		}
+		fragments = append(fragments, b)
+	}
+}
+
+// ReadFileMeta reads only the file meta information of the DICOM Part 10 file
+// of the given name, without reading the whole file.
+func ReadFileMeta(name string) (*Dataset, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	head := make([]byte, 144)
+	if _, err := io.ReadFull(f, head); err != nil {
+		return nil, ErrNotPart10
+	}
+	if string(head[128:132]) != "DICM" || binary.LittleEndian.Uint16(head[132:]) != 0x0002 || binary.LittleEndian.Uint16(head[134:]) != 0x0000 {
+		return nil, ErrNotPart10
+	}
+	// the group length is not trusted to size a buffer, the file may be shorter.
+	length := binary.LittleEndian.Uint32(head[140:])
+	rest, err := ioutil.ReadAll(io.LimitReader(f, int64(length)))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(rest)) < int64(length) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	r := &reader{b: append(head, rest...), pos: 132, explicit: true}
+	return r.dataset(len(r.b))
+}
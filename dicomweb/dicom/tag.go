@@ -0,0 +1,109 @@
+// Package dicom reads and writes DICOM Part 10 files and renders their data
+// sets in the DICOM JSON model.
+//
+// It supports the implicit and explicit VR little endian transfer syntaxes and
+// the encapsulated ones which encode the data set in explicit VR little
+// endian. Deflated and big endian transfer syntaxes are not supported.
+package dicom
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Tag defines a data element tag, the group in the upper 16 bits and the element in the lower 16 bits.
+type Tag uint32
+
+// NewTag creates a tag from its group and element.
+func NewTag(group, element uint16) Tag {
+	return Tag(uint32(group)<<16 | uint32(element))
+}
+
+// ParseTag parses a tag given as 8 hexadecimal digits, e.g. "0020000D", as
+// used by the DICOM JSON model and QIDO query parameters.
+func ParseTag(s string) (Tag, error) {
+	if len(s) != 8 {
+		return 0, fmt.Errorf("invalid tag %q", s)
+	}
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid tag %q", s)
+	}
+	return Tag(v), nil
+}
+
+// Group returns the group number of the tag.
+func (t Tag) Group() uint16 {
+	return uint16(t >> 16)
+}
+
+// Element returns the element number of the tag.
+func (t Tag) Element() uint16 {
+	return uint16(t)
+}
+
+// Hex returns the tag as 8 uppercase hexadecimal digits, e.g. "0020000D".
+func (t Tag) Hex() string {
+	return fmt.Sprintf("%08X", uint32(t))
+}
+
+// String returns the tag in the (gggg,eeee) form.
+func (t Tag) String() string {
+	return fmt.Sprintf("(%04X,%04X)", t.Group(), t.Element())
+}
+
+// IsPrivate reports whether the tag belongs to a private group.
+func (t Tag) IsPrivate() bool {
+	return t.Group()%2 == 1
+}
+
+// Tags used by the package and its callers.
+const (
+	FileMetaInformationGroupLength Tag = 0x00020000
+	FileMetaInformationVersion     Tag = 0x00020001
+	MediaStorageSOPClassUID        Tag = 0x00020002
+	MediaStorageSOPInstanceUID     Tag = 0x00020003
+	TransferSyntaxUID              Tag = 0x00020010
+	ImplementationClassUID         Tag = 0x00020012
+	ImplementationVersionName      Tag = 0x00020013
+
+	SpecificCharacterSet   Tag = 0x00080005
+	SOPClassUID            Tag = 0x00080016
+	SOPInstanceUID         Tag = 0x00080018
+	StudyDate              Tag = 0x00080020
+	SeriesDate             Tag = 0x00080021
+	StudyTime              Tag = 0x00080030
+	SeriesTime             Tag = 0x00080031
+	AccessionNumber        Tag = 0x00080050
+	Modality               Tag = 0x00080060
+	ModalitiesInStudy      Tag = 0x00080061
+	StudyDescription       Tag = 0x00081030
+	SeriesDescription      Tag = 0x0008103E
+	PatientName            Tag = 0x00100010
+	PatientID              Tag = 0x00100020
+	PatientBirthDate       Tag = 0x00100030
+	PatientSex             Tag = 0x00100040
+	StudyInstanceUID       Tag = 0x0020000D
+	SeriesInstanceUID      Tag = 0x0020000E
+	StudyID                Tag = 0x00200010
+	SeriesNumber           Tag = 0x00200011
+	InstanceNumber         Tag = 0x00200013
+	SamplesPerPixel        Tag = 0x00280002
+	NumberOfFrames         Tag = 0x00280008
+	Rows                   Tag = 0x00280010
+	Columns                Tag = 0x00280011
+	BitsAllocated          Tag = 0x00280100
+	PixelData              Tag = 0x7FE00010
+	Item                   Tag = 0xFFFEE000
+	ItemDelimitationItem   Tag = 0xFFFEE00D
+	SequenceDelimitation   Tag = 0xFFFEE0DD
+	DataSetTrailingPadding Tag = 0xFFFCFFFC
+)
+
+// Transfer syntaxes known to the package.
+const (
+	ImplicitVRLittleEndian         = "1.2.840.10008.1.2"
+	ExplicitVRLittleEndian         = "1.2.840.10008.1.2.1"
+	DeflatedExplicitVRLittleEndian = "1.2.840.10008.1.2.1.99"
+	ExplicitVRBigEndian            = "1.2.840.10008.1.2.2"
+)
@@ -0,0 +1,189 @@
+package dicom
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+)
+
+// Implementation identification written to the file meta information.
+const (
+	ImplementationClassUIDValue    = "2.25.224871934961420719463447357402915126461"
+	ImplementationVersionNameValue = "DICOMWEBGO"
+)
+
+// Encode encodes the data set as a DICOM Part 10 file, in the transfer syntax
+// given by its TransferSyntaxUID, explicit VR little endian if missing. The
+// file meta information is completed from the data set when incomplete, and
+// its group length is recomputed.
+func Encode(ds *Dataset) ([]byte, error) {
+	meta := &Dataset{}
+	body := &Dataset{}
+	for _, e := range ds.Elements {
+		if e.Tag.Group() == 0x0002 {
+			if e.Tag != FileMetaInformationGroupLength {
+				meta.Elements = append(meta.Elements, e)
+			}
+		} else {
+			body.Elements = append(body.Elements, e)
+		}
+	}
+
+	ts := meta.String(TransferSyntaxUID)
+	switch ts {
+	case "":
+		ts = ExplicitVRLittleEndian
+		meta.Set(NewString(TransferSyntaxUID, "UI", ts))
+	case DeflatedExplicitVRLittleEndian, ExplicitVRBigEndian:
+		return nil, fmt.Errorf("dicom: unsupported transfer syntax %s", ts)
+	}
+	if meta.Get(FileMetaInformationVersion) == nil {
+		meta.Set(NewBytes(FileMetaInformationVersion, "OB", []byte{0x00, 0x01}))
+	}
+	if meta.Get(MediaStorageSOPClassUID) == nil {
+		meta.Set(NewString(MediaStorageSOPClassUID, "UI", body.String(SOPClassUID)))
+	}
+	if meta.Get(MediaStorageSOPInstanceUID) == nil {
+		meta.Set(NewString(MediaStorageSOPInstanceUID, "UI", body.String(SOPInstanceUID)))
+	}
+	if meta.Get(ImplementationClassUID) == nil {
+		meta.Set(NewString(ImplementationClassUID, "UI", ImplementationClassUIDValue))
+		meta.Set(NewString(ImplementationVersionName, "SH", ImplementationVersionNameValue))
+	}
+
+	metaBytes := &bytes.Buffer{}
+	w := &writer{buf: metaBytes, explicit: true}
+	if err := w.dataset(meta); err != nil {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+	buf.Write(make([]byte, 128))
+	buf.WriteString("DICM")
+	w = &writer{buf: buf, explicit: true}
+	if err := w.element(NewUint32(FileMetaInformationGroupLength, uint32(metaBytes.Len()))); err != nil {
+		return nil, err
+	}
+	buf.Write(metaBytes.Bytes())
+
+	w.explicit = ts != ImplicitVRLittleEndian
+	if err := w.dataset(body); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// WriteFile encodes the data set as a DICOM Part 10 file of the given name.
+func WriteFile(name string, ds *Dataset) error {
+	b, err := Encode(ds)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(name, b, 0644)
+}
+
+// EncodeDataset encodes the data set without preamble nor file meta
+// information, in explicit VR little endian.
+func EncodeDataset(ds *Dataset) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	w := &writer{buf: buf, explicit: true}
+	if err := w.dataset(ds); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+type writer struct {
+	buf      *bytes.Buffer
+	explicit bool
+}
+
+func (w *writer) uint16(v uint16) {
+	b := make([]byte, 2)
+	binary.LittleEndian.PutUint16(b, v)
+	w.buf.Write(b)
+}
+
+func (w *writer) uint32(v uint32) {
+	b := make([]byte, 4)
+	binary.LittleEndian.PutUint32(b, v)
+	w.buf.Write(b)
+}
+
+func (w *writer) tag(t Tag) {
+	w.uint16(t.Group())
+	w.uint16(t.Element())
+}
+
+func (w *writer) dataset(ds *Dataset) error {
+	for _, e := range ds.Elements {
+		if err := w.element(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *writer) header(t Tag, vr string, length uint32) error {
+	w.tag(t)
+	if !w.explicit {
+		w.uint32(length)
+		return nil
+	}
+	if len(vr) != 2 {
+		return fmt.Errorf("dicom: invalid VR %q of %s", vr, t)
+	}
+	w.buf.WriteString(vr)
+	if hasLongLength(vr) {
+		w.uint16(0)
+		w.uint32(length)
+		return nil
+	}
+	if length > 0xFFFF {
+		return fmt.Errorf("dicom: value of %s too long for VR %s", t, vr)
+	}
+	w.uint16(uint16(length))
+	return nil
+}
+
+func (w *writer) element(e *Element) error {
+	switch {
+	case e.VR == "SQ":
+		items := &bytes.Buffer{}
+		iw := &writer{buf: items, explicit: w.explicit}
+		for _, item := range e.Items {
+			content := &bytes.Buffer{}
+			if err := (&writer{buf: content, explicit: w.explicit}).dataset(item); err != nil {
+				return err
+			}
+			iw.tag(Item)
+			iw.uint32(uint32(content.Len()))
+			items.Write(content.Bytes())
+		}
+		if err := w.header(e.Tag, "SQ", uint32(items.Len())); err != nil {
+			return err
+		}
+		w.buf.Write(items.Bytes())
+	case e.Fragments != nil:
+		if err := w.header(e.Tag, "OB", undefinedLength); err != nil {
+			return err
+		}
+		for _, f := range e.Fragments {
+			w.tag(Item)
+			w.uint32(uint32(len(f)))
+			w.buf.Write(f)
+		}
+		w.tag(SequenceDelimitation)
+		w.uint32(0)
+	default:
+		if len(e.Value)%2 == 1 {
+			return fmt.Errorf("dicom: odd length value of %s", e.Tag)
+		}
+		if err := w.header(e.Tag, e.VR, uint32(len(e.Value))); err != nil {
+			return err
+		}
+		w.buf.Write(e.Value)
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package dicomweb
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"encoding/base64"
 	"encoding/json"
@@ -9,7 +10,6 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
-	"log"
 	"mime"
 	"mime/multipart"
 	"net/http"
@@ -29,9 +29,15 @@ type Client struct {
 	qidoEndpoint  string
 	wadoEndpoint  string
 	stowEndpoint  string
+	upsEndpoint   string
 	authorization string
 	boundary      string
-	optionFuncs *[]OptionFunc
+	optionFuncs   *[]OptionFunc
+	retryPolicy   *RetryPolicy
+	limiters      map[Service]*limiter
+	middlewares   []Middleware
+	capabilities  map[Service]*Capabilities
+	failover      *failover
 }
 
 // OptionFunc is a signature for methods which can modify dicom requests
@@ -46,10 +52,24 @@ type ClientOption struct {
 	WADOEndpoint string
 	// STOWEndpoint endpoint for STOW.
 	STOWEndpoint string
+	// UPSEndpoint endpoint for UPS-RS, the worklist service.
+	UPSEndpoint string
 	// HTTPClient to perform requests. Uses http.DefaultClient otherwise
 	HTTPClient *http.Client
 	// OptionFuncs is an array of OptionFunc which are called before each request
 	OptionFuncs *[]OptionFunc
+	// RetryPolicy to retry requests that failed transiently. No retry otherwise
+	RetryPolicy *RetryPolicy
+	// QIDOLimit request budget of the QIDO endpoint. Unlimited otherwise
+	QIDOLimit *Limit
+	// WADOLimit request budget of the WADO endpoint. Unlimited otherwise
+	WADOLimit *Limit
+	// STOWLimit request budget of the STOW endpoint. Unlimited otherwise
+	STOWLimit *Limit
+	// UPSLimit request budget of the UPS endpoint. Unlimited otherwise
+	UPSLimit *Limit
+	// Middlewares is an array of Middleware wrapping each request, the first one is the outermost
+	Middlewares []Middleware
 }
 
 // WithAuthentication configures the client.
@@ -76,70 +96,81 @@ func NewClient(option ClientOption) *Client {
 	if option.HTTPClient != nil {
 		httpClient = option.HTTPClient
 	}
-	return &Client{
+	c := &Client{
 		httpClient:   httpClient,
-		optionFuncs: option.OptionFuncs,
+		optionFuncs:  option.OptionFuncs,
+		retryPolicy:  option.RetryPolicy,
 		qidoEndpoint: option.QIDOEndpoint,
 		wadoEndpoint: option.WADOEndpoint,
 		stowEndpoint: option.STOWEndpoint,
+		upsEndpoint:  option.UPSEndpoint,
 		boundary:     "dicomwebgoWxkTrZ",
+		middlewares:  option.Middlewares,
 	}
+	if option.QIDOLimit != nil {
+		c.WithLimit(QIDOService, *option.QIDOLimit)
+	}
+	if option.WADOLimit != nil {
+		c.WithLimit(WADOService, *option.WADOLimit)
+	}
+	if option.STOWLimit != nil {
+		c.WithLimit(STOWService, *option.STOWLimit)
+	}
+	if option.UPSLimit != nil {
+		c.WithLimit(UPSService, *option.UPSLimit)
+	}
+	return c
 }
 
 // Query based on QIDO, query a list of either matched studies, series or instances.
 func (c *Client) Query(req QIDORequest) ([]QIDOResponse, error) {
-	url := c.qidoEndpoint
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	res, ok := req.Type.resource()
+	if !ok {
+		return nil, errors.New("failed to query: need to specify query type")
+	}
+	var path string
+	var err error
 	switch req.Type {
-	case Study:
-		url += "/studies"
 	case Series:
-		url += "/studies/" + req.StudyInstanceUID
-		url += "/series"
+		path, err = resourcePath(req.StudyInstanceUID, "", "")
 	case Instance:
-		url += "/studies/" + req.StudyInstanceUID
-		url += "/series/" + req.SeriesInstanceUID
-		url += "/instances"
-	default:
-		return nil, errors.New("failed to query: need to specify query type")
+		path, err = resourcePath(req.StudyInstanceUID, req.SeriesInstanceUID, "")
 	}
+	if err != nil {
+		return nil, err
+	}
+	path += "/" + res.path
 
-	r, err := http.NewRequest("GET", url, nil)
+	r, err := http.NewRequest("GET", c.qidoEndpoint+path, nil)
 	if err != nil {
 		return nil, err
 	}
+	// the UIDs in the path are not repeated as query parameters.
+	inPath := map[string]bool{
+		"0020000D": strings.HasPrefix(path, "/studies/"),
+		"0020000E": strings.Contains(path, "/series/"),
+	}
 	q := r.URL.Query()
-	mp := map[string]interface{}{}
-	databytes, _ := json.Marshal(req)
-	json.Unmarshal(databytes, &mp)
-
-	for k, v := range mp {
-		if k == "Type" || k == "0020000D" || k == "0020000E" || k == "00080018" {
+	for k, v := range req.params() {
+		if inPath[k] {
 			continue
 		}
-		switch t := v.(type) {
-		case float64:
-			q.Add(k, fmt.Sprintf("%.0f", t))
-		case string:
-			q.Add(k, t)
-		}
+		q.Add(k, v)
 	}
 
 	r.URL.RawQuery = q.Encode()
 
-	if c.authorization != "" {
-		r.Header.Set("Authorization", c.authorization)
-	}
-	if c.optionFuncs != nil {
-		for _, fn := range *c.optionFuncs {
-			if fn == nil {
-				continue
-			}
-			if err := fn(r); err != nil {
-				return nil, err
-			}
-		}
-	}
-	resp, err := c.httpClient.Do(r)
+	resp, err := c.do(&Operation{
+		Service:           QIDOService,
+		QIDOType:          req.Type,
+		StudyInstanceUID:  req.StudyInstanceUID,
+		SeriesInstanceUID: req.SeriesInstanceUID,
+		SOPInstanceUID:    req.SOPInstanceUID,
+	}, r)
 	if err != nil {
 		return nil, err
 	}
@@ -156,132 +187,148 @@ func (c *Client) Query(req QIDORequest) ([]QIDOResponse, error) {
 
 // Retrieve based on WADO, retrieve the DICOM image of given id.
 func (c *Client) Retrieve(req WADORequest) ([][]byte, error) {
-	if ok := req.Validate(); !ok {
-		return nil, errors.New("parameters does not match the given type")
+	parts := [][]byte{}
+	err := c.retrieve(req, func(p io.Reader, root bool) error {
+		data, err := ioutil.ReadAll(p)
+		if err != nil {
+			return fmt.Errorf("failed to read multipart response: %v", err)
+		}
+		// the root part designated by the start parameter comes first.
+		if root {
+			parts = append([][]byte{data}, parts...)
+		} else {
+			parts = append(parts, data)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
+	return parts, nil
+}
 
-	url := c.wadoEndpoint
-
+// wadoURL returns the URL of the resource the request retrieves.
+func (c *Client) wadoURL(req WADORequest) (string, error) {
+	if req.Type == URIReference {
+		return req.RetrieveURL, nil
+	}
+	path, err := resourcePath(req.StudyInstanceUID, req.SeriesInstanceUID, req.SOPInstanceUID)
+	if err != nil {
+		return "", err
+	}
 	switch req.Type {
-	case StudyRaw:
-		url += "/studies/" + req.StudyInstanceUID
-	case StudyRendered:
-		url += "/studies/" + req.StudyInstanceUID
-		url += "/rendered"
-	case SeriesRaw:
-		url += "/studies/" + req.StudyInstanceUID
-		url += "/series/" + req.SeriesInstanceUID
-	case SeriesRendered:
-		url += "/studies/" + req.StudyInstanceUID
-		url += "/series/" + req.SeriesInstanceUID
-		url += "/rendered"
-	case SeriesMetadata:
-		url += "/studies/" + req.StudyInstanceUID
-		url += "/series/" + req.SeriesInstanceUID
-		url += "/metadata"
-	case InstanceRaw:
-		url += "/studies/" + req.StudyInstanceUID
-		url += "/series/" + req.SeriesInstanceUID
-		url += "/instances/" + req.SOPInstanceUID
-	case InstanceRendered:
-		url += "/studies/" + req.StudyInstanceUID
-		url += "/series/" + req.SeriesInstanceUID
-		url += "/instances/" + req.SOPInstanceUID
-		url += "/rendered"
-	case InstanceMetadata:
-		url += "/studies/" + req.StudyInstanceUID
-		url += "/series/" + req.SeriesInstanceUID
-		url += "/instances/" + req.SOPInstanceUID
-		url += "/metadata"
+	case StudyRendered, SeriesRendered, InstanceRendered:
+		path += "/rendered"
+	case SeriesMetadata, InstanceMetadata:
+		path += "/metadata"
 	case Frame:
-		url += "/studies/" + req.StudyInstanceUID
-		url += "/series/" + req.SeriesInstanceUID
-		url += "/instances/" + req.SOPInstanceUID
-		url += "/frames/" + strconv.Itoa(req.FrameID)
-	case URIReference:
-		url = req.RetrieveURL
+		path += "/frames/" + strconv.Itoa(req.FrameID)
 	}
+	return c.wadoEndpoint + path, nil
+}
 
+// retrieve sends the WADO request and calls fn with each part of the multipart
+// response as it is read. root tells if the part is the one designated by the
+// start parameter of the response.
+func (c *Client) retrieve(req WADORequest, fn func(p io.Reader, root bool) error) error {
+	if err := req.Validate(); err != nil {
+		return err
+	}
+
+	url, err := c.wadoURL(req)
+	if err != nil {
+		return err
+	}
 	r, err := http.NewRequest("GET", url, nil)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	if c.authorization != "" {
-		r.Header.Set("Authorization", c.authorization)
+	op := &Operation{
+		Service:           WADOService,
+		WADOType:          req.Type,
+		StudyInstanceUID:  req.StudyInstanceUID,
+		SeriesInstanceUID: req.SeriesInstanceUID,
+		SOPInstanceUID:    req.SOPInstanceUID,
+		FrameID:           req.FrameID,
 	}
-	if c.optionFuncs != nil {
-		for _, fn := range *c.optionFuncs {
-			if fn == nil {
-				continue
-			}
-			if err := fn(r); err != nil {
-				return nil, err
-			}
-		}
-	}
-	resp, err := c.httpClient.Do(r)
+	resp, err := c.do(op, r)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode/100 != 2 {
-		// b, _ := ioutil.ReadAll(resp.Body)
-		return nil, errors.New(resp.Status)
+		return errors.New(resp.Status)
 	}
 
-	parts := [][]byte{}
 	mediaType, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
 	if err != nil {
-		return nil, err
+		return err
+	}
+	var body io.Reader = resp.Body
+	var progress *progressReader
+	if req.Progress != nil {
+		progress = newProgressReader(resp.Body, req.Progress, resp.ContentLength, req.expectedParts())
+		body = progress
 	}
 	if !strings.HasPrefix(mediaType, "multipart/") {
-		return nil, errors.New("unexpected Content-Type, should be multipart/related")
+		return errors.New("unexpected Content-Type, should be multipart/related")
 	}
 
+	var next func() (io.Reader, bool, error)
 	if params["start"] == "" {
-		mr := multipart.NewReader(resp.Body, params["boundary"])
-		for {
+		mr := multipart.NewReader(body, params["boundary"])
+		next = func() (io.Reader, bool, error) {
 			p, err := mr.NextPart()
-			if err == io.EOF {
-				return parts, nil
-			} else if err != nil {
-				log.Fatalf("failed to read next multipart: %v", err)
-				return nil, err
-			}
-
-			data, err := ioutil.ReadAll(p)
-			if err != nil {
-				log.Fatalf("failed to read multipart response: %v", err)
-				return nil, err
-			}
-			parts = append(parts, data)
+			return p, false, err
 		}
 	} else {
-		r := related.NewReader(resp.Body, params)
-		obj, err := r.ReadObject()
-		if err != nil {
-			return nil, err
-		}
-		for _, part := range obj.Values {
-			data, err := ioutil.ReadAll(part)
+		rr := related.NewReader(body, params)
+		next = func() (io.Reader, bool, error) {
+			p, err := rr.NextPart()
 			if err != nil {
-				return nil, err
+				return nil, false, err
 			}
-			parts = append(parts, data)
+			return p, p.Root, nil
 		}
 	}
 
-	return parts, nil
+	root := false
+	for {
+		p, isRoot, err := next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("failed to read next multipart: %v", err)
+		}
+		if err := fn(p, isRoot); err != nil {
+			return err
+		}
+		root = root || isRoot
+		op.Parts++
+		if progress != nil {
+			progress.part()
+		}
+	}
+	if params["start"] != "" && !root {
+		return fmt.Errorf("start part %s not found in multipart response", params["start"])
+	}
+	return nil
 }
 
 // Store based on STOW, store the DICOM study to PACS server.
 func (c *Client) Store(req STOWRequest) (interface{}, error) {
-	url := c.stowEndpoint + "/studies/"
-
-	if req.StudyInstanceUID != "" {
-		url += req.StudyInstanceUID
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	path, err := resourcePath(req.StudyInstanceUID, "", "")
+	if err != nil {
+		return nil, err
 	}
+	if path == "" {
+		path = "/studies/"
+	}
+	url := c.stowEndpoint + path
 
 	body := &bytes.Buffer{}
 	writer := multipart.NewWriter(body)
@@ -290,6 +337,8 @@ func (c *Client) Store(req STOWRequest) (interface{}, error) {
 	header := textproto.MIMEHeader{}
 	header.Set("Content-Type", "application/dicom")
 
+	// end offset of each part in the body, for the progress.
+	ends := []int64{}
 	for _, p := range req.Parts {
 		w, err := writer.CreatePart(header)
 		if err != nil {
@@ -298,6 +347,7 @@ func (c *Client) Store(req STOWRequest) (interface{}, error) {
 		if _, err = w.Write(p); err != nil {
 			return nil, err
 		}
+		ends = append(ends, int64(body.Len()))
 	}
 
 	if err := writer.Close(); err != nil {
@@ -308,10 +358,58 @@ func (c *Client) Store(req STOWRequest) (interface{}, error) {
 	if err != nil {
 		return nil, err
 	}
+	if req.Progress != nil {
+		// each attempt of the request reports from the start of the body.
+		data := body.Bytes()
+		getBody := func() (io.ReadCloser, error) {
+			pr := newProgressReader(bytes.NewReader(data), req.Progress, int64(len(data)), len(req.Parts))
+			pr.ends = ends
+			return ioutil.NopCloser(pr), nil
+		}
+		r.Body, _ = getBody()
+		r.GetBody = getBody
+	}
 
 	// The RFC 2045 doc states that certain values cannot be used as parameter values in the Content-Type header,
 	// which includes '/', so the `application/dicom` needs to be wrapped by double quotes.
 	r.Header.Set("Content-Type", fmt.Sprintf("multipart/related; type=\"application/dicom\"; boundary=%s", c.boundary))
+	resp, err := c.do(&Operation{
+		Service:          STOWService,
+		StudyInstanceUID: req.StudyInstanceUID,
+		Parts:            len(req.Parts),
+	}, r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// 409 Conflict carries the failed instances in the response body.
+	if resp.StatusCode/100 != 2 && resp.StatusCode != http.StatusConflict {
+		return nil, errors.New(resp.Status)
+	}
+
+	var result interface{}
+	json.NewDecoder(resp.Body).Decode(&result)
+
+	return result, nil
+}
+
+// do sends the request of the operation to the server. It sets the
+// authorization, applies the OptionFuncs, checks the request against the
+// capabilities of the endpoint and sends the request through the middlewares.
+func (c *Client) do(op *Operation, r *http.Request) (*http.Response, error) {
+	if err := c.authorize(r); err != nil {
+		return nil, err
+	}
+	if err := c.checkCapabilities(op.Service, r); err != nil {
+		return nil, err
+	}
+	r = r.WithContext(context.WithValue(r.Context(), operationKey{}, op))
+	return c.chain(op.Service).RoundTrip(r)
+}
+
+// authorize sets the authorization and applies the OptionFuncs to the request.
+func (c *Client) authorize(r *http.Request) error {
 	if c.authorization != "" {
 		r.Header.Set("Authorization", c.authorization)
 	}
@@ -321,17 +419,18 @@ func (c *Client) Store(req STOWRequest) (interface{}, error) {
 				continue
 			}
 			if err := fn(r); err != nil {
-				return nil, err
+				return err
 			}
 		}
 	}
-	resp, err := c.httpClient.Do(r)
-	if err != nil {
-		return nil, err
-	}
-
-	var result interface{}
-	json.NewDecoder(resp.Body).Decode(&result)
+	return nil
+}
 
-	return result, nil
+// statusError returns the error of a failed response, with the reason given
+// by its Warning header if any.
+func statusError(resp *http.Response) error {
+	if warning := resp.Header.Get("Warning"); warning != "" {
+		return fmt.Errorf("%s: %s", resp.Status, warning)
+	}
+	return errors.New(resp.Status)
 }
@@ -27,7 +27,7 @@ func TestClientWithAuthentication(t *testing.T) {
 	// just make an arbitrary request to mock server.
 	qido := QIDORequest{
 		Type:             Study,
-		StudyInstanceUID: "study-instance-id",
+		StudyInstanceUID: "1.2.3",
 	}
 	c.Query(qido)
 }
@@ -67,7 +67,7 @@ func TestClientWithOptionFunc(t *testing.T) {
 	// just make an arbitrary request to mock server.
 	qido := QIDORequest{
 		Type:             Study,
-		StudyInstanceUID: "study-instance-id",
+		StudyInstanceUID: "1.2.3",
 	}
 	c.Query(qido)
 }
@@ -90,14 +90,14 @@ func TestClientWithFailingOptionFuncs(t *testing.T) {
 
 	_, err := c.Query(QIDORequest{
 		Type:             Study,
-		StudyInstanceUID: "study-instance-id",
+		StudyInstanceUID: "1.2.3",
 	})
 	assert.Equal(t, simulated, err)
-	_, err = c.Store(STOWRequest{})
+	_, err = c.Store(STOWRequest{Parts: [][]byte{[]byte("part: 0")}})
 	assert.Equal(t, simulated, err)
 	wado := WADORequest{
 		Type:             StudyRaw,
-		StudyInstanceUID: "test",
+		StudyInstanceUID: "1.2.3",
 	}
 	_, err = c.Retrieve(wado)
 	assert.Equal(t, simulated, err)
@@ -141,14 +141,14 @@ func TestQIDOQueryAllStudyWithOptions(t *testing.T) {
 
 func TestQIDOQuerySeries(t *testing.T) {
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		assert.Equal(t, "/studies/study-id/series", r.URL.String())
+		assert.Equal(t, "/studies/1.2.3/series", r.URL.String())
 	}))
 
 	c := NewClient(ClientOption{
 		QIDOEndpoint: ts.URL,
 	})
 
-	studyInstanceUID := "study-id"
+	studyInstanceUID := "1.2.3"
 	qido := QIDORequest{
 		Type:             Series,
 		StudyInstanceUID: studyInstanceUID,
@@ -159,14 +159,14 @@ func TestQIDOQuerySeries(t *testing.T) {
 
 func TestQIDOQueryInstance(t *testing.T) {
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		assert.Equal(t, "/studies/study-id/series/series-id/instances", r.URL.String())
+		assert.Equal(t, "/studies/1.2.3/series/1.2.3.4/instances", r.URL.String())
 	}))
 	c := NewClient(ClientOption{
 		QIDOEndpoint: ts.URL,
 	})
 
-	studyInstanceUID := "study-id"
-	seriesInstanceUID := "series-id"
+	studyInstanceUID := "1.2.3"
+	seriesInstanceUID := "1.2.3.4"
 	qido := QIDORequest{
 		Type:              Instance,
 		StudyInstanceUID:  studyInstanceUID,
@@ -178,22 +178,20 @@ func TestQIDOQueryInstance(t *testing.T) {
 
 func TestQIDOQueryUnspecifyType(t *testing.T) {
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		assert.Equal(t, "/studies/study-id/series/series-id/instances", r.URL.String())
+		assert.Equal(t, "/studies/1.2.3/series/1.2.3.4/instances", r.URL.String())
 	}))
 	c := NewClient(ClientOption{
 		QIDOEndpoint: ts.URL,
 	})
 
-	studyInstanceUID := "study-id"
-	seriesInstanceUID := "series-id"
+	studyInstanceUID := "1.2.3"
+	seriesInstanceUID := "1.2.3.4"
 	qido := QIDORequest{
 		StudyInstanceUID:  studyInstanceUID,
 		SeriesInstanceUID: seriesInstanceUID,
 	}
 	_, err := c.Query(qido)
-	if assert.Error(t, err) {
-		assert.Equal(t, errors.New("failed to query: need to specify query type"), err)
-	}
+	assert.EqualError(t, err, `invalid Type "0": unknown query type`)
 }
 
 func TestQIDOQueryInternalServerError(t *testing.T) {
@@ -204,8 +202,8 @@ func TestQIDOQueryInternalServerError(t *testing.T) {
 		QIDOEndpoint: ts.URL,
 	})
 
-	studyInstanceUID := "study-id"
-	seriesInstanceUID := "series-id"
+	studyInstanceUID := "1.2.3"
+	seriesInstanceUID := "1.2.3.4"
 	qido := QIDORequest{
 		Type:              Instance,
 		StudyInstanceUID:  studyInstanceUID,
@@ -255,7 +253,7 @@ part: 1
 		},
 	}).WithAuthentication("user:name")
 
-	studyInstanceUID := "study-id"
+	studyInstanceUID := "1.2.3"
 
 	wado := WADORequest{
 		Type:             StudyRaw,
@@ -270,7 +268,7 @@ func TestWADOQueryInvalidURL(t *testing.T) {
 		WADOEndpoint: "%$^",
 	})
 
-	studyInstanceUID := "study-id"
+	studyInstanceUID := "1.2.3"
 
 	wado := WADORequest{
 		Type:             StudyRaw,
@@ -302,7 +300,7 @@ part: 1
 		WADOEndpoint: ts.URL,
 	})
 
-	studyInstanceUID := "study-id"
+	studyInstanceUID := "1.2.3"
 
 	wado := WADORequest{
 		Type:             StudyRaw,
@@ -334,7 +332,7 @@ part: 1
 		WADOEndpoint: ts.URL,
 	})
 
-	studyInstanceUID := "study-id"
+	studyInstanceUID := "1.2.3"
 
 	wado := WADORequest{
 		Type:             StudyRaw,
@@ -366,7 +364,7 @@ part: 1
 		WADOEndpoint: ts.URL,
 	})
 
-	studyInstanceUID := "study-id"
+	studyInstanceUID := "1.2.3"
 
 	wado := WADORequest{
 		Type:             StudyRendered,
@@ -398,8 +396,8 @@ part: 1
 		WADOEndpoint: ts.URL,
 	})
 
-	studyInstanceUID := "study-id"
-	seriesInstanceUID := "series-id"
+	studyInstanceUID := "1.2.3"
+	seriesInstanceUID := "1.2.3.4"
 
 	wado := WADORequest{
 		Type:              SeriesRaw,
@@ -432,8 +430,8 @@ part: 1
 		WADOEndpoint: ts.URL,
 	})
 
-	studyInstanceUID := "study-id"
-	seriesInstanceUID := "series-id"
+	studyInstanceUID := "1.2.3"
+	seriesInstanceUID := "1.2.3.4"
 
 	wado := WADORequest{
 		Type:              SeriesRendered,
@@ -466,8 +464,8 @@ part: 1
 		WADOEndpoint: ts.URL,
 	})
 
-	studyInstanceUID := "study-id"
-	seriesInstanceUID := "series-id"
+	studyInstanceUID := "1.2.3"
+	seriesInstanceUID := "1.2.3.4"
 
 	wado := WADORequest{
 		Type:              SeriesMetadata,
@@ -500,9 +498,9 @@ part: 1
 		WADOEndpoint: ts.URL,
 	})
 
-	studyInstanceUID := "study-id"
-	seriesInstanceUID := "series-id"
-	instanceUID := "instance-id"
+	studyInstanceUID := "1.2.3"
+	seriesInstanceUID := "1.2.3.4"
+	instanceUID := "1.2.3.4.5"
 
 	wado := WADORequest{
 		Type:              InstanceRaw,
@@ -536,9 +534,9 @@ part: 1
 		WADOEndpoint: ts.URL,
 	})
 
-	studyInstanceUID := "study-id"
-	seriesInstanceUID := "series-id"
-	instanceUID := "instance-id"
+	studyInstanceUID := "1.2.3"
+	seriesInstanceUID := "1.2.3.4"
+	instanceUID := "1.2.3.4.5"
 
 	wado := WADORequest{
 		Type:              InstanceRendered,
@@ -572,9 +570,9 @@ part: 1
 		WADOEndpoint: ts.URL,
 	})
 
-	studyInstanceUID := "study-id"
-	seriesInstanceUID := "series-id"
-	instanceUID := "instance-id"
+	studyInstanceUID := "1.2.3"
+	seriesInstanceUID := "1.2.3.4"
+	instanceUID := "1.2.3.4.5"
 
 	wado := WADORequest{
 		Type:              InstanceMetadata,
@@ -608,9 +606,9 @@ part: 1
 		WADOEndpoint: ts.URL,
 	})
 
-	studyInstanceUID := "study-id"
-	seriesInstanceUID := "series-id"
-	instanceUID := "instance-id"
+	studyInstanceUID := "1.2.3"
+	seriesInstanceUID := "1.2.3.4"
+	instanceUID := "1.2.3.4.5"
 
 	wado := WADORequest{
 		Type:              Frame,
@@ -635,9 +633,9 @@ func TestWADORetrieveInternalServerError(t *testing.T) {
 		WADOEndpoint: ts.URL,
 	})
 
-	studyInstanceUID := "study-id"
-	seriesInstanceUID := "series-id"
-	instanceUID := "instance-id"
+	studyInstanceUID := "1.2.3"
+	seriesInstanceUID := "1.2.3.4"
+	instanceUID := "1.2.3.4.5"
 
 	wado := WADORequest{
 		Type:              InstanceRaw,
@@ -660,9 +658,9 @@ func TestWADORetrieveInternalUnsupportContentType(t *testing.T) {
 		WADOEndpoint: ts.URL,
 	})
 
-	studyInstanceUID := "study-id"
-	seriesInstanceUID := "series-id"
-	instanceUID := "instance-id"
+	studyInstanceUID := "1.2.3"
+	seriesInstanceUID := "1.2.3.4"
+	instanceUID := "1.2.3.4.5"
 
 	wado := WADORequest{
 		Type:              InstanceRaw,
@@ -684,9 +682,9 @@ func TestWADORetrieveInvalidRequest(t *testing.T) {
 		Type: InstanceRaw,
 	}
 	_, err := c.Retrieve(wado)
-	if assert.Error(t, err) {
-		assert.Equal(t, errors.New("parameters does not match the given type"), err)
-	}
+	assert.EqualError(t, err, "invalid StudyInstanceUID: required by InstanceRaw; "+
+		"invalid SeriesInstanceUID: required by InstanceRaw; "+
+		"invalid SOPInstanceUID: required by InstanceRaw")
 }
 
 func TestWADOURIReference(t *testing.T) {
@@ -709,9 +707,7 @@ func TestWADOUnspecifiedType(t *testing.T) {
 
 	wado := WADORequest{}
 	_, err := c.Retrieve(wado)
-	if assert.Error(t, err) {
-		assert.Equal(t, errors.New("parameters does not match the given type"), err)
-	}
+	assert.EqualError(t, err, `invalid Type "0": unknown retrieve type`)
 }
 
 func TestSTOWStore(t *testing.T) {
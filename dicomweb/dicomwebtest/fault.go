@@ -0,0 +1,158 @@
+package dicomwebtest
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/toastcheng/dicomweb-go/dicomweb"
+)
+
+var errInjected = errors.New("injected fault")
+
+// Fault describes a failure injected into a response of the server. The zero
+// Fault injects nothing, so that it can be used to let a request through.
+type Fault struct {
+	// Delay delays the response, or until the request is canceled.
+	Delay time.Duration
+	// Status responds with the status code instead of serving the request.
+	Status int
+	// RetryAfter sets the Retry-After header of the Status response.
+	RetryAfter string
+
+	// Truncate cuts the response body after the given number of bytes.
+	Truncate int
+	// WrongBoundary announces a boundary other than the one of the multipart body.
+	WrongBoundary bool
+	// MissingStart announces a start parameter referring to no part of the
+	// multipart body.
+	MissingStart bool
+
+	// RejectParts fails the STOW-RS parts of the given indexes, counting from 0,
+	// with server.FailureProcessing.
+	RejectParts []int
+}
+
+// Inject scripts faults for the requests of the service: the n-th next request
+// to the service gets the n-th fault. Requests beyond the script are served normally.
+func (s *Server) Inject(service dicomweb.Service, faults ...Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.faults == nil {
+		s.faults = map[dicomweb.Service][]Fault{}
+	}
+	s.faults[service] = append(s.faults[service], faults...)
+}
+
+// Requests returns the number of requests the server received for the service.
+func (s *Server) Requests(service dicomweb.Service) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.requests[service]
+}
+
+// nextFault counts the request to the service and pops its scripted fault.
+func (s *Server) nextFault(service dicomweb.Service) Fault {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.requests == nil {
+		s.requests = map[dicomweb.Service]int{}
+	}
+	s.requests[service]++
+	if len(s.faults[service]) == 0 {
+		return Fault{}
+	}
+	f := s.faults[service][0]
+	s.faults[service] = s.faults[service][1:]
+	return f
+}
+
+// serviceOf tells which service the request is addressed to.
+func serviceOf(r *http.Request) dicomweb.Service {
+	if r.Method == http.MethodPost {
+		return dicomweb.STOWService
+	}
+	seg := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch seg[len(seg)-1] {
+	case "studies", "series", "instances":
+		return dicomweb.QIDOService
+	}
+	return dicomweb.WADOService
+}
+
+// inject applies the fault before the request is served. It returns false if
+// the fault replaces the response.
+func (f Fault) inject(w http.ResponseWriter, r *http.Request) bool {
+	if f.Delay > 0 {
+		select {
+		case <-time.After(f.Delay):
+		case <-r.Context().Done():
+			return false
+		}
+	}
+	if f.Status != 0 {
+		if f.RetryAfter != "" {
+			w.Header().Set("Retry-After", f.RetryAfter)
+		}
+		http.Error(w, fmt.Sprintf("injected fault: %s", http.StatusText(f.Status)), f.Status)
+		return false
+	}
+	return true
+}
+
+// wrap returns a ResponseWriter which applies the faults on the response body.
+func (f Fault) wrap(w http.ResponseWriter) http.ResponseWriter {
+	if f.Truncate == 0 && !f.WrongBoundary && !f.MissingStart {
+		return w
+	}
+	return &faultWriter{ResponseWriter: w, fault: f}
+}
+
+type faultWriter struct {
+	http.ResponseWriter
+	fault       Fault
+	wroteHeader bool
+	written     int
+}
+
+func (w *faultWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+
+	mediaType, params, err := mime.ParseMediaType(w.Header().Get("Content-Type"))
+	if err == nil && strings.HasPrefix(mediaType, "multipart/") {
+		if w.fault.WrongBoundary {
+			params["boundary"] = "wrong-" + params["boundary"]
+		}
+		if w.fault.MissingStart {
+			params["start"] = "<missing@dicomwebtest>"
+		}
+		w.Header().Set("Content-Type", mime.FormatMediaType(mediaType, params))
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *faultWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	n := len(b)
+	if w.fault.Truncate > 0 {
+		if w.written >= w.fault.Truncate {
+			return n, nil
+		}
+		if w.written+len(b) > w.fault.Truncate {
+			b = b[:w.fault.Truncate-w.written]
+		}
+	}
+	w.written += len(b)
+	if _, err := w.ResponseWriter.Write(b); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
@@ -0,0 +1,100 @@
+package dicomwebtest_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/toastcheng/dicomweb-go/dicomweb"
+	"github.com/toastcheng/dicomweb-go/dicomweb/dicomwebtest"
+	"github.com/toastcheng/dicomweb-go/dicomweb/server"
+)
+
+var studyRequest = dicomweb.WADORequest{Type: dicomweb.StudyRaw, StudyInstanceUID: "1.2.1"}
+
+func TestFaultStatus(t *testing.T) {
+	s := newTestServer()
+	defer s.Close()
+	s.Inject(dicomweb.WADOService,
+		dicomwebtest.Fault{Status: http.StatusServiceUnavailable, RetryAfter: "0"},
+		dicomwebtest.Fault{Status: http.StatusTooManyRequests},
+	)
+
+	c := s.Client()
+	_, err := c.Retrieve(studyRequest)
+	assert.EqualError(t, err, "503 Service Unavailable")
+
+	c = c.WithRetry(dicomweb.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond})
+	parts, err := c.Retrieve(studyRequest)
+	assert.NoError(t, err)
+	assert.Len(t, parts, 3)
+	assert.Equal(t, 3, s.Requests(dicomweb.WADOService))
+	assert.Equal(t, 0, s.Requests(dicomweb.QIDOService))
+}
+
+func TestFaultMultipart(t *testing.T) {
+	s := newTestServer()
+	defer s.Close()
+	s.Inject(dicomweb.WADOService,
+		dicomwebtest.Fault{Truncate: 300},
+		dicomwebtest.Fault{WrongBoundary: true},
+		dicomwebtest.Fault{MissingStart: true},
+		dicomwebtest.Fault{},
+	)
+	c := s.Client()
+
+	_, err := c.Retrieve(studyRequest)
+	assert.Error(t, err)
+	_, err = c.Retrieve(studyRequest)
+	assert.Error(t, err)
+	_, err = c.Retrieve(studyRequest)
+	assert.EqualError(t, err, "start part <missing@dicomwebtest> not found in multipart response")
+
+	parts, err := c.Retrieve(studyRequest)
+	assert.NoError(t, err)
+	assert.Len(t, parts, 3)
+}
+
+func TestFaultDelay(t *testing.T) {
+	s := newTestServer()
+	defer s.Close()
+	s.Inject(dicomweb.QIDOService, dicomwebtest.Fault{Delay: time.Second})
+
+	c := dicomweb.NewClient(dicomweb.ClientOption{
+		QIDOEndpoint: s.URL,
+		HTTPClient:   &http.Client{Timeout: 50 * time.Millisecond},
+	})
+	_, err := c.Query(dicomweb.QIDORequest{Type: dicomweb.Study})
+	assert.Error(t, err)
+
+	resp, err := c.Query(dicomweb.QIDORequest{Type: dicomweb.Study})
+	assert.NoError(t, err)
+	assert.Len(t, resp, 2)
+}
+
+func TestFaultRejectParts(t *testing.T) {
+	s := newTestServer()
+	defer s.Close()
+	s.Inject(dicomweb.STOWService, dicomwebtest.Fault{RejectParts: []int{1}})
+
+	parts := [][]byte{}
+	for _, uid := range []string{"1.2.3.1.1", "1.2.3.1.2"} {
+		parts = append(parts, dicomwebtest.NewInstance(dicomwebtest.Instance{
+			StudyInstanceUID:  "1.2.3",
+			SeriesInstanceUID: "1.2.3.1",
+			SOPInstanceUID:    uid,
+		}))
+	}
+	resp, err := s.Client().Store(dicomweb.STOWRequest{StudyInstanceUID: "1.2.3", Parts: parts})
+	assert.NoError(t, err)
+	assert.Equal(t, 5, s.Len())
+
+	result := resp.(map[string]interface{})
+	failed := result["00081198"].(map[string]interface{})["Value"].([]interface{})
+	if assert.Len(t, failed, 1) {
+		item := failed[0].(map[string]interface{})
+		assert.Equal(t, []interface{}{"1.2.3.1.2"}, item["00081155"].(map[string]interface{})["Value"])
+		assert.Equal(t, []interface{}{float64(server.FailureProcessing)}, item["00081197"].(map[string]interface{})["Value"])
+	}
+}
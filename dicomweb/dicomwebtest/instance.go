@@ -0,0 +1,93 @@
+package dicomwebtest
+
+import (
+	"strconv"
+
+	"github.com/toastcheng/dicomweb-go/dicomweb/dicom"
+)
+
+// SecondaryCaptureImageStorage SOP class of the instances created by NewInstance.
+const SecondaryCaptureImageStorage = "1.2.840.10008.5.1.4.1.1.7"
+
+// Instance describes a synthetic instance created by NewInstance.
+type Instance struct {
+	StudyInstanceUID  string
+	SeriesInstanceUID string
+	SOPInstanceUID    string
+	// SOPClassUID uses SecondaryCaptureImageStorage otherwise.
+	SOPClassUID     string
+	PatientID       string
+	PatientName     string
+	StudyDate       string
+	StudyTime       string
+	AccessionNumber string
+	// Modality uses OT otherwise.
+	Modality       string
+	SeriesNumber   int
+	InstanceNumber int
+	// Rows uses 2 otherwise.
+	Rows int
+	// Columns uses 2 otherwise.
+	Columns int
+	// Frames number of frames. Uses 1 otherwise.
+	Frames int
+}
+
+// NewInstance creates a DICOM Part 10 file of an 8 bits monochrome image. The
+// pixels of the n-th frame, counting from 1, all have the value n.
+func NewInstance(i Instance) []byte {
+	if i.SOPClassUID == "" {
+		i.SOPClassUID = SecondaryCaptureImageStorage
+	}
+	if i.Modality == "" {
+		i.Modality = "OT"
+	}
+	if i.Rows == 0 {
+		i.Rows = 2
+	}
+	if i.Columns == 0 {
+		i.Columns = 2
+	}
+	if i.Frames == 0 {
+		i.Frames = 1
+	}
+
+	ds := &dicom.Dataset{}
+	ds.Set(dicom.NewString(dicom.TransferSyntaxUID, "UI", dicom.ExplicitVRLittleEndian))
+	ds.Set(dicom.NewString(dicom.SOPClassUID, "UI", i.SOPClassUID))
+	ds.Set(dicom.NewString(dicom.SOPInstanceUID, "UI", i.SOPInstanceUID))
+	ds.Set(dicom.NewString(dicom.StudyDate, "DA", i.StudyDate))
+	ds.Set(dicom.NewString(dicom.StudyTime, "TM", i.StudyTime))
+	ds.Set(dicom.NewString(dicom.AccessionNumber, "SH", i.AccessionNumber))
+	ds.Set(dicom.NewString(dicom.Modality, "CS", i.Modality))
+	ds.Set(dicom.NewString(dicom.PatientName, "PN", i.PatientName))
+	ds.Set(dicom.NewString(dicom.PatientID, "LO", i.PatientID))
+	ds.Set(dicom.NewString(dicom.StudyInstanceUID, "UI", i.StudyInstanceUID))
+	ds.Set(dicom.NewString(dicom.SeriesInstanceUID, "UI", i.SeriesInstanceUID))
+	ds.Set(dicom.NewString(dicom.SeriesNumber, "IS", strconv.Itoa(i.SeriesNumber)))
+	ds.Set(dicom.NewString(dicom.InstanceNumber, "IS", strconv.Itoa(i.InstanceNumber)))
+	ds.Set(dicom.NewUint16(dicom.SamplesPerPixel, 1))
+	ds.Set(dicom.NewString(0x00280004, "CS", "MONOCHROME2"))
+	ds.Set(dicom.NewString(dicom.NumberOfFrames, "IS", strconv.Itoa(i.Frames)))
+	ds.Set(dicom.NewUint16(dicom.Rows, uint16(i.Rows)))
+	ds.Set(dicom.NewUint16(dicom.Columns, uint16(i.Columns)))
+	ds.Set(dicom.NewUint16(dicom.BitsAllocated, 8))
+	ds.Set(dicom.NewUint16(0x00280101, 8))
+	ds.Set(dicom.NewUint16(0x00280102, 7))
+	ds.Set(dicom.NewUint16(0x00280103, 0))
+
+	size := i.Rows * i.Columns
+	pixels := make([]byte, size*i.Frames)
+	for f := 0; f < i.Frames; f++ {
+		for p := 0; p < size; p++ {
+			pixels[f*size+p] = byte(f + 1)
+		}
+	}
+	ds.Set(dicom.NewBytes(dicom.PixelData, "OB", pixels))
+
+	b, err := dicom.Encode(ds)
+	if err != nil {
+		panic("dicomwebtest: failed to create instance: " + err.Error())
+	}
+	return b
+}
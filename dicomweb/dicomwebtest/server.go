@@ -0,0 +1,178 @@
+// Package dicomwebtest provides an in-memory DICOMweb server for testing
+// DICOMweb clients end to end, in the spirit of net/http/httptest.
+//
+// The server implements QIDO-RS search of studies, series and instances,
+// WADO-RS retrieve of instances, metadata and frames, STOW-RS store and the
+// deletion of studies, series and instances, over the DICOM Part 10 instances
+// it is seeded with or which are stored to it.
+package dicomwebtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	"github.com/toastcheng/dicomweb-go/dicomweb"
+	"github.com/toastcheng/dicomweb-go/dicomweb/dicom"
+	"github.com/toastcheng/dicomweb-go/dicomweb/server"
+)
+
+// Server is an in-memory DICOMweb server listening on a system-chosen port on
+// the local loopback interface.
+type Server struct {
+	*httptest.Server
+
+	mu        sync.RWMutex
+	instances []*instance
+	faults    map[dicomweb.Service][]Fault
+	requests  map[dicomweb.Service]int
+	handler   *server.Handler
+}
+
+type instance struct {
+	ds   *dicom.Dataset
+	data []byte
+}
+
+func (i *instance) study() string {
+	return i.ds.String(dicom.StudyInstanceUID)
+}
+
+func (i *instance) series() string {
+	return i.ds.String(dicom.SeriesInstanceUID)
+}
+
+func (i *instance) sop() string {
+	return i.ds.String(dicom.SOPInstanceUID)
+}
+
+// NewServer starts and returns a new server seeded with the given DICOM Part 10
+// instances. It panics if an instance cannot be parsed. The caller should call
+// Close when finished, to shut it down.
+func NewServer(instances ...[]byte) *Server {
+	s := &Server{}
+	s.handler = server.NewHandler(storage{s})
+	for _, b := range instances {
+		if err := s.Add(b); err != nil {
+			panic(fmt.Sprintf("dicomwebtest: failed to add instance: %v", err))
+		}
+	}
+	s.Server = httptest.NewServer(s)
+	return s
+}
+
+// Add adds a DICOM Part 10 instance to the server, replacing the instance of the
+// same SOP Instance UID if any.
+func (s *Server) Add(data []byte) error {
+	ds, err := dicom.Parse(data)
+	if err != nil {
+		return err
+	}
+	i := &instance{ds: ds, data: data}
+	if i.study() == "" || i.series() == "" || i.sop() == "" {
+		return errors.New("instance without Study, Series or SOP Instance UID")
+	}
+	s.add(i)
+	return nil
+}
+
+func (s *Server) add(i *instance) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k, existing := range s.instances {
+		if existing.sop() == i.sop() {
+			s.instances[k] = i
+			return
+		}
+	}
+	s.instances = append(s.instances, i)
+}
+
+// remove removes the instances of the given study, series and instance, an
+// empty UID matching any, and returns the number of instances removed.
+func (s *Server) remove(study, series, sop string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kept := s.instances[:0]
+	for _, i := range s.instances {
+		if (study == "" || i.study() == study) &&
+			(series == "" || i.series() == series) &&
+			(sop == "" || i.sop() == sop) {
+			continue
+		}
+		kept = append(kept, i)
+	}
+	removed := len(s.instances) - len(kept)
+	s.instances = kept
+	return removed
+}
+
+// Instance returns the DICOM Part 10 instance of the given SOP Instance UID.
+func (s *Server) Instance(sopInstanceUID string) ([]byte, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, i := range s.instances {
+		if i.sop() == sopInstanceUID {
+			return i.data, true
+		}
+	}
+	return nil, false
+}
+
+// Len returns the number of instances held by the server.
+func (s *Server) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.instances)
+}
+
+// Client returns a client with its QIDO, WADO and STOW endpoints set to the server.
+func (s *Server) Client() *dicomweb.Client {
+	return dicomweb.NewClient(dicomweb.ClientOption{
+		QIDOEndpoint: s.URL,
+		WADOEndpoint: s.URL,
+		STOWEndpoint: s.URL,
+	})
+}
+
+// selectInstances returns the instances of the given study, series and
+// instance, an empty UID matching any. Each UID may be a list of UIDs
+// separated by commas or backslashes, as in a QIDO-RS query.
+func (s *Server) selectInstances(study, series, sop string) []*instance {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	selected := []*instance{}
+	for _, i := range s.instances {
+		if matchUID(study, i.study()) && matchUID(series, i.series()) && matchUID(sop, i.sop()) {
+			selected = append(selected, i)
+		}
+	}
+	return selected
+}
+
+// matchUID tells if the UID is in the list, an empty list matching any.
+func matchUID(list, uid string) bool {
+	if list == "" {
+		return true
+	}
+	for _, v := range strings.FieldsFunc(list, func(r rune) bool { return r == ',' || r == '\\' }) {
+		if v == uid {
+			return true
+		}
+	}
+	return false
+}
+
+// ServeHTTP injects the scripted fault, if any, and serves the request.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f := s.nextFault(serviceOf(r))
+	if !f.inject(w, r) {
+		return
+	}
+	ctx := context.WithValue(r.Context(), faultKey{}, f)
+	s.handler.ServeHTTP(f.wrap(w), r.WithContext(ctx))
+}
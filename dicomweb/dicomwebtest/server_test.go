@@ -0,0 +1,243 @@
+package dicomwebtest_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/toastcheng/dicomweb-go/dicomweb"
+	"github.com/toastcheng/dicomweb-go/dicomweb/dicom"
+	"github.com/toastcheng/dicomweb-go/dicomweb/dicomwebtest"
+)
+
+func newTestServer() *dicomwebtest.Server {
+	return dicomwebtest.NewServer(
+		dicomwebtest.NewInstance(dicomwebtest.Instance{
+			StudyInstanceUID:  "1.2.1",
+			SeriesInstanceUID: "1.2.1.1",
+			SOPInstanceUID:    "1.2.1.1.1",
+			PatientID:         "PAT-1",
+			PatientName:       "Doe^John",
+			StudyDate:         "20200101",
+			Modality:          "CT",
+		}),
+		dicomwebtest.NewInstance(dicomwebtest.Instance{
+			StudyInstanceUID:  "1.2.1",
+			SeriesInstanceUID: "1.2.1.1",
+			SOPInstanceUID:    "1.2.1.1.2",
+			PatientID:         "PAT-1",
+			PatientName:       "Doe^John",
+			StudyDate:         "20200101",
+			Modality:          "CT",
+			Frames:            3,
+		}),
+		dicomwebtest.NewInstance(dicomwebtest.Instance{
+			StudyInstanceUID:  "1.2.1",
+			SeriesInstanceUID: "1.2.1.2",
+			SOPInstanceUID:    "1.2.1.2.1",
+			PatientID:         "PAT-1",
+			PatientName:       "Doe^John",
+			StudyDate:         "20200101",
+			Modality:          "SR",
+		}),
+		dicomwebtest.NewInstance(dicomwebtest.Instance{
+			StudyInstanceUID:  "1.2.2",
+			SeriesInstanceUID: "1.2.2.1",
+			SOPInstanceUID:    "1.2.2.1.1",
+			PatientID:         "PAT-2",
+			StudyDate:         "20210101",
+			Modality:          "MR",
+		}),
+	)
+}
+
+func TestServerQueryStudies(t *testing.T) {
+	s := newTestServer()
+	defer s.Close()
+	c := s.Client()
+
+	resp, err := c.Query(dicomweb.QIDORequest{Type: dicomweb.Study})
+	assert.NoError(t, err)
+	if assert.Len(t, resp, 2) {
+		assert.Equal(t, "1.2.1", resp[0].StudyInstanceUID.Value[0])
+		assert.Equal(t, []interface{}{"CT", "SR"}, resp[0].ModalitiesInStudy.Value)
+		assert.Equal(t, []interface{}{float64(2)}, resp[0].NumberOfStudyRelatedSeries.Value)
+		assert.Equal(t, []interface{}{float64(3)}, resp[0].NumberOfStudyRelatedInstances.Value)
+		assert.Equal(t, "1.2.2", resp[1].StudyInstanceUID.Value[0])
+	}
+
+	resp, err = c.Query(dicomweb.QIDORequest{Type: dicomweb.Study, PatientID: "PAT-2"})
+	assert.NoError(t, err)
+	if assert.Len(t, resp, 1) {
+		assert.Equal(t, "1.2.2", resp[0].StudyInstanceUID.Value[0])
+	}
+
+	resp, err = c.Query(dicomweb.QIDORequest{Type: dicomweb.Study, StudyDate: "20200101-20201231"})
+	assert.NoError(t, err)
+	assert.Len(t, resp, 1)
+
+	// the search is filtered by UID, or by a list of UIDs matching any of them.
+	resp, err = c.Query(dicomweb.QIDORequest{Type: dicomweb.Study, StudyInstanceUID: "1.2.2"})
+	assert.NoError(t, err)
+	if assert.Len(t, resp, 1) {
+		assert.Equal(t, "1.2.2", resp[0].StudyInstanceUID.Value[0])
+	}
+	resp, err = c.Query(dicomweb.QIDORequest{Type: dicomweb.Study, StudyInstanceUID: "1.2.1,1.2.9"})
+	assert.NoError(t, err)
+	assert.Len(t, resp, 1)
+	r, err := http.Get(s.URL + "/studies?0020000D=1.2.1,1.2.2,1.2.9")
+	if assert.NoError(t, err) {
+		defer r.Body.Close()
+		studies := []dicomweb.QIDOResponse{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&studies))
+		assert.Len(t, studies, 2)
+	}
+
+	// filters keyed by keyword are matched as those keyed by tag.
+	resp, err = c.Query(dicomweb.QIDORequest{Type: dicomweb.Study, Filters: map[string]string{"PatientName": "doe^*"}})
+	assert.NoError(t, err)
+	if assert.Len(t, resp, 1) {
+		assert.Equal(t, "1.2.1", resp[0].StudyInstanceUID.Value[0])
+	}
+	resp, err = c.Query(dicomweb.QIDORequest{Type: dicomweb.Study, Filters: map[string]string{"00100010": "*"}})
+	assert.NoError(t, err)
+	assert.Len(t, resp, 2)
+
+	resp, err = c.Query(dicomweb.QIDORequest{Type: dicomweb.Study, Limit: 1, Offset: 1})
+	assert.NoError(t, err)
+	if assert.Len(t, resp, 1) {
+		assert.Equal(t, "1.2.2", resp[0].StudyInstanceUID.Value[0])
+	}
+}
+
+func TestServerQuerySeriesAndInstances(t *testing.T) {
+	s := newTestServer()
+	defer s.Close()
+	c := s.Client()
+
+	resp, err := c.Query(dicomweb.QIDORequest{Type: dicomweb.Series, StudyInstanceUID: "1.2.1"})
+	assert.NoError(t, err)
+	if assert.Len(t, resp, 2) {
+		assert.Equal(t, "CT", resp[0].Modality.Value[0])
+		assert.Equal(t, []interface{}{float64(2)}, resp[0].NumberOfSeriesRelatedInstances.Value)
+	}
+
+	resp, err = c.Query(dicomweb.QIDORequest{
+		Type:              dicomweb.Instance,
+		StudyInstanceUID:  "1.2.1",
+		SeriesInstanceUID: "1.2.1.1",
+	})
+	assert.NoError(t, err)
+	if assert.Len(t, resp, 2) {
+		assert.Equal(t, "1.2.1.1.2", resp[1].SOPInstanceUID.Value[0])
+		assert.Equal(t, []interface{}{float64(3)}, resp[1].NumberOfFrames.Value)
+	}
+
+	resp, err = c.Query(dicomweb.QIDORequest{Type: dicomweb.Instance, StudyInstanceUID: "1.2.1", SOPInstanceUID: "1.2.1.2.1"})
+	assert.NoError(t, err)
+	if assert.Len(t, resp, 1) {
+		assert.Equal(t, "1.2.1.2.1", resp[0].SOPInstanceUID.Value[0])
+	}
+}
+
+func TestServerRetrieve(t *testing.T) {
+	s := newTestServer()
+	defer s.Close()
+	c := s.Client()
+
+	parts, err := c.Retrieve(dicomweb.WADORequest{Type: dicomweb.StudyRaw, StudyInstanceUID: "1.2.1"})
+	assert.NoError(t, err)
+	if assert.Len(t, parts, 3) {
+		ds, err := dicom.Parse(parts[2])
+		assert.NoError(t, err)
+		assert.Equal(t, "1.2.1.2.1", ds.String(dicom.SOPInstanceUID))
+	}
+
+	parts, err = c.Retrieve(dicomweb.WADORequest{
+		Type:              dicomweb.InstanceRaw,
+		StudyInstanceUID:  "1.2.1",
+		SeriesInstanceUID: "1.2.1.1",
+		SOPInstanceUID:    "1.2.1.1.1",
+	})
+	assert.NoError(t, err)
+	if assert.Len(t, parts, 1) {
+		original, _ := s.Instance("1.2.1.1.1")
+		assert.Equal(t, original, parts[0])
+	}
+
+	_, err = c.Retrieve(dicomweb.WADORequest{Type: dicomweb.StudyRaw, StudyInstanceUID: "9.9.9"})
+	assert.EqualError(t, err, "404 Not Found")
+}
+
+func TestServerRetrieveFrames(t *testing.T) {
+	s := newTestServer()
+	defer s.Close()
+	c := s.Client()
+
+	parts, err := c.Retrieve(dicomweb.WADORequest{
+		Type:              dicomweb.Frame,
+		StudyInstanceUID:  "1.2.1",
+		SeriesInstanceUID: "1.2.1.1",
+		SOPInstanceUID:    "1.2.1.1.2",
+		FrameID:           2,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, [][]byte{{2, 2, 2, 2}}, parts)
+
+	_, err = c.Retrieve(dicomweb.WADORequest{
+		Type:              dicomweb.Frame,
+		StudyInstanceUID:  "1.2.1",
+		SeriesInstanceUID: "1.2.1.1",
+		SOPInstanceUID:    "1.2.1.1.2",
+		FrameID:           4,
+	})
+	assert.EqualError(t, err, "404 Not Found")
+}
+
+func TestServerRetrieveMetadata(t *testing.T) {
+	s := newTestServer()
+	defer s.Close()
+	c := s.Client()
+
+	_, err := c.Retrieve(dicomweb.WADORequest{
+		Type:              dicomweb.SeriesMetadata,
+		StudyInstanceUID:  "1.2.1",
+		SeriesInstanceUID: "1.2.1.1",
+	})
+	// metadata is returned as JSON, which Retrieve does not parse.
+	assert.EqualError(t, err, "unexpected Content-Type, should be multipart/related")
+}
+
+func TestServerStore(t *testing.T) {
+	s := newTestServer()
+	defer s.Close()
+	c := s.Client()
+
+	resp, err := c.Store(dicomweb.STOWRequest{
+		StudyInstanceUID: "1.2.3",
+		Parts: [][]byte{
+			dicomwebtest.NewInstance(dicomwebtest.Instance{
+				StudyInstanceUID:  "1.2.3",
+				SeriesInstanceUID: "1.2.3.1",
+				SOPInstanceUID:    "1.2.3.1.1",
+			}),
+			dicomwebtest.NewInstance(dicomwebtest.Instance{
+				StudyInstanceUID:  "1.2.4",
+				SeriesInstanceUID: "1.2.4.1",
+				SOPInstanceUID:    "1.2.4.1.1",
+			}),
+			[]byte("not dicom"),
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 5, s.Len())
+
+	result := resp.(map[string]interface{})
+	assert.Len(t, result["00081199"].(map[string]interface{})["Value"], 1)
+	assert.Len(t, result["00081198"].(map[string]interface{})["Value"], 2)
+
+	parts, err := c.Retrieve(dicomweb.WADORequest{Type: dicomweb.StudyRaw, StudyInstanceUID: "1.2.3"})
+	assert.NoError(t, err)
+	assert.Len(t, parts, 1)
+}
@@ -0,0 +1,71 @@
+package dicomwebtest
+
+import (
+	"context"
+
+	"github.com/toastcheng/dicomweb-go/dicomweb"
+	"github.com/toastcheng/dicomweb-go/dicomweb/dicom"
+	"github.com/toastcheng/dicomweb-go/dicomweb/server"
+)
+
+// faultKey is the context key of the fault injected into the request.
+type faultKey struct{}
+
+// storage is the server.Storage over the instances held in memory by the server.
+type storage struct {
+	s *Server
+}
+
+func (st storage) Search(ctx context.Context, req dicomweb.QIDORequest) ([]*dicom.Dataset, error) {
+	instances := []*dicom.Dataset{}
+	for _, i := range st.s.selectInstances(req.StudyInstanceUID, req.SeriesInstanceUID, "") {
+		instances = append(instances, i.ds)
+	}
+	return server.Search(req, instances), nil
+}
+
+func (st storage) Retrieve(ctx context.Context, req dicomweb.WADORequest) ([][]byte, error) {
+	instances := st.s.selectInstances(req.StudyInstanceUID, req.SeriesInstanceUID, req.SOPInstanceUID)
+	if len(instances) == 0 {
+		return nil, server.ErrNotFound
+	}
+	parts := [][]byte{}
+	for _, i := range instances {
+		parts = append(parts, i.data)
+	}
+	return parts, nil
+}
+
+func (st storage) Store(ctx context.Context, req dicomweb.STOWRequest) ([]error, error) {
+	f, _ := ctx.Value(faultKey{}).(Fault)
+	errs := make([]error, len(req.Parts))
+	for n, data := range req.Parts {
+		ds, err := server.CheckInstance(req.StudyInstanceUID, data)
+		if err != nil {
+			errs[n] = err
+			continue
+		}
+		if contains(f.RejectParts, n) {
+			errs[n] = &server.StoreError{Reason: server.FailureProcessing, Err: errInjected}
+			continue
+		}
+		st.s.add(&instance{ds: ds, data: data})
+	}
+	return errs, nil
+}
+
+func (st storage) Delete(ctx context.Context, req dicomweb.DeleteRequest) error {
+	if st.s.remove(req.StudyInstanceUID, req.SeriesInstanceUID, req.SOPInstanceUID) == 0 {
+		return server.ErrNotFound
+	}
+	return nil
+}
+
+func contains(list []int, n int) bool {
+	for _, v := range list {
+		if v == n {
+			return true
+		}
+	}
+	return false
+}
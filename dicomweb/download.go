@@ -0,0 +1,224 @@
+package dicomweb
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/toastcheng/dicomweb-go/dicomweb/dicom"
+	"github.com/toastcheng/dicomweb-go/dicomweb/uid"
+)
+
+// DownloadStudy downloads the instances of the study to dir, laid out as
+// <SeriesInstanceUID>/<SOPInstanceUID>.dcm, and writes the DICOMDIR of the set.
+// It returns the number of instances downloaded.
+//
+// The instances are enumerated with a QIDO-RS search, page by page, and
+// streamed to disk one at a time. Instances already on disk are skipped, so
+// that calling it again resumes an interrupted download.
+func (c *Client) DownloadStudy(studyInstanceUID, dir string) (int, error) {
+	resp, err := c.studyInstances(studyInstanceUID)
+	if err != nil {
+		return 0, err
+	}
+
+	// series of each instance, and instances missing on disk by series.
+	seriesOf := map[string]string{}
+	seriesList := []string{}
+	missing := map[string][]string{}
+	total := map[string]int{}
+	for _, r := range resp {
+		series, sop := tagValue(r.SeriesInstanceUID), tagValue(r.SOPInstanceUID)
+		if !uid.Valid(series) || !uid.Valid(sop) {
+			return 0, fmt.Errorf("invalid UID in search result: %q/%q", series, sop)
+		}
+		if _, ok := total[series]; !ok {
+			seriesList = append(seriesList, series)
+		}
+		seriesOf[sop] = series
+		total[series]++
+		if _, err := os.Stat(instancePath(dir, series, sop)); os.IsNotExist(err) {
+			missing[series] = append(missing[series], sop)
+		}
+	}
+	if len(resp) == 0 {
+		return 0, fmt.Errorf("study %s not found", studyInstanceUID)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return 0, err
+	}
+	// partial files of an interrupted download.
+	if stale, err := filepath.Glob(filepath.Join(dir, ".part-*")); err == nil {
+		for _, f := range stale {
+			os.Remove(f)
+		}
+	}
+
+	n := 0
+	save := func(p io.Reader, root bool) error {
+		if err := saveInstance(dir, p, seriesOf); err != nil {
+			return err
+		}
+		n++
+		return nil
+	}
+
+	// the whole study at once when nothing is on disk, otherwise the missing
+	// series, or the missing instances of the series partially on disk.
+	reqs := []WADORequest{}
+	nothingOnDisk := true
+	for series, count := range total {
+		nothingOnDisk = nothingOnDisk && len(missing[series]) == count
+	}
+	if nothingOnDisk {
+		reqs = append(reqs, WADORequest{Type: StudyRaw, StudyInstanceUID: studyInstanceUID})
+	} else {
+		for _, series := range seriesList {
+			switch len(missing[series]) {
+			case 0:
+			case total[series]:
+				reqs = append(reqs, WADORequest{Type: SeriesRaw, StudyInstanceUID: studyInstanceUID, SeriesInstanceUID: series})
+			default:
+				for _, sop := range missing[series] {
+					reqs = append(reqs, WADORequest{Type: InstanceRaw, StudyInstanceUID: studyInstanceUID, SeriesInstanceUID: series, SOPInstanceUID: sop})
+				}
+			}
+		}
+	}
+	for _, req := range reqs {
+		if err := c.retrieve(req, save); err != nil {
+			return n, err
+		}
+	}
+
+	files := []dicom.DirectoryFile{}
+	for _, series := range seriesList {
+		sops, err := filepath.Glob(filepath.Join(dir, series, "*.dcm"))
+		if err != nil {
+			return n, err
+		}
+		for _, f := range sops {
+			ds, err := dicom.ReadFile(f)
+			if err != nil {
+				return n, err
+			}
+			files = append(files, dicom.DirectoryFile{
+				ID:      []string{series, filepath.Base(f)},
+				Dataset: ds,
+			})
+		}
+	}
+	b, err := dicom.EncodeDICOMDIR("", files)
+	if err != nil {
+		return n, err
+	}
+	return n, writeFile(filepath.Join(dir, "DICOMDIR"), bytes.NewReader(b))
+}
+
+// saveInstance streams the DICOM Part 10 instance to its file.
+func saveInstance(dir string, p io.Reader, seriesOf map[string]string) error {
+	tmp, err := ioutil.TempFile(dir, ".part-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = io.Copy(tmp, p)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read multipart response: %v", err)
+	}
+
+	meta, err := dicom.ReadFileMeta(tmp.Name())
+	if err != nil {
+		return err
+	}
+	sop := meta.String(dicom.MediaStorageSOPInstanceUID)
+	series, ok := seriesOf[sop]
+	if !ok {
+		// an instance the search did not return.
+		ds, err := dicom.ReadFile(tmp.Name())
+		if err != nil {
+			return err
+		}
+		series = ds.String(dicom.SeriesInstanceUID)
+	}
+	if !uid.Valid(series) || !uid.Valid(sop) {
+		return fmt.Errorf("invalid UID in retrieved instance: %q/%q", series, sop)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, series), 0755); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), instancePath(dir, series, sop))
+}
+
+// writeFile writes the file through a temporary file, so that it is either
+// complete or missing.
+func writeFile(name string, r io.Reader) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(name), ".part-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = io.Copy(tmp, r)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
+
+// studyInstancesPageSize number of instances searched per request by studyInstances.
+const studyInstancesPageSize = 1000
+
+// studyInstances searches the instances of the study page by page, as servers
+// cap the number of results of a search, possibly below the requested limit,
+// until a page is empty or adds no new instance.
+func (c *Client) studyInstances(studyInstanceUID string) ([]QIDOResponse, error) {
+	instances := []QIDOResponse{}
+	seen := map[string]bool{}
+	for offset := 0; ; {
+		page, err := c.Query(QIDORequest{
+			Type:             Instance,
+			StudyInstanceUID: studyInstanceUID,
+			Limit:            studyInstancesPageSize,
+			Offset:           offset,
+		})
+		if err != nil {
+			return nil, err
+		}
+		added := 0
+		for _, r := range page {
+			if sop := tagValue(r.SOPInstanceUID); !seen[sop] {
+				seen[sop] = true
+				instances = append(instances, r)
+				added++
+			}
+		}
+		// a server ignoring the offset returns the same page again.
+		if added == 0 {
+			return instances, nil
+		}
+		offset += len(page)
+	}
+}
+
+func instancePath(dir, series, sop string) string {
+	return filepath.Join(dir, series, sop+".dcm")
+}
+
+// tagValue returns the first value of the tag as a string.
+func tagValue(t Tag) string {
+	if len(t.Value) == 0 {
+		return ""
+	}
+	s, _ := t.Value[0].(string)
+	return s
+}
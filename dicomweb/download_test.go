@@ -0,0 +1,78 @@
+package dicomweb_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/toastcheng/dicomweb-go/dicomweb"
+	"github.com/toastcheng/dicomweb-go/dicomweb/dicom"
+	"github.com/toastcheng/dicomweb-go/dicomweb/dicomwebtest"
+)
+
+func TestDownloadStudy(t *testing.T) {
+	instances := [][]byte{}
+	for _, uids := range [][2]string{
+		{"1.2.1.1", "1.2.1.1.1"},
+		{"1.2.1.1", "1.2.1.1.2"},
+		{"1.2.1.2", "1.2.1.2.1"},
+	} {
+		instances = append(instances, dicomwebtest.NewInstance(dicomwebtest.Instance{
+			StudyInstanceUID:  "1.2.1",
+			SeriesInstanceUID: uids[0],
+			SOPInstanceUID:    uids[1],
+			PatientID:         "PAT-1",
+		}))
+	}
+	s := dicomwebtest.NewServer(instances...)
+	defer s.Close()
+	c := s.Client()
+
+	dir, err := ioutil.TempDir("", "download")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	n, err := c.DownloadStudy("1.2.1", dir)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, n)
+	assert.Equal(t, 2, s.Requests(dicomweb.QIDOService))
+	assert.Equal(t, 1, s.Requests(dicomweb.WADOService))
+	data, err := ioutil.ReadFile(filepath.Join(dir, "1.2.1.1", "1.2.1.1.2.dcm"))
+	assert.NoError(t, err)
+	assert.Equal(t, instances[1], data)
+
+	ds, err := dicom.ReadFile(filepath.Join(dir, "DICOMDIR"))
+	assert.NoError(t, err)
+	types := []string{}
+	for _, item := range ds.Get(dicom.DirectoryRecordSequence).Items {
+		types = append(types, item.String(dicom.DirectoryRecordType))
+	}
+	assert.Equal(t, []string{"PATIENT", "STUDY", "SERIES", "IMAGE", "IMAGE", "SERIES", "IMAGE"}, types)
+
+	// resume with one instance and one series missing.
+	os.Remove(filepath.Join(dir, "1.2.1.1", "1.2.1.1.1.dcm"))
+	os.RemoveAll(filepath.Join(dir, "1.2.1.2"))
+	n, err = c.DownloadStudy("1.2.1", dir)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, 3, s.Requests(dicomweb.WADOService))
+	assert.FileExists(t, filepath.Join(dir, "1.2.1.1", "1.2.1.1.1.dcm"))
+	assert.FileExists(t, filepath.Join(dir, "1.2.1.2", "1.2.1.2.1.dcm"))
+
+	n, err = c.DownloadStudy("1.2.1", dir)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+	assert.Equal(t, 3, s.Requests(dicomweb.WADOService))
+}
+
+func TestDownloadStudyNotFound(t *testing.T) {
+	s := dicomwebtest.NewServer()
+	defer s.Close()
+
+	_, err := s.Client().DownloadStudy("1.2.9", os.TempDir())
+	assert.EqualError(t, err, "study 1.2.9 not found")
+}
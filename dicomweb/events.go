@@ -0,0 +1,303 @@
+package dicomweb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// EventType defines the type of a UPS event report, see PS3.4 CC.2.4.
+type EventType int
+
+const (
+	// StateReportEvent the state of the workitem changed.
+	StateReportEvent EventType = iota + 1
+	// CancelRequestedEvent the cancellation of the workitem was requested.
+	CancelRequestedEvent
+	// ProgressReportEvent the progress of the workitem changed.
+	ProgressReportEvent
+	// SCPStatusChangeEvent the status of the worklist service changed, e.g. it is going down.
+	SCPStatusChangeEvent
+	// AssignedEvent the workitem was assigned to a performer.
+	AssignedEvent
+)
+
+// String returns the name of the event type.
+func (t EventType) String() string {
+	switch t {
+	case StateReportEvent:
+		return "UPS State Report"
+	case CancelRequestedEvent:
+		return "UPS Cancel Requested"
+	case ProgressReportEvent:
+		return "UPS Progress Report"
+	case SCPStatusChangeEvent:
+		return "SCP Status Change"
+	case AssignedEvent:
+		return "UPS Assigned"
+	}
+	return "unknown"
+}
+
+// Tags of the event report attributes with a field in EventReport.
+const (
+	tagAffectedSOPInstanceUID = "00001000"
+	tagEventTypeID            = "00001002"
+)
+
+// EventReport defines an event of a subscribed workitem, delivered over the
+// event channel.
+type EventReport struct {
+	// WorkitemUID UID of the workitem the event is about.
+	WorkitemUID string
+	// Type type of the event.
+	Type EventType
+	// State state of the workitem, for state reports.
+	State ProcedureStepState
+	// ReasonForCancellation reason given by the requester, for cancel requests.
+	ReasonForCancellation string
+	// Attributes all the attributes of the event report, keyed by tag.
+	Attributes map[string]Tag
+}
+
+// UnmarshalJSON decodes the event report from the DICOM JSON model.
+func (e *EventReport) UnmarshalJSON(b []byte) error {
+	attrs := map[string]Tag{}
+	if err := json.Unmarshal(b, &attrs); err != nil {
+		return err
+	}
+	value := func(tag string) string {
+		if t, ok := attrs[tag]; ok && len(t.Value) > 0 {
+			return tagString(t.Value[0])
+		}
+		return ""
+	}
+	*e = EventReport{
+		WorkitemUID:           value(tagAffectedSOPInstanceUID),
+		State:                 ProcedureStepState(value(tagProcedureStepState)),
+		ReasonForCancellation: value(tagReasonForCancellation),
+		Attributes:            attrs,
+	}
+	if id, err := strconv.Atoi(value(tagEventTypeID)); err == nil {
+		e.Type = EventType(id)
+	}
+	return nil
+}
+
+// Subscription specifies the workitems an AE subscribes to.
+type Subscription struct {
+	// AETitle AE title of the subscriber, which names its event channel.
+	AETitle string
+	// WorkitemUID workitem to subscribe to. The whole worklist is subscribed to otherwise
+	WorkitemUID string
+	// Filters attributes the workitems of a global subscription must match.
+	Filters map[string]string
+	// DeletionLock keeps the completed or canceled workitems until the
+	// subscriber receives their last event.
+	DeletionLock bool
+}
+
+// Subscribe subscribes the AE to the events of a workitem, of the workitems
+// matching the filters, or of the whole worklist. The events are delivered
+// over the event channel of the AE, see Events.
+func (c *Client) Subscribe(s Subscription) error {
+	_, err := c.Worklist(UPSRequest{
+		Type:         SubscribeWorklist,
+		WorkitemUID:  s.WorkitemUID,
+		Filters:      s.Filters,
+		AETitle:      s.AETitle,
+		DeletionLock: s.DeletionLock,
+	})
+	return err
+}
+
+// Unsubscribe unsubscribes the AE from the events of a workitem, or of the
+// whole worklist and all the workitems if s.WorkitemUID is empty.
+func (c *Client) Unsubscribe(s Subscription) error {
+	_, err := c.Worklist(UPSRequest{
+		Type:        UnsubscribeWorklist,
+		WorkitemUID: s.WorkitemUID,
+		AETitle:     s.AETitle,
+	})
+	return err
+}
+
+// SuspendSubscription suspends the global subscription of the AE, so that it
+// is not subscribed to the new workitems, while it keeps receiving the events
+// of the workitems it is already subscribed to.
+func (c *Client) SuspendSubscription(aeTitle string) error {
+	_, err := c.Worklist(UPSRequest{Type: SuspendWorklistSubscription, AETitle: aeTitle})
+	return err
+}
+
+// EventOption specifies the option of the event channel.
+type EventOption struct {
+	// URL WebSocket URL of the event channel. Uses the subscribers resource of the UPS endpoint otherwise
+	URL string
+	// Buffer size of the buffer of the returned channel.
+	Buffer int
+	// ReconnectBackoff backoff before reconnecting after the connection is lost, doubling on each failed attempt. Uses 1s otherwise.
+	ReconnectBackoff time.Duration
+	// MaxReconnectBackoff upper bound of the backoff. Uses 30s otherwise.
+	MaxReconnectBackoff time.Duration
+	// OnError is called with the errors of the event channel: failed
+	// connections, lost connections and malformed event reports.
+	OnError func(error)
+}
+
+// Events opens the event channel of the AE and returns the event reports it
+// receives, until ctx is done, after which the returned channel is closed.
+// The connection is reopened whenever it is lost; it fails only if the first
+// connection fails. The events sent while the channel was disconnected may be
+// lost, depending on the server.
+//
+// The event channel is a WebSocket connection, it does not go through the
+// middlewares, the retry policy nor the limits of the client.
+func (c *Client) Events(ctx context.Context, aeTitle string, option EventOption) (<-chan EventReport, error) {
+	if aeTitle == "" {
+		return nil, &ValidationError{Field: "AETitle", Err: fmt.Errorf("required by the event channel")}
+	}
+	u := option.URL
+	if u == "" {
+		u = c.upsEndpoint + "/subscribers/" + url.PathEscape(aeTitle)
+	}
+	u, err := websocketURL(u)
+	if err != nil {
+		return nil, err
+	}
+	if option.ReconnectBackoff <= 0 {
+		option.ReconnectBackoff = time.Second
+	}
+	if option.MaxReconnectBackoff <= 0 {
+		option.MaxReconnectBackoff = 30 * time.Second
+	}
+
+	conn, err := c.dialEvents(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	events := make(chan EventReport, option.Buffer)
+	go func() {
+		defer close(events)
+		backoff := option.ReconnectBackoff
+		for {
+			c.readEvents(ctx, conn, events, option.OnError)
+			for {
+				if ctx.Err() != nil {
+					return
+				}
+				timer := time.NewTimer(backoff)
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return
+				case <-timer.C:
+				}
+				if conn, err = c.dialEvents(ctx, u); err == nil {
+					backoff = option.ReconnectBackoff
+					break
+				}
+				if option.OnError != nil {
+					option.OnError(err)
+				}
+				if backoff *= 2; backoff > option.MaxReconnectBackoff {
+					backoff = option.MaxReconnectBackoff
+				}
+			}
+		}
+	}()
+	return events, nil
+}
+
+// dialEvents opens a connection to the event channel, with the authorization
+// and the headers set by the OptionFuncs.
+func (c *Client) dialEvents(ctx context.Context, u string) (*websocket.Conn, error) {
+	r, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.authorize(r); err != nil {
+		return nil, err
+	}
+
+	dialer := *websocket.DefaultDialer
+	if tr, ok := c.httpClient.Transport.(*http.Transport); ok {
+		dialer.TLSClientConfig = tr.TLSClientConfig
+		dialer.Proxy = tr.Proxy
+	}
+	conn, resp, err := dialer.DialContext(ctx, u, r.Header)
+	if err != nil {
+		if resp != nil {
+			return nil, fmt.Errorf("failed to open event channel: %s", resp.Status)
+		}
+		return nil, fmt.Errorf("failed to open event channel: %v", err)
+	}
+	return conn, nil
+}
+
+// readEvents sends the event reports received on the connection to events,
+// until the connection is lost or ctx is done. A message holds an event report
+// or an array of them.
+func (c *Client) readEvents(ctx context.Context, conn *websocket.Conn, events chan<- EventReport, onError func(error)) {
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-done:
+		}
+		conn.Close()
+	}()
+
+	for {
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			if ctx.Err() == nil && onError != nil {
+				onError(fmt.Errorf("event channel lost: %v", err))
+			}
+			return
+		}
+		reports := []EventReport{}
+		if msg = bytes.TrimSpace(msg); len(msg) > 0 && msg[0] == '{' {
+			msg = append(append([]byte{'['}, msg...), ']')
+		}
+		if err := json.Unmarshal(msg, &reports); err != nil {
+			if onError != nil {
+				onError(fmt.Errorf("failed to decode event report: %v", err))
+			}
+			continue
+		}
+		for _, r := range reports {
+			select {
+			case events <- r:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// websocketURL returns the URL with its http scheme replaced by ws, or https by wss.
+func websocketURL(v string) (string, error) {
+	u, err := url.Parse(v)
+	if err != nil {
+		return "", err
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "ws":
+		u.Scheme = "ws"
+	case "https", "wss":
+		u.Scheme = "wss"
+	default:
+		return "", &ValidationError{Field: "URL", Value: v, Err: fmt.Errorf("not an absolute http, https, ws or wss URL")}
+	}
+	return u.String(), nil
+}
@@ -0,0 +1,108 @@
+package dicomweb
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvents(t *testing.T) {
+	var connections int32
+	upgrader := websocket.Upgrader{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/subscribers/AI_ENGINE", r.URL.Path)
+		assert.Equal(t, "Basic dXNlcjpwYXNzd29yZA==", r.Header.Get("Authorization"))
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		switch atomic.AddInt32(&connections, 1) {
+		case 1:
+			conn.WriteMessage(websocket.TextMessage, []byte(`{
+				"00001000": {"vr": "UI", "Value": ["1.2.3"]},
+				"00001002": {"vr": "US", "Value": [1]},
+				"00741000": {"vr": "CS", "Value": ["IN PROGRESS"]}
+			}`))
+			// the connection is lost, the client reconnects.
+		case 2:
+			conn.WriteMessage(websocket.TextMessage, []byte(`not json`))
+			conn.WriteMessage(websocket.TextMessage, []byte(`[{
+				"00001000": {"vr": "UI", "Value": ["1.2.3"]},
+				"00001002": {"vr": "US", "Value": [2]},
+				"00741238": {"vr": "LT", "Value": ["patient left"]}
+			}]`))
+			conn.ReadMessage()
+		}
+	}))
+	defer ts.Close()
+	c := NewClient(ClientOption{UPSEndpoint: ts.URL}).WithAuthentication("user:password")
+
+	errs := make(chan error, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	events, err := c.Events(ctx, "AI_ENGINE", EventOption{
+		ReconnectBackoff: time.Millisecond,
+		OnError:          func(err error) { errs <- err },
+	})
+	assert.NoError(t, err)
+
+	e := <-events
+	assert.Equal(t, "1.2.3", e.WorkitemUID)
+	assert.Equal(t, StateReportEvent, e.Type)
+	assert.Equal(t, StateInProgress, e.State)
+
+	e = <-events
+	assert.Equal(t, CancelRequestedEvent, e.Type)
+	assert.Equal(t, "patient left", e.ReasonForCancellation)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&connections))
+	assert.Contains(t, (<-errs).Error(), "event channel lost")
+	assert.Contains(t, (<-errs).Error(), "failed to decode event report")
+
+	cancel()
+	select {
+	case _, ok := <-events:
+		assert.False(t, ok)
+	case <-time.After(time.Second):
+		t.Fatal("event channel not closed")
+	}
+}
+
+func TestEventsFailure(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer ts.Close()
+	c := NewClient(ClientOption{UPSEndpoint: ts.URL})
+
+	_, err := c.Events(context.Background(), "AI_ENGINE", EventOption{})
+	assert.EqualError(t, err, "failed to open event channel: 403 Forbidden")
+
+	_, err = c.Events(context.Background(), "", EventOption{})
+	assert.EqualError(t, err, "invalid AETitle: required by the event channel")
+
+	_, err = c.Events(context.Background(), "AI_ENGINE", EventOption{URL: "ftp://pacs/subscribers/AI_ENGINE"})
+	assert.EqualError(t, err, `invalid URL "ftp://pacs/subscribers/AI_ENGINE": not an absolute http, https, ws or wss URL`)
+}
+
+func TestSubscribe(t *testing.T) {
+	ts, _, subscriptions := newUPSTestServer(t)
+	defer ts.Close()
+	c := NewClient(ClientOption{UPSEndpoint: ts.URL})
+
+	assert.NoError(t, c.Subscribe(Subscription{AETitle: "AI_ENGINE", WorkitemUID: "1.2.3"}))
+	assert.NoError(t, c.Subscribe(Subscription{AETitle: "AI_ENGINE", DeletionLock: true}))
+	assert.NoError(t, c.SuspendSubscription("AI_ENGINE"))
+	assert.NoError(t, c.Unsubscribe(Subscription{AETitle: "AI_ENGINE", WorkitemUID: "1.2.3"}))
+	assert.Equal(t, []string{
+		"POST /workitems/1.2.3/subscribers/AI_ENGINE?",
+		"POST /workitems/1.2.840.10008.5.1.4.34.5/subscribers/AI_ENGINE?deletionlock=true",
+		"POST /workitems/1.2.840.10008.5.1.4.34.5/subscribers/AI_ENGINE/suspend?",
+		"DELETE /workitems/1.2.3/subscribers/AI_ENGINE?",
+	}, *subscriptions)
+}
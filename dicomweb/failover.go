@@ -0,0 +1,289 @@
+package dicomweb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EndpointSet defines the endpoints of a PACS.
+type EndpointSet struct {
+	// Name name of the PACS in the statistics. Uses the QIDO endpoint otherwise
+	Name string
+	// QIDOEndpoint endpoint for QIDO.
+	QIDOEndpoint string
+	// WADOEndpoint endpoint for WADO.
+	WADOEndpoint string
+	// STOWEndpoint endpoint for STOW.
+	STOWEndpoint string
+	// UPSEndpoint endpoint for UPS-RS.
+	UPSEndpoint string
+	// HealthCheckURL URL probed with a GET request to check the PACS is up. Uses a search of one study on the QIDO endpoint otherwise
+	HealthCheckURL string
+}
+
+// endpoint returns the endpoint of the service.
+func (s EndpointSet) endpoint(service Service) string {
+	switch service {
+	case QIDOService:
+		return s.QIDOEndpoint
+	case WADOService:
+		return s.WADOEndpoint
+	case STOWService:
+		return s.STOWEndpoint
+	case UPSService:
+		return s.UPSEndpoint
+	}
+	return ""
+}
+
+// FailoverOption specifies the PACS a client fails over to.
+type FailoverOption struct {
+	// Endpoints endpoint sets of the PACS, in order of preference.
+	Endpoints []EndpointSet
+	// Cooldown time a PACS which failed is skipped for, after which it is
+	// health checked before it is used again. Uses 30s otherwise
+	Cooldown time.Duration
+	// HealthCheckTimeout timeout of a health check. Uses 5s otherwise
+	HealthCheckTimeout time.Duration
+}
+
+// EndpointStats defines the statistics of a PACS of the failover.
+type EndpointStats struct {
+	// Name name of the PACS.
+	Name string
+	// Healthy tells if the PACS is used, false after it failed until it passes a health check.
+	Healthy bool
+	// Requests number of requests sent to the PACS.
+	Requests int64
+	// Failures number of requests which failed with a connection error or a 5xx status.
+	Failures int64
+	// Failovers number of failed requests which were sent to the next PACS.
+	Failovers int64
+	// HealthChecks number of health checks of the PACS.
+	HealthChecks int64
+	// LastError error of the last failed request or health check, if any.
+	LastError error
+	// LastFailure time of the last failed request or health check.
+	LastFailure time.Time
+}
+
+// WithFailover configures the client to send its requests to the first
+// healthy PACS of the ordered endpoint sets. A request which fails with a
+// connection error or a 5xx status is sent again to the next PACS, and the
+// PACS which failed is skipped until its cooldown elapses and it passes a
+// health check. If no PACS is healthy, they are all tried in order.
+//
+// The endpoints of the client are replaced by the ones of the first set. The
+// failover wraps the retry policy and the limits of the client, inside all the
+// middlewares whenever they are added, so that each PACS is tried with the
+// retry policy while the middlewares see the URLs of the first set. Requests
+// without a body to send again, and the event channel, do not fail over.
+func (c *Client) WithFailover(option FailoverOption) (*Client, error) {
+	if len(option.Endpoints) == 0 {
+		return nil, errors.New("no endpoint set to fail over to")
+	}
+	if option.Cooldown <= 0 {
+		option.Cooldown = 30 * time.Second
+	}
+	if option.HealthCheckTimeout <= 0 {
+		option.HealthCheckTimeout = 5 * time.Second
+	}
+	f := &failover{client: c, option: option}
+	for _, s := range option.Endpoints {
+		name := s.Name
+		if name == "" {
+			name = s.QIDOEndpoint
+		}
+		f.stats = append(f.stats, &EndpointStats{Name: name, Healthy: true})
+	}
+
+	primary := option.Endpoints[0]
+	c.qidoEndpoint = primary.QIDOEndpoint
+	c.wadoEndpoint = primary.WADOEndpoint
+	c.stowEndpoint = primary.STOWEndpoint
+	c.upsEndpoint = primary.UPSEndpoint
+	c.failover = f
+	return c, nil
+}
+
+// EndpointStats returns the statistics of each PACS of the failover, in the
+// order of the endpoint sets, or nil if the client does not fail over.
+func (c *Client) EndpointStats() []EndpointStats {
+	if c.failover == nil {
+		return nil
+	}
+	f := c.failover
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	stats := make([]EndpointStats, len(f.stats))
+	for i, s := range f.stats {
+		stats[i] = *s
+	}
+	return stats
+}
+
+// CheckEndpoints health checks each PACS of the failover, updating whether it
+// is used, and returns the error of each health check, nil if it passed.
+func (c *Client) CheckEndpoints(ctx context.Context) []error {
+	if c.failover == nil {
+		return nil
+	}
+	errs := make([]error, len(c.failover.option.Endpoints))
+	for i := range errs {
+		errs[i] = c.failover.check(ctx, i)
+	}
+	return errs
+}
+
+// failover holds the state of the PACS of a client.
+type failover struct {
+	client *Client
+	option FailoverOption
+	mu     sync.Mutex
+	stats  []*EndpointStats
+}
+
+func (f *failover) middleware(next http.RoundTripper) http.RoundTripper {
+	return RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		op, ok := OperationFromContext(r.Context())
+		primary := ""
+		if ok {
+			primary = f.option.Endpoints[0].endpoint(op.Service)
+		}
+		if primary == "" || !hasPathPrefix(r.URL.String(), primary) {
+			// e.g. a retrieve URL outside of the endpoints.
+			return next.RoundTrip(r)
+		}
+		rest := strings.TrimPrefix(r.URL.String(), primary)
+
+		candidates := f.candidates(r.Context())
+		for n, i := range candidates {
+			u, err := url.Parse(f.option.Endpoints[i].endpoint(op.Service) + rest)
+			if err != nil {
+				return nil, err
+			}
+			attempt := r.WithContext(r.Context())
+			attempt.URL = u
+			attempt.Host = u.Host
+			if n > 0 && r.Body != nil {
+				if attempt.Body, err = r.GetBody(); err != nil {
+					return nil, err
+				}
+			}
+
+			resp, err := next.RoundTrip(attempt)
+			last := n == len(candidates)-1 || (r.Body != nil && r.GetBody == nil)
+			if err == nil && resp.StatusCode < 500 {
+				f.record(i, nil, false)
+				return resp, nil
+			}
+			if err != nil && r.Context().Err() != nil {
+				return nil, err
+			}
+			if err == nil {
+				f.record(i, errors.New(resp.Status), !last)
+			} else {
+				f.record(i, err, !last)
+			}
+			if last {
+				return resp, err
+			}
+			if resp != nil {
+				io.Copy(ioutil.Discard, resp.Body)
+				resp.Body.Close()
+			}
+		}
+		return nil, errors.New("no endpoint set to send the request to")
+	})
+}
+
+// candidates returns the indexes of the PACS to try in order: the healthy
+// ones, and the unhealthy ones whose cooldown elapsed if they pass a health
+// check. All the PACS are returned if none of them is healthy.
+func (f *failover) candidates(ctx context.Context) []int {
+	candidates := []int{}
+	for i := range f.option.Endpoints {
+		f.mu.Lock()
+		healthy := f.stats[i].Healthy
+		cooled := time.Since(f.stats[i].LastFailure) >= f.option.Cooldown
+		f.mu.Unlock()
+		if healthy || (cooled && f.check(ctx, i) == nil) {
+			candidates = append(candidates, i)
+		}
+	}
+	if len(candidates) == 0 {
+		for i := range f.option.Endpoints {
+			candidates = append(candidates, i)
+		}
+	}
+	return candidates
+}
+
+// check health checks the PACS, marking it healthy if the check passes and
+// unhealthy otherwise.
+func (f *failover) check(ctx context.Context, i int) error {
+	s := f.option.Endpoints[i]
+	u := s.HealthCheckURL
+	if u == "" {
+		u = s.QIDOEndpoint + "/studies?limit=1"
+	}
+	ctx, cancel := context.WithTimeout(ctx, f.option.HealthCheckTimeout)
+	defer cancel()
+
+	err := func() error {
+		r, err := http.NewRequest("GET", u, nil)
+		if err != nil {
+			return err
+		}
+		if err := f.client.authorize(r); err != nil {
+			return err
+		}
+		resp, err := f.client.httpClient.Do(r.WithContext(ctx))
+		if err != nil {
+			return err
+		}
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode/100 != 2 {
+			return fmt.Errorf("health check failed: %s", resp.Status)
+		}
+		return nil
+	}()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	st := f.stats[i]
+	st.HealthChecks++
+	st.Healthy = err == nil
+	if err != nil {
+		st.LastError = err
+		st.LastFailure = time.Now()
+	}
+	return err
+}
+
+// record records the outcome of a request sent to the PACS.
+func (f *failover) record(i int, err error, failedOver bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	st := f.stats[i]
+	st.Requests++
+	if err == nil {
+		return
+	}
+	st.Failures++
+	st.Healthy = false
+	st.LastError = err
+	st.LastFailure = time.Now()
+	if failedOver {
+		st.Failovers++
+	}
+}
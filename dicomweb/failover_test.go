@@ -0,0 +1,138 @@
+package dicomweb_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/toastcheng/dicomweb-go/dicomweb"
+	"github.com/toastcheng/dicomweb-go/dicomweb/dicomwebtest"
+)
+
+func endpointSet(name string, s *dicomwebtest.Server) dicomweb.EndpointSet {
+	return dicomweb.EndpointSet{Name: name, QIDOEndpoint: s.URL, WADOEndpoint: s.URL, STOWEndpoint: s.URL}
+}
+
+func TestFailover(t *testing.T) {
+	primary, dr := newParallelTestServer(), newParallelTestServer()
+	defer primary.Close()
+	defer dr.Close()
+
+	c, err := dicomweb.NewClient(dicomweb.ClientOption{}).WithFailover(dicomweb.FailoverOption{
+		Endpoints: []dicomweb.EndpointSet{endpointSet("primary", primary), endpointSet("dr", dr)},
+		Cooldown:  time.Hour,
+	})
+	assert.NoError(t, err)
+
+	// the primary fails, the request is sent to the DR PACS.
+	primary.Inject(dicomweb.QIDOService, dicomwebtest.Fault{Status: http.StatusServiceUnavailable})
+	studies, err := c.Query(dicomweb.QIDORequest{Type: dicomweb.Study})
+	assert.NoError(t, err)
+	assert.Len(t, studies, 1)
+	assert.Equal(t, 1, primary.Requests(dicomweb.QIDOService))
+	assert.Equal(t, 1, dr.Requests(dicomweb.QIDOService))
+
+	// the primary is skipped during its cooldown.
+	_, err = c.Query(dicomweb.QIDORequest{Type: dicomweb.Study})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, primary.Requests(dicomweb.QIDOService))
+	assert.Equal(t, 2, dr.Requests(dicomweb.QIDOService))
+
+	stats := c.EndpointStats()
+	assert.Equal(t, "primary", stats[0].Name)
+	assert.False(t, stats[0].Healthy)
+	assert.Equal(t, int64(1), stats[0].Requests)
+	assert.Equal(t, int64(1), stats[0].Failures)
+	assert.Equal(t, int64(1), stats[0].Failovers)
+	assert.EqualError(t, stats[0].LastError, "503 Service Unavailable")
+	assert.True(t, stats[1].Healthy)
+	assert.Equal(t, int64(2), stats[1].Requests)
+
+	// the primary passes a health check, it is used again.
+	assert.Equal(t, []error{nil, nil}, c.CheckEndpoints(context.Background()))
+	assert.True(t, c.EndpointStats()[0].Healthy)
+	_, err = c.Query(dicomweb.QIDORequest{Type: dicomweb.Study})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, primary.Requests(dicomweb.QIDOService))
+}
+
+func TestFailoverMiddlewares(t *testing.T) {
+	primary, dr := newParallelTestServer(), newParallelTestServer()
+	defer primary.Close()
+	defer dr.Close()
+
+	c, err := dicomweb.NewClient(dicomweb.ClientOption{}).WithFailover(dicomweb.FailoverOption{
+		Endpoints: []dicomweb.EndpointSet{endpointSet("primary", primary), endpointSet("dr", dr)},
+	})
+	assert.NoError(t, err)
+	// a middleware added afterwards sees the request once, to the primary.
+	urls := []string{}
+	c.WithMiddleware(func(next http.RoundTripper) http.RoundTripper {
+		return dicomweb.RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			urls = append(urls, r.URL.String())
+			return next.RoundTrip(r)
+		})
+	})
+
+	primary.Inject(dicomweb.QIDOService, dicomwebtest.Fault{Status: http.StatusServiceUnavailable})
+	_, err = c.Query(dicomweb.QIDORequest{Type: dicomweb.Study})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{primary.URL + "/studies"}, urls)
+	assert.Equal(t, 1, dr.Requests(dicomweb.QIDOService))
+}
+
+func TestFailoverStore(t *testing.T) {
+	primary, dr := newParallelTestServer(), dicomwebtest.NewServer()
+	defer dr.Close()
+	// the primary is down.
+	primary.Close()
+
+	c, err := dicomweb.NewClient(dicomweb.ClientOption{}).WithFailover(dicomweb.FailoverOption{
+		Endpoints: []dicomweb.EndpointSet{endpointSet("", primary), endpointSet("dr", dr)},
+		Cooldown:  time.Millisecond,
+	})
+	assert.NoError(t, err)
+
+	instance := dicomwebtest.NewInstance(dicomwebtest.Instance{StudyInstanceUID: "1.2.9", SeriesInstanceUID: "1.2.9.1", SOPInstanceUID: "1.2.9.1.1"})
+	_, err = c.Store(dicomweb.STOWRequest{StudyInstanceUID: "1.2.9", Parts: [][]byte{instance}})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, dr.Len())
+
+	// once the cooldown elapsed, the primary is health checked before it is used.
+	time.Sleep(2 * time.Millisecond)
+	_, err = c.Query(dicomweb.QIDORequest{Type: dicomweb.Study})
+	assert.NoError(t, err)
+	stats := c.EndpointStats()
+	assert.Equal(t, primary.URL, stats[0].Name)
+	assert.Equal(t, int64(1), stats[0].HealthChecks)
+	assert.Equal(t, int64(1), stats[0].Requests)
+	assert.Equal(t, int64(2), stats[1].Requests)
+
+	_, err = dicomweb.NewClient(dicomweb.ClientOption{}).WithFailover(dicomweb.FailoverOption{})
+	assert.EqualError(t, err, "no endpoint set to fail over to")
+	assert.Nil(t, dicomweb.NewClient(dicomweb.ClientOption{}).EndpointStats())
+}
+
+func TestFailoverAllDown(t *testing.T) {
+	primary, dr := newParallelTestServer(), newParallelTestServer()
+	defer primary.Close()
+	defer dr.Close()
+	c, err := dicomweb.NewClient(dicomweb.ClientOption{}).WithFailover(dicomweb.FailoverOption{
+		Endpoints: []dicomweb.EndpointSet{endpointSet("primary", primary), endpointSet("dr", dr)},
+		Cooldown:  time.Hour,
+	})
+	assert.NoError(t, err)
+
+	primary.Inject(dicomweb.QIDOService, dicomwebtest.Fault{Status: http.StatusInternalServerError}, dicomwebtest.Fault{Status: http.StatusInternalServerError})
+	dr.Inject(dicomweb.QIDOService, dicomwebtest.Fault{Status: http.StatusBadGateway})
+	_, err = c.Query(dicomweb.QIDORequest{Type: dicomweb.Study})
+	assert.EqualError(t, err, "502 Bad Gateway")
+
+	// none is healthy, both are tried in order.
+	_, err = c.Query(dicomweb.QIDORequest{Type: dicomweb.Study})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, primary.Requests(dicomweb.QIDOService))
+	assert.Equal(t, 2, dr.Requests(dicomweb.QIDOService))
+}
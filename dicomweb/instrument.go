@@ -0,0 +1,205 @@
+package dicomweb
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Tracer starts a span for each DICOMweb operation. Its shape follows
+// OpenTelemetry, so that an OpenTelemetry tracer can be plugged in with a thin
+// adapter, and a recorder can be used in tests.
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is a single traced operation.
+type Span interface {
+	SetAttributes(attrs ...Attribute)
+	RecordError(err error)
+	End()
+}
+
+// Metrics records the measurements of DICOMweb operations into histograms.
+type Metrics interface {
+	Record(ctx context.Context, name string, value float64, attrs ...Attribute)
+}
+
+// Attribute is a key-value pair describing a span or a measurement.
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// Attribute keys set by the instrumentation.
+const (
+	AttributeService           = "dicomweb.service"
+	AttributeQIDOType          = "dicomweb.qido_type"
+	AttributeWADOType          = "dicomweb.wado_type"
+	AttributeUPSType           = "dicomweb.ups_type"
+	AttributeDeleteType        = "dicomweb.delete_type"
+	AttributeStudyInstanceUID  = "dicomweb.study_instance_uid"
+	AttributeSeriesInstanceUID = "dicomweb.series_instance_uid"
+	AttributeSOPInstanceUID    = "dicomweb.sop_instance_uid"
+	AttributeParts             = "dicomweb.parts"
+	AttributeRequestBytes      = "dicomweb.request_bytes"
+	AttributeResponseBytes     = "dicomweb.response_bytes"
+	AttributeHTTPMethod        = "http.method"
+	AttributeHTTPStatusCode    = "http.status_code"
+)
+
+// Histograms recorded by the instrumentation.
+const (
+	// MetricDuration duration of the operation in seconds, until its response body is closed.
+	MetricDuration = "dicomweb.client.duration"
+	// MetricRequestSize size of the request body in bytes.
+	MetricRequestSize = "dicomweb.client.request.size"
+	// MetricResponseSize size of the response body in bytes.
+	MetricResponseSize = "dicomweb.client.response.size"
+)
+
+// Instrument returns a middleware emitting a span and recording metrics for
+// each operation. Either tracer or metrics can be nil. The span ends once the
+// response body is closed, so that it covers the transfer of the response.
+func Instrument(tracer Tracer, metrics Metrics) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			op, ok := OperationFromContext(r.Context())
+			if !ok {
+				op = &Operation{}
+			}
+			name := op.Service.String()
+			switch op.Service {
+			case QIDOService:
+				name += " " + op.QIDOType.String()
+			case WADOService:
+				if op.DeleteType != 0 {
+					name += " " + op.DeleteType.String()
+				} else {
+					name += " " + op.WADOType.String()
+				}
+			case UPSService:
+				name += " " + op.UPSType.String()
+			}
+
+			ctx := r.Context()
+			var span Span
+			if tracer != nil {
+				ctx, span = tracer.Start(ctx, name)
+				r = r.WithContext(ctx)
+			}
+
+			i := &instrumentation{
+				ctx:     ctx,
+				op:      op,
+				span:    span,
+				metrics: metrics,
+				start:   time.Now(),
+				attrs:   operationAttributes(r, op),
+			}
+			if r.ContentLength > 0 {
+				i.written = r.ContentLength
+				i.attrs = append(i.attrs, Attribute{AttributeRequestBytes, r.ContentLength})
+			}
+
+			resp, err := next.RoundTrip(r)
+			if err != nil {
+				i.finish(err)
+				return nil, err
+			}
+			i.attrs = append(i.attrs, Attribute{AttributeHTTPStatusCode, resp.StatusCode})
+			if resp.StatusCode/100 != 2 {
+				i.err = errors.New(resp.Status)
+			}
+			resp.Body = &instrumentedBody{ReadCloser: resp.Body, i: i}
+			return resp, nil
+		})
+	}
+}
+
+func operationAttributes(r *http.Request, op *Operation) []Attribute {
+	attrs := []Attribute{
+		{AttributeService, op.Service.String()},
+		{AttributeHTTPMethod, r.Method},
+	}
+	switch op.Service {
+	case QIDOService:
+		attrs = append(attrs, Attribute{AttributeQIDOType, op.QIDOType.String()})
+	case WADOService:
+		if op.DeleteType != 0 {
+			attrs = append(attrs, Attribute{AttributeDeleteType, op.DeleteType.String()})
+		} else {
+			attrs = append(attrs, Attribute{AttributeWADOType, op.WADOType.String()})
+		}
+	case UPSService:
+		attrs = append(attrs, Attribute{AttributeUPSType, op.UPSType.String()})
+	}
+	if op.StudyInstanceUID != "" {
+		attrs = append(attrs, Attribute{AttributeStudyInstanceUID, op.StudyInstanceUID})
+	}
+	if op.SeriesInstanceUID != "" {
+		attrs = append(attrs, Attribute{AttributeSeriesInstanceUID, op.SeriesInstanceUID})
+	}
+	if op.SOPInstanceUID != "" {
+		attrs = append(attrs, Attribute{AttributeSOPInstanceUID, op.SOPInstanceUID})
+	}
+	return attrs
+}
+
+// instrumentation holds the state of an instrumented operation.
+type instrumentation struct {
+	ctx     context.Context
+	op      *Operation
+	span    Span
+	metrics Metrics
+	start   time.Time
+	attrs   []Attribute
+	written int64
+	read    int64
+	err     error
+	once    sync.Once
+}
+
+func (i *instrumentation) finish(err error) {
+	i.once.Do(func() {
+		attrs := i.attrs
+		if i.op.Parts > 0 {
+			attrs = append(attrs, Attribute{AttributeParts, i.op.Parts})
+		}
+		if i.span != nil {
+			i.span.SetAttributes(append(attrs, Attribute{AttributeResponseBytes, i.read})...)
+			if err != nil {
+				i.span.RecordError(err)
+			}
+			i.span.End()
+		}
+		if i.metrics != nil {
+			i.metrics.Record(i.ctx, MetricDuration, time.Since(i.start).Seconds(), attrs...)
+			if i.written > 0 {
+				i.metrics.Record(i.ctx, MetricRequestSize, float64(i.written), attrs...)
+			}
+			i.metrics.Record(i.ctx, MetricResponseSize, float64(i.read), attrs...)
+		}
+	})
+}
+
+// instrumentedBody counts the bytes read and finishes the instrumentation once closed.
+type instrumentedBody struct {
+	io.ReadCloser
+	i *instrumentation
+}
+
+func (b *instrumentedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.i.read += int64(n)
+	return n, err
+}
+
+func (b *instrumentedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.i.finish(b.i.err)
+	return err
+}
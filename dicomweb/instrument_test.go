@@ -0,0 +1,144 @@
+package dicomweb
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordedSpan struct {
+	name  string
+	attrs map[string]interface{}
+	err   error
+	ended bool
+}
+
+func (s *recordedSpan) SetAttributes(attrs ...Attribute) {
+	for _, a := range attrs {
+		s.attrs[a.Key] = a.Value
+	}
+}
+
+func (s *recordedSpan) RecordError(err error) { s.err = err }
+
+func (s *recordedSpan) End() { s.ended = true }
+
+type recorder struct {
+	mu      sync.Mutex
+	spans   []*recordedSpan
+	metrics map[string][]float64
+}
+
+func (r *recorder) Start(ctx context.Context, name string) (context.Context, Span) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := &recordedSpan{name: name, attrs: map[string]interface{}{}}
+	r.spans = append(r.spans, s)
+	return ctx, s
+}
+
+func (r *recorder) Record(ctx context.Context, name string, value float64, attrs ...Attribute) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.metrics == nil {
+		r.metrics = map[string][]float64{}
+	}
+	r.metrics[name] = append(r.metrics[name], value)
+}
+
+func TestInstrumentRetrieve(t *testing.T) {
+	body := `--TOAST
+Content-Type: application/dicom
+
+part: 0
+--TOAST
+Content-Type: application/dicom
+
+part: 1
+--TOAST--`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "multipart/related; type=\"application/dicom\"; boundary=TOAST")
+		fmt.Fprint(w, body)
+	}))
+	defer ts.Close()
+
+	rec := &recorder{}
+	c := NewClient(ClientOption{
+		WADOEndpoint: ts.URL,
+		Middlewares:  []Middleware{Instrument(rec, rec)},
+	})
+
+	_, err := c.Retrieve(WADORequest{
+		Type:              SeriesRaw,
+		StudyInstanceUID:  "1.2.3",
+		SeriesInstanceUID: "1.2.3.4",
+	})
+	assert.NoError(t, err)
+
+	if assert.Len(t, rec.spans, 1) {
+		s := rec.spans[0]
+		assert.Equal(t, "WADO SeriesRaw", s.name)
+		assert.True(t, s.ended)
+		assert.NoError(t, s.err)
+		assert.Equal(t, "WADO", s.attrs[AttributeService])
+		assert.Equal(t, "SeriesRaw", s.attrs[AttributeWADOType])
+		assert.Equal(t, "1.2.3", s.attrs[AttributeStudyInstanceUID])
+		assert.Equal(t, "1.2.3.4", s.attrs[AttributeSeriesInstanceUID])
+		assert.Equal(t, http.StatusOK, s.attrs[AttributeHTTPStatusCode])
+		assert.Equal(t, 2, s.attrs[AttributeParts])
+		assert.Equal(t, int64(len(body)), s.attrs[AttributeResponseBytes])
+	}
+	assert.Len(t, rec.metrics[MetricDuration], 1)
+	assert.Equal(t, []float64{float64(len(body))}, rec.metrics[MetricResponseSize])
+}
+
+func TestInstrumentStore(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("{}"))
+	}))
+	defer ts.Close()
+
+	rec := &recorder{}
+	c := NewClient(ClientOption{
+		STOWEndpoint: ts.URL,
+	}).WithMiddleware(Instrument(rec, rec))
+
+	_, err := c.Store(STOWRequest{Parts: [][]byte{[]byte("part: 0"), []byte("part: 1")}})
+	assert.NoError(t, err)
+
+	if assert.Len(t, rec.spans, 1) {
+		s := rec.spans[0]
+		assert.Equal(t, "STOW", s.name)
+		assert.Equal(t, 2, s.attrs[AttributeParts])
+		assert.True(t, s.attrs[AttributeRequestBytes].(int64) > 0)
+	}
+	assert.Len(t, rec.metrics[MetricRequestSize], 1)
+}
+
+func TestInstrumentQueryError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	rec := &recorder{}
+	c := NewClient(ClientOption{
+		QIDOEndpoint: ts.URL,
+	}).WithMiddleware(Instrument(rec, nil))
+
+	_, err := c.Query(QIDORequest{Type: Series, StudyInstanceUID: "1.2.3"})
+	assert.Error(t, err)
+
+	if assert.Len(t, rec.spans, 1) {
+		s := rec.spans[0]
+		assert.Equal(t, "QIDO Series", s.name)
+		assert.True(t, s.ended)
+		assert.EqualError(t, s.err, "503 Service Unavailable")
+		assert.Equal(t, http.StatusServiceUnavailable, s.attrs[AttributeHTTPStatusCode])
+	}
+}
@@ -0,0 +1,165 @@
+package dicomweb
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Service defines the DICOMweb service a request is sent to.
+type Service int
+
+const (
+	// QIDOService query service.
+	QIDOService Service = iota + 1
+	// WADOService retrieve service.
+	WADOService
+	// STOWService store service.
+	STOWService
+	// UPSService worklist service.
+	UPSService
+)
+
+// String returns the name of the service.
+func (s Service) String() string {
+	switch s {
+	case QIDOService:
+		return "QIDO"
+	case WADOService:
+		return "WADO"
+	case STOWService:
+		return "STOW"
+	case UPSService:
+		return "UPS"
+	}
+	return "unknown"
+}
+
+// Limit specifies the request budget of an endpoint. Requests over the budget
+// wait until they fit in, or until their context is done.
+type Limit struct {
+	// Rate average number of requests per second. No rate limit if zero.
+	Rate float64
+	// Burst number of requests that can be sent at once above Rate. Uses 1 otherwise.
+	Burst int
+	// MaxInFlight maximum number of concurrent requests. No cap if zero.
+	MaxInFlight int
+}
+
+// WithLimit configures the request budget of the endpoint of the given service.
+// The budget is shared by all goroutines using the client.
+func (c *Client) WithLimit(service Service, limit Limit) *Client {
+	if c.limiters == nil {
+		c.limiters = map[Service]*limiter{}
+	}
+	c.limiters[service] = newLimiter(limit)
+	return c
+}
+
+// limiter is a token bucket combined with a semaphore capping the requests in flight.
+type limiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	sem    chan struct{}
+}
+
+func newLimiter(limit Limit) *limiter {
+	burst := float64(limit.Burst)
+	if burst < 1 {
+		burst = 1
+	}
+	l := &limiter{
+		rate:   limit.Rate,
+		burst:  burst,
+		tokens: burst,
+		last:   time.Now(),
+	}
+	if limit.MaxInFlight > 0 {
+		l.sem = make(chan struct{}, limit.MaxInFlight)
+	}
+	return l
+}
+
+// acquire blocks until the request fits in the budget. The caller must call
+// release once the request is done if acquire succeeded.
+func (l *limiter) acquire(ctx context.Context) error {
+	if l.rate > 0 {
+		l.mu.Lock()
+		now := time.Now()
+		l.tokens += now.Sub(l.last).Seconds() * l.rate
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+		l.last = now
+		l.tokens--
+		var wait time.Duration
+		if l.tokens < 0 {
+			wait = time.Duration(-l.tokens / l.rate * float64(time.Second))
+		}
+		l.mu.Unlock()
+
+		if wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				l.mu.Lock()
+				l.tokens++
+				l.mu.Unlock()
+				return ctx.Err()
+			case <-timer.C:
+			}
+		}
+	}
+
+	if l.sem != nil {
+		select {
+		case l.sem <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+func (l *limiter) release() {
+	if l.sem != nil {
+		<-l.sem
+	}
+}
+
+// limitedBody releases the in-flight slot once the response body is closed.
+type limitedBody struct {
+	io.ReadCloser
+	once    sync.Once
+	limiter *limiter
+}
+
+func (b *limitedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.limiter.release)
+	return err
+}
+
+// roundTrip performs a single attempt of the request within the budget of the service.
+func (c *Client) roundTrip(service Service, r *http.Request) (*http.Response, error) {
+	l := c.limiters[service]
+	if l == nil {
+		return c.httpClient.Do(r)
+	}
+	if err := l.acquire(r.Context()); err != nil {
+		return nil, err
+	}
+	resp, err := c.httpClient.Do(r)
+	if err != nil {
+		l.release()
+		return nil, err
+	}
+	resp.Body = &limitedBody{ReadCloser: resp.Body, limiter: l}
+	return resp, nil
+}
@@ -0,0 +1,87 @@
+package dicomweb
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimitMaxInFlight(t *testing.T) {
+	var inFlight, peak int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		atomic.AddInt32(&inFlight, -1)
+		w.Write([]byte("[]"))
+	}))
+	defer ts.Close()
+
+	c := NewClient(ClientOption{
+		QIDOEndpoint: ts.URL,
+		QIDOLimit:    &Limit{MaxInFlight: 2},
+	})
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := c.Query(QIDORequest{Type: Study})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(2), atomic.LoadInt32(&peak))
+}
+
+func TestLimitRate(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("[]"))
+	}))
+	defer ts.Close()
+
+	c := NewClient(ClientOption{
+		QIDOEndpoint: ts.URL,
+	}).WithLimit(QIDOService, Limit{Rate: 50, Burst: 2})
+
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		_, err := c.Query(QIDORequest{Type: Study})
+		assert.NoError(t, err)
+	}
+	// 2 requests fit in the burst, the remaining 4 wait 20ms each.
+	assert.True(t, time.Since(start) >= 70*time.Millisecond)
+}
+
+func TestLimitPerService(t *testing.T) {
+	c := NewClient(ClientOption{
+		WADOLimit: &Limit{MaxInFlight: 1},
+	})
+	assert.Nil(t, c.limiters[QIDOService])
+	assert.Nil(t, c.limiters[STOWService])
+	assert.NotNil(t, c.limiters[WADOService])
+}
+
+func TestLimitAcquireCanceled(t *testing.T) {
+	l := newLimiter(Limit{MaxInFlight: 1})
+	assert.NoError(t, l.acquire(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, l.acquire(ctx))
+
+	l.release()
+	assert.NoError(t, l.acquire(context.Background()))
+}
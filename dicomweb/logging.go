@@ -0,0 +1,159 @@
+package dicomweb
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Logger is the structured logger the client writes diagnostics to. Arguments
+// are alternating keys and values, so that a *slog.Logger can be used directly.
+type Logger interface {
+	Debug(msg string, args ...interface{})
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+// LogOption specifies what the logging middleware records.
+type LogOption struct {
+	// MaxBodySize maximum number of bytes of an error response body which are logged. Uses 4096 otherwise.
+	MaxBodySize int
+	// RedactParams query parameters, by keyword or tag, whose values are redacted. Uses DefaultRedactParams otherwise.
+	RedactParams []string
+}
+
+// DefaultRedactParams query parameters holding protected health information.
+var DefaultRedactParams = []string{
+	"PatientName", "00100010",
+	"PatientID", "00100020",
+	"PatientBirthDate", "00100030",
+	"PatientBirthTime", "00100032",
+	"OtherPatientIDs", "00101000",
+	"OtherPatientNames", "00101001",
+	"PatientAddress", "00101040",
+	"PatientTelephoneNumbers", "00102154",
+	"ReferringPhysicianName", "00080090",
+}
+
+const redacted = "REDACTED"
+
+// WithLogger configures the client to log each request with the default LogOption.
+func (c *Client) WithLogger(logger Logger) *Client {
+	return c.WithMiddleware(Logging(logger, LogOption{}))
+}
+
+// Logging returns a middleware logging the method, URL, status and duration of
+// each request. Successful requests are logged at debug level, failed ones at
+// warn or error level along with the beginning of the response body.
+// Values of PHI query parameters are redacted in the URL and the body.
+func Logging(logger Logger, option LogOption) Middleware {
+	maxBodySize := option.MaxBodySize
+	if maxBodySize <= 0 {
+		maxBodySize = 4096
+	}
+	params := option.RedactParams
+	if params == nil {
+		params = DefaultRedactParams
+	}
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			u, secrets := redactURL(r.URL, params)
+			start := time.Now()
+			resp, err := next.RoundTrip(r)
+			duration := time.Since(start)
+			if err != nil {
+				logger.Error("dicomweb request failed",
+					"method", r.Method,
+					"url", u,
+					"duration", duration,
+					"error", redactError(err, u, secrets),
+				)
+				return nil, err
+			}
+
+			if resp.StatusCode/100 == 2 || resp.StatusCode == http.StatusNotModified {
+				logger.Debug("dicomweb request",
+					"method", r.Method,
+					"url", u,
+					"status", resp.StatusCode,
+					"duration", duration,
+				)
+				return resp, nil
+			}
+
+			b, _ := ioutil.ReadAll(io.LimitReader(resp.Body, int64(maxBodySize)))
+			resp.Body = struct {
+				io.Reader
+				io.Closer
+			}{io.MultiReader(bytes.NewReader(b), resp.Body), resp.Body}
+
+			log := logger.Warn
+			if resp.StatusCode/100 == 5 {
+				log = logger.Error
+			}
+			log("dicomweb request failed",
+				"method", r.Method,
+				"url", u,
+				"status", resp.StatusCode,
+				"duration", duration,
+				"body", redactString(string(b), secrets),
+			)
+			return resp, nil
+		})
+	}
+}
+
+// redactURL returns the URL with the values of the given query parameters
+// redacted, and the redacted values, along with their percent-encoded forms.
+func redactURL(u *url.URL, params []string) (string, []string) {
+	q := u.Query()
+	secrets := []string{}
+	for k, vs := range q {
+		if !containsFold(params, k) {
+			continue
+		}
+		for i, v := range vs {
+			if v != "" {
+				secrets = append(secrets, v, url.QueryEscape(v), url.PathEscape(v))
+				vs[i] = redacted
+			}
+		}
+	}
+	if len(secrets) == 0 {
+		return u.String(), nil
+	}
+	copied := *u
+	copied.RawQuery = q.Encode()
+	return copied.String(), secrets
+}
+
+// redactError returns the message of the error, with the URL of a *url.Error
+// replaced by the redacted one.
+func redactError(err error, redactedURL string, secrets []string) string {
+	if ue, ok := err.(*url.Error); ok {
+		return ue.Op + " " + redactedURL + ": " + redactString(ue.Err.Error(), secrets)
+	}
+	return redactString(err.Error(), secrets)
+}
+
+func redactString(s string, secrets []string) string {
+	for _, secret := range secrets {
+		s = strings.Replace(s, secret, redacted, -1)
+	}
+	return s
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,132 @@
+package dicomweb
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type logEntry struct {
+	level string
+	msg   string
+	attrs map[string]interface{}
+}
+
+type testLogger struct {
+	entries []logEntry
+}
+
+func (l *testLogger) log(level, msg string, args ...interface{}) {
+	attrs := map[string]interface{}{}
+	for i := 0; i+1 < len(args); i += 2 {
+		attrs[args[i].(string)] = args[i+1]
+	}
+	l.entries = append(l.entries, logEntry{level, msg, attrs})
+}
+
+func (l *testLogger) Debug(msg string, args ...interface{}) { l.log("debug", msg, args...) }
+func (l *testLogger) Info(msg string, args ...interface{})  { l.log("info", msg, args...) }
+func (l *testLogger) Warn(msg string, args ...interface{})  { l.log("warn", msg, args...) }
+func (l *testLogger) Error(msg string, args ...interface{}) { l.log("error", msg, args...) }
+
+func TestLoggingRequest(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("[]"))
+	}))
+	defer ts.Close()
+
+	logger := &testLogger{}
+	c := NewClient(ClientOption{
+		QIDOEndpoint: ts.URL,
+	}).WithLogger(logger)
+
+	_, err := c.Query(QIDORequest{Type: Study, PatientID: "P-12345", AccessionNumber: "an"})
+	assert.NoError(t, err)
+
+	if assert.Len(t, logger.entries, 1) {
+		e := logger.entries[0]
+		assert.Equal(t, "debug", e.level)
+		assert.Equal(t, "GET", e.attrs["method"])
+		assert.Equal(t, http.StatusOK, e.attrs["status"])
+		assert.Equal(t, ts.URL+"/studies?00080050=an&00100020=REDACTED", e.attrs["url"])
+		assert.Contains(t, e.attrs, "duration")
+	}
+}
+
+func TestLoggingErrorBody(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, `{"errorMessage": "unknown patient P-12345"}%s`, strings.Repeat(" ", 100))
+	}))
+	defer ts.Close()
+
+	logger := &testLogger{}
+	c := NewClient(ClientOption{
+		QIDOEndpoint: ts.URL,
+		Middlewares:  []Middleware{Logging(logger, LogOption{MaxBodySize: 64})},
+	})
+
+	_, err := c.Query(QIDORequest{Type: Study, PatientID: "P-12345"})
+	assert.EqualError(t, err, "400 Bad Request")
+
+	if assert.Len(t, logger.entries, 1) {
+		e := logger.entries[0]
+		assert.Equal(t, "warn", e.level)
+		assert.Equal(t, http.StatusBadRequest, e.attrs["status"])
+		body := e.attrs["body"].(string)
+		assert.True(t, strings.HasPrefix(body, `{"errorMessage": "unknown patient REDACTED"}`))
+		assert.Len(t, body, 64-len("P-12345")+len(redacted))
+	}
+}
+
+func TestLoggingServerErrorKeepsBody(t *testing.T) {
+	logger := &testLogger{}
+	rt := Logging(logger, LogOption{MaxBodySize: 8})(RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusInternalServerError,
+			Body:       ioutil.NopCloser(strings.NewReader("internal failure")),
+		}, nil
+	}))
+
+	r, _ := http.NewRequest("GET", "http://localhost/studies", nil)
+	resp, err := rt.RoundTrip(r)
+	assert.NoError(t, err)
+	b, _ := ioutil.ReadAll(resp.Body)
+	assert.Equal(t, "internal failure", string(b))
+
+	if assert.Len(t, logger.entries, 1) {
+		assert.Equal(t, "error", logger.entries[0].level)
+		assert.Equal(t, "internal", logger.entries[0].attrs["body"])
+	}
+}
+
+func TestLoggingTransportError(t *testing.T) {
+	logger := &testLogger{}
+	c := NewClient(ClientOption{
+		QIDOEndpoint: "http://127.0.0.1:0",
+	}).WithLogger(logger)
+
+	_, err := c.Query(QIDORequest{Type: Study, PatientID: "P-12345"})
+	assert.Error(t, err)
+
+	if assert.Len(t, logger.entries, 1) {
+		e := logger.entries[0]
+		assert.Equal(t, "error", e.level)
+		assert.NotContains(t, e.attrs["error"], "P-12345")
+	}
+
+	// the error holds the percent-encoded URL.
+	logger.entries = nil
+	_, err = c.Query(QIDORequest{Type: Study, Filters: map[string]string{"PatientName": "Doe^John"}})
+	assert.Error(t, err)
+	if assert.Len(t, logger.entries, 1) {
+		msg := logger.entries[0].attrs["error"].(string)
+		assert.NotContains(t, msg, "Doe")
+		assert.Contains(t, msg, "PatientName="+redacted)
+	}
+}
@@ -0,0 +1,168 @@
+package dicomweb
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/toastcheng/dicomweb-go/dicomweb/dicom"
+)
+
+// params returns the non-empty filters of the request keyed by tag, along with
+// the limit and offset.
+func (r QIDORequest) params() map[string]string {
+	mp := map[string]interface{}{}
+	databytes, _ := json.Marshal(r)
+	json.Unmarshal(databytes, &mp)
+
+	params := map[string]string{}
+	for k, v := range mp {
+		if k == "Type" {
+			continue
+		}
+		switch t := v.(type) {
+		case float64:
+			params[k] = fmt.Sprintf("%.0f", t)
+		case string:
+			params[k] = t
+		}
+	}
+	for k, v := range r.Filters {
+		params[k] = v
+	}
+	return params
+}
+
+// Match reports whether the attributes match the filters of the request,
+// following the QIDO-RS matching rules: UIDs are matched against a list of
+// UIDs, dates and times against a range, and other values with the * and ?
+// wildcards. Person names are matched case-insensitively. A filter of * alone
+// matches any value, even an absent attribute. Filters may be keyed by tag or
+// by keyword, e.g. PatientName, and a filter on an unknown attribute matches
+// nothing. The query type, limit and offset are not considered.
+func (r QIDORequest) Match(attrs QIDORawResponse) bool {
+	for k, query := range r.params() {
+		switch k {
+		case "limit", "offset", "includefield", "fuzzymatching":
+			continue
+		}
+		if query == "*" {
+			continue
+		}
+		key, ok := filterTag(k)
+		if !ok {
+			return false
+		}
+		tag, ok := attrs[key]
+		if !ok || !matchTag(tag, query) {
+			return false
+		}
+	}
+	return true
+}
+
+// filterTag returns the tag a filter is keyed by, as attributes are, e.g.
+// 00100010 for both PatientName and 00100010.
+func filterTag(k string) (string, bool) {
+	if len(k) == 8 && strings.Trim(strings.ToUpper(k), "0123456789ABCDEF") == "" {
+		return strings.ToUpper(k), true
+	}
+	if t, ok := dicom.LookupKeyword(k); ok {
+		return t.Hex(), true
+	}
+	return "", false
+}
+
+func matchTag(tag Tag, query string) bool {
+	for _, v := range tag.Value {
+		value := tagString(v)
+		switch tag.VR {
+		case "UI":
+			for _, uid := range strings.FieldsFunc(query, func(r rune) bool { return r == ',' || r == '\\' }) {
+				if uid == value {
+					return true
+				}
+			}
+		case "DA", "TM", "DT":
+			if matchRange(value, query) {
+				return true
+			}
+		case "PN":
+			if matchWildcard(strings.ToLower(value), strings.ToLower(query)) {
+				return true
+			}
+		default:
+			if matchWildcard(value, query) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// tagString returns the value of a tag as a string, the alphabetic
+// representation for person names.
+func tagString(v interface{}) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case float64:
+		return fmt.Sprintf("%v", t)
+	case map[string]interface{}:
+		if s, ok := t["Alphabetic"].(string); ok {
+			return s
+		}
+	case map[string]string:
+		return t["Alphabetic"]
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+func matchRange(value, query string) bool {
+	i := strings.Index(query, "-")
+	if i < 0 {
+		return value == query
+	}
+	from, to := query[:i], query[i+1:]
+	if from != "" && value < from {
+		return false
+	}
+	if to != "" && value[:min(len(value), len(to))] > to {
+		return false
+	}
+	return true
+}
+
+// matchWildcard matches the value against the query, where * matches any
+// sequence of characters and ? matches a single character.
+func matchWildcard(value, query string) bool {
+	v, q := []rune(value), []rune(query)
+	vi, qi := 0, 0
+	star, mark := -1, 0
+	for vi < len(v) {
+		switch {
+		case qi < len(q) && (q[qi] == '?' || q[qi] == v[vi]):
+			vi++
+			qi++
+		case qi < len(q) && q[qi] == '*':
+			star, mark = qi, vi
+			qi++
+		case star >= 0:
+			mark++
+			vi, qi = mark, star+1
+		default:
+			return false
+		}
+	}
+	for qi < len(q) && q[qi] == '*' {
+		qi++
+	}
+	return qi == len(q)
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,60 @@
+package dicomweb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQIDORequestMatch(t *testing.T) {
+	attrs := QIDORawResponse{
+		"0020000D": {VR: "UI", Value: []interface{}{"1.2.3"}},
+		"00100020": {VR: "LO", Value: []interface{}{"PAT-001"}},
+		"00100010": {VR: "PN", Value: []interface{}{map[string]interface{}{"Alphabetic": "Doe^John"}}},
+		"00080020": {VR: "DA", Value: []interface{}{"20200615"}},
+		"00080030": {VR: "TM", Value: []interface{}{"103000"}},
+		"00080050": {VR: "SH", Value: []interface{}{"ACC/1"}},
+	}
+
+	cases := []struct {
+		req   QIDORequest
+		match bool
+	}{
+		{QIDORequest{Type: Study}, true},
+		{QIDORequest{Type: Study, Limit: 1, Offset: 2}, true},
+		{QIDORequest{StudyInstanceUID: "1.2.3"}, true},
+		{QIDORequest{StudyInstanceUID: "4.5.6,1.2.3"}, true},
+		{QIDORequest{StudyInstanceUID: "1.2"}, false},
+		{QIDORequest{PatientID: "PAT-001"}, true},
+		{QIDORequest{PatientID: "PAT-*"}, true},
+		{QIDORequest{PatientID: "PAT-00?"}, true},
+		{QIDORequest{PatientID: "pat-*"}, false},
+		{QIDORequest{PatientID: "PAT-0"}, false},
+		{QIDORequest{StudyDate: "20200615"}, true},
+		{QIDORequest{StudyDate: "20200101-20201231"}, true},
+		{QIDORequest{StudyDate: "20200101-"}, true},
+		{QIDORequest{StudyDate: "-20200101"}, false},
+		{QIDORequest{StudyTime: "0900-1200"}, true},
+		{QIDORequest{StudyTime: "1100-1200"}, false},
+		{QIDORequest{AccessionNumber: "ACC/*"}, true},
+		{QIDORequest{SeriesDate: "20200615"}, false},
+		{QIDORequest{SeriesDate: "*"}, true},
+		{QIDORequest{Filters: map[string]string{"PatientName": "DOE^*"}}, true},
+		{QIDORequest{Filters: map[string]string{"00100010": "Smith*"}}, false},
+		{QIDORequest{Filters: map[string]string{"0020000d": "1.2.3"}}, true},
+		{QIDORequest{Filters: map[string]string{"Modality": "CT"}}, false},
+		{QIDORequest{Filters: map[string]string{"Modality": "*"}}, true},
+		{QIDORequest{Filters: map[string]string{"NoSuchKeyword": "x"}}, false},
+		{QIDORequest{Filters: map[string]string{"includefield": "all"}}, true},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.match, c.req.Match(attrs), "%+v", c.req)
+	}
+}
+
+func TestMatchPersonName(t *testing.T) {
+	tag := Tag{VR: "PN", Value: []interface{}{map[string]string{"Alphabetic": "Doe^John"}}}
+	assert.True(t, matchTag(tag, "doe^*"))
+	assert.True(t, matchTag(tag, "DOE^JOHN"))
+	assert.False(t, matchTag(tag, "Smith*"))
+}
@@ -0,0 +1,81 @@
+package dicomweb
+
+import (
+	"context"
+	"net/http"
+)
+
+// RoundTripperFunc is an adapter to allow the use of ordinary functions as http.RoundTripper.
+type RoundTripperFunc func(*http.Request) (*http.Response, error)
+
+// RoundTrip calls f(r).
+func (f RoundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+// Middleware wraps the round trip of every request sent by Query, Retrieve,
+// Store, Delete, Worklist and Capabilities. A middleware sees the outgoing
+// request after the authorization and the OptionFuncs are applied, and the
+// response before it is parsed by the client; it can also short-circuit the
+// request by returning a response on its own, e.g. from a cache. The retry
+// policy, the rate limits and the failover apply inside the chain.
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+// WithMiddleware appends middlewares to the chain of the client. The first
+// middleware is the outermost one.
+func (c *Client) WithMiddleware(middlewares ...Middleware) *Client {
+	c.middlewares = append(c.middlewares, middlewares...)
+	return c
+}
+
+// Operation describes the DICOMweb operation a request is sent for.
+type Operation struct {
+	// Service the service of the operation.
+	Service Service
+	// QIDOType the query type, for QIDO operations.
+	QIDOType QIDOType
+	// WADOType the retrieve type, for WADO operations.
+	WADOType WADOType
+	// UPSType the worklist transaction, for UPS operations.
+	UPSType UPSType
+	// DeleteType the deleted resource, for WADO operations deleting it.
+	DeleteType DeleteType
+	// StudyInstanceUID study of the operation, if any.
+	StudyInstanceUID string
+	// SeriesInstanceUID series of the operation, if any.
+	SeriesInstanceUID string
+	// SOPInstanceUID instance of the operation, or workitem of a UPS operation, if any.
+	SOPInstanceUID string
+	// FrameID frame of the operation, if any.
+	FrameID int
+	// Parts number of parts sent by Store, or received by Retrieve once the
+	// response is read and before its body is closed.
+	Parts int
+}
+
+type operationKey struct{}
+
+// OperationFromContext returns the operation the request with the given
+// context is sent for. It is meant to be called by middlewares.
+func OperationFromContext(ctx context.Context) (*Operation, bool) {
+	op, ok := ctx.Value(operationKey{}).(*Operation)
+	return op, ok
+}
+
+// chain builds the round tripper that sends the requests of the given service
+// through the middlewares.
+func (c *Client) chain(service Service) http.RoundTripper {
+	var rt http.RoundTripper = RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		return c.send(service, r)
+	})
+	if c.failover != nil {
+		rt = c.failover.middleware(rt)
+	}
+	for i := len(c.middlewares) - 1; i >= 0; i-- {
+		if c.middlewares[i] == nil {
+			continue
+		}
+		rt = c.middlewares[i](rt)
+	}
+	return rt
+}
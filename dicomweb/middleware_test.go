@@ -0,0 +1,128 @@
+package dicomweb
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddlewareOrder(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "inner", r.Header.Get("X-Middleware"))
+		w.Write([]byte("[]"))
+	}))
+	defer ts.Close()
+
+	calls := []string{}
+	mw := func(name string) Middleware {
+		return func(next http.RoundTripper) http.RoundTripper {
+			return RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+				calls = append(calls, name+" request")
+				r.Header.Set("X-Middleware", name)
+				resp, err := next.RoundTrip(r)
+				calls = append(calls, name+" response")
+				return resp, err
+			})
+		}
+	}
+
+	c := NewClient(ClientOption{
+		QIDOEndpoint: ts.URL,
+		Middlewares:  []Middleware{mw("outer")},
+	}).WithMiddleware(nil, mw("inner"))
+
+	_, err := c.Query(QIDORequest{Type: Study})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"outer request", "inner request", "inner response", "outer response"}, calls)
+}
+
+func TestMiddlewareSeesOptionFuncs(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("[]"))
+	}))
+	defer ts.Close()
+
+	c := NewClient(ClientOption{
+		QIDOEndpoint: ts.URL,
+		OptionFuncs: &[]OptionFunc{
+			func(r *http.Request) error {
+				r.Header.Set("X-Custom", "custom")
+				return nil
+			},
+		},
+	}).WithAuthentication("user:password").WithMiddleware(func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			assert.Equal(t, "custom", r.Header.Get("X-Custom"))
+			assert.Equal(t, "Basic dXNlcjpwYXNzd29yZA==", r.Header.Get("Authorization"))
+			return next.RoundTrip(r)
+		})
+	})
+
+	_, err := c.Query(QIDORequest{Type: Study})
+	assert.NoError(t, err)
+}
+
+func TestMiddlewareShortCircuit(t *testing.T) {
+	c := NewClient(ClientOption{
+		WADOEndpoint: "http://127.0.0.1:0",
+	}).WithMiddleware(func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			header := http.Header{}
+			header.Set("Content-Type", "multipart/related; type=\"application/dicom\"; boundary=TOAST")
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Status:     "200 OK",
+				Header:     header,
+				Body: ioutil.NopCloser(bytes.NewBufferString(`--TOAST
+Content-Type: application/dicom
+
+cached
+--TOAST--`)),
+				Request: r,
+			}, nil
+		})
+	})
+
+	parts, err := c.Retrieve(WADORequest{Type: StudyRaw, StudyInstanceUID: "1.2.3"})
+	assert.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("cached")}, parts)
+}
+
+func TestMiddlewareOperation(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "multipart/related; type=\"application/dicom\"; boundary=TOAST")
+		w.Write([]byte("--TOAST--"))
+	}))
+	defer ts.Close()
+
+	var op *Operation
+	c := NewClient(ClientOption{
+		WADOEndpoint: ts.URL,
+	}).WithMiddleware(func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			op, _ = OperationFromContext(r.Context())
+			return next.RoundTrip(r)
+		})
+	})
+
+	_, err := c.Retrieve(WADORequest{
+		Type:              Frame,
+		StudyInstanceUID:  "1.2.3",
+		SeriesInstanceUID: "1.2.3.4",
+		SOPInstanceUID:    "1.2.3.4.5",
+		FrameID:           2,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, &Operation{
+		Service:           WADOService,
+		WADOType:          Frame,
+		StudyInstanceUID:  "1.2.3",
+		SeriesInstanceUID: "1.2.3.4",
+		SOPInstanceUID:    "1.2.3.4.5",
+		FrameID:           2,
+	}, op)
+}
@@ -0,0 +1,64 @@
+package dicomweb
+
+import "encoding/json"
+
+// ScheduledProcedureStep defines a scheduled procedure step of a modality
+// worklist item, see PS3.4 K.6.1.2.2.
+type ScheduledProcedureStep struct {
+	// ID ID of the scheduled procedure step.
+	ID string
+	// Description description of the scheduled procedure step.
+	Description string
+	// Modality modality the step is scheduled on.
+	Modality string
+	// StationAETitle AE title of the station the step is scheduled on.
+	StationAETitle string
+	// StationName name of the station the step is scheduled on.
+	StationName string
+	// StartDate date the step is scheduled to start on.
+	StartDate string
+	// StartTime time the step is scheduled to start at.
+	StartTime string
+	// PerformingPhysicianName name of the physician scheduled to perform the step.
+	PerformingPhysicianName string
+	// Status status of the step, e.g. SCHEDULED or ARRIVED.
+	Status string
+	// Attributes all the attributes of the step, keyed by tag.
+	Attributes map[string]Tag
+}
+
+// ScheduledProcedureSteps returns the scheduled procedure steps of the
+// modality worklist item, the items of its ScheduledProcedureStepSequence.
+func (r QIDOResponse) ScheduledProcedureSteps() []ScheduledProcedureStep {
+	steps := []ScheduledProcedureStep{}
+	for _, item := range r.ScheduledProcedureStepSequence.Value {
+		// the items are decoded as generic JSON objects.
+		b, err := json.Marshal(item)
+		if err != nil {
+			continue
+		}
+		attrs := map[string]Tag{}
+		if err := json.Unmarshal(b, &attrs); err != nil {
+			continue
+		}
+		value := func(tag string) string {
+			if t, ok := attrs[tag]; ok && len(t.Value) > 0 {
+				return tagString(t.Value[0])
+			}
+			return ""
+		}
+		steps = append(steps, ScheduledProcedureStep{
+			ID:                      value("00400009"),
+			Description:             value("00400007"),
+			Modality:                value("00080060"),
+			StationAETitle:          value("00400001"),
+			StationName:             value("00400010"),
+			StartDate:               value("00400002"),
+			StartTime:               value("00400003"),
+			PerformingPhysicianName: value("00400006"),
+			Status:                  value("00400020"),
+			Attributes:              attrs,
+		})
+	}
+	return steps
+}
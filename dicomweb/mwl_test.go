@@ -0,0 +1,59 @@
+package dicomweb
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryPatientsAndModalityWorklist(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/patients":
+			assert.Equal(t, "PAT-1", r.URL.Query().Get("00100020"))
+			w.Write([]byte(`[{"00100010": {"vr": "PN", "Value": [{"Alphabetic": "Doe^John"}]}, "00201200": {"vr": "IS", "Value": [2]}}]`))
+		case "/mwlitems":
+			assert.Equal(t, "CT", r.URL.Query().Get("00400100.00080060"))
+			w.Write([]byte(`[{
+				"00100020": {"vr": "LO", "Value": ["PAT-1"]},
+				"00400100": {"vr": "SQ", "Value": [{
+					"00080060": {"vr": "CS", "Value": ["CT"]},
+					"00400001": {"vr": "AE", "Value": ["CT01"]},
+					"00400002": {"vr": "DA", "Value": ["20200102"]},
+					"00400006": {"vr": "PN", "Value": [{"Alphabetic": "House^Gregory"}]},
+					"00400009": {"vr": "SH", "Value": ["SPS-1"]}
+				}]}
+			}]`))
+		case "/custom/search":
+			w.Write([]byte(`[]`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+	c := NewClient(ClientOption{QIDOEndpoint: ts.URL})
+
+	patients, err := c.Query(QIDORequest{Type: Patient, PatientID: "PAT-1"})
+	assert.NoError(t, err)
+	assert.Len(t, patients, 1)
+	assert.Equal(t, "Doe^John", tagString(patients[0].PatientName.Value[0]))
+
+	items, err := c.Query(QIDORequest{Type: ModalityWorklist, Filters: map[string]string{"00400100.00080060": "CT"}})
+	assert.NoError(t, err)
+	steps := items[0].ScheduledProcedureSteps()
+	assert.Len(t, steps, 1)
+	assert.Equal(t, "SPS-1", steps[0].ID)
+	assert.Equal(t, "CT01", steps[0].StationAETitle)
+	assert.Equal(t, "20200102", steps[0].StartDate)
+	assert.Equal(t, "House^Gregory", steps[0].PerformingPhysicianName)
+	assert.Equal(t, "CS", steps[0].Attributes["00080060"].VR)
+
+	custom := RegisterQIDOType("CustomSearch", "/custom/search")
+	assert.Equal(t, "CustomSearch", custom.String())
+	_, err = c.Query(QIDORequest{Type: custom})
+	assert.NoError(t, err)
+	assert.NoError(t, QIDORequest{Type: custom}.Validate())
+	assert.Equal(t, "unknown", QIDOType(custom+1).String())
+}
@@ -0,0 +1,104 @@
+package dicomweb
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ParallelOption specifies how RetrieveStudyParallel splits the retrieval of a study.
+type ParallelOption struct {
+	// Workers number of concurrent retrievals. Uses 4 otherwise
+	Workers int
+	// PerInstance retrieves the instances one by one. Retrieves the series otherwise
+	PerInstance bool
+	// Progress is called after each retrieval with the number of completed retrievals and the total number, if set
+	Progress func(done, total int)
+}
+
+// RetrieveErrors holds the errors of the retrievals which failed.
+type RetrieveErrors []error
+
+func (e RetrieveErrors) Error() string {
+	msgs := []string{}
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return fmt.Sprintf("%d retrievals failed: %s", len(e), strings.Join(msgs, "; "))
+}
+
+// RetrieveStudyParallel retrieves the study like Retrieve with StudyRaw does, but
+// over concurrent requests: the series, or instances, of the study are
+// enumerated with a QIDO-RS search, page by page, then retrieved by a pool of workers. The
+// instances are returned in the order of the search. If any retrieval fails,
+// the errors of all the failed retrievals are returned as RetrieveErrors.
+func (c *Client) RetrieveStudyParallel(studyInstanceUID string, option ParallelOption) ([][]byte, error) {
+	workers := option.Workers
+	if workers <= 0 {
+		workers = 4
+	}
+
+	resp, err := c.studyInstances(studyInstanceUID)
+	if err != nil {
+		return nil, err
+	}
+	reqs := []WADORequest{}
+	seen := map[string]bool{}
+	for _, r := range resp {
+		series, sop := tagValue(r.SeriesInstanceUID), tagValue(r.SOPInstanceUID)
+		if option.PerInstance {
+			reqs = append(reqs, WADORequest{Type: InstanceRaw, StudyInstanceUID: studyInstanceUID, SeriesInstanceUID: series, SOPInstanceUID: sop})
+		} else if !seen[series] {
+			seen[series] = true
+			reqs = append(reqs, WADORequest{Type: SeriesRaw, StudyInstanceUID: studyInstanceUID, SeriesInstanceUID: series})
+		}
+	}
+	if len(reqs) == 0 {
+		return nil, fmt.Errorf("study %s not found", studyInstanceUID)
+	}
+
+	results := make([][][]byte, len(reqs))
+	errs := make([]error, len(reqs))
+	jobs := make(chan int)
+	var mu sync.Mutex
+	done := 0
+	wg := sync.WaitGroup{}
+	for w := 0; w < workers && w < len(reqs); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i], errs[i] = c.Retrieve(reqs[i])
+				mu.Lock()
+				done++
+				if option.Progress != nil {
+					option.Progress(done, len(reqs))
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for i := range reqs {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	failed := RetrieveErrors{}
+	parts := [][]byte{}
+	for i, req := range reqs {
+		if errs[i] != nil {
+			uid := req.SeriesInstanceUID
+			if req.SOPInstanceUID != "" {
+				uid = req.SOPInstanceUID
+			}
+			failed = append(failed, fmt.Errorf("%s %s: %v", req.Type, uid, errs[i]))
+			continue
+		}
+		parts = append(parts, results[i]...)
+	}
+	if len(failed) > 0 {
+		return nil, failed
+	}
+	return parts, nil
+}
@@ -0,0 +1,96 @@
+package dicomweb_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/toastcheng/dicomweb-go/dicomweb"
+	"github.com/toastcheng/dicomweb-go/dicomweb/dicomwebtest"
+)
+
+func newParallelTestServer() *dicomwebtest.Server {
+	instances := [][]byte{}
+	for _, uids := range [][2]string{
+		{"1.2.1.1", "1.2.1.1.1"},
+		{"1.2.1.1", "1.2.1.1.2"},
+		{"1.2.1.2", "1.2.1.2.1"},
+		{"1.2.1.3", "1.2.1.3.1"},
+		{"1.2.1.3", "1.2.1.3.2"},
+	} {
+		instances = append(instances, dicomwebtest.NewInstance(dicomwebtest.Instance{
+			StudyInstanceUID:  "1.2.1",
+			SeriesInstanceUID: uids[0],
+			SOPInstanceUID:    uids[1],
+		}))
+	}
+	return dicomwebtest.NewServer(instances...)
+}
+
+func TestRetrieveStudyParallel(t *testing.T) {
+	s := newParallelTestServer()
+	defer s.Close()
+	c := s.Client()
+
+	expected, err := c.Retrieve(dicomweb.WADORequest{Type: dicomweb.StudyRaw, StudyInstanceUID: "1.2.1"})
+	assert.NoError(t, err)
+
+	progress := []int{}
+	parts, err := c.RetrieveStudyParallel("1.2.1", dicomweb.ParallelOption{
+		Workers: 2,
+		Progress: func(done, total int) {
+			assert.Equal(t, 3, total)
+			progress = append(progress, done)
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, expected, parts)
+	assert.Equal(t, []int{1, 2, 3}, progress)
+	assert.Equal(t, 4, s.Requests(dicomweb.WADOService))
+
+	parts, err = c.RetrieveStudyParallel("1.2.1", dicomweb.ParallelOption{PerInstance: true})
+	assert.NoError(t, err)
+	assert.Equal(t, expected, parts)
+	assert.Equal(t, 9, s.Requests(dicomweb.WADOService))
+}
+
+func TestRetrieveStudyParallelPages(t *testing.T) {
+	s := newParallelTestServer()
+	defer s.Close()
+	// the server caps the searches at 2 results, below the requested limit.
+	c := s.Client().WithMiddleware(func(next http.RoundTripper) http.RoundTripper {
+		return dicomweb.RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			if q := r.URL.Query(); q.Get("limit") != "" {
+				q.Set("limit", "2")
+				r.URL.RawQuery = q.Encode()
+			}
+			return next.RoundTrip(r)
+		})
+	})
+
+	parts, err := c.RetrieveStudyParallel("1.2.1", dicomweb.ParallelOption{PerInstance: true})
+	assert.NoError(t, err)
+	assert.Len(t, parts, 5)
+	// pages of 2, 2, 1 and 0 instances.
+	assert.Equal(t, 4, s.Requests(dicomweb.QIDOService))
+}
+
+func TestRetrieveStudyParallelErrors(t *testing.T) {
+	s := newParallelTestServer()
+	defer s.Close()
+	s.Inject(dicomweb.WADOService,
+		dicomwebtest.Fault{Status: http.StatusInternalServerError},
+		dicomwebtest.Fault{},
+		dicomwebtest.Fault{Status: http.StatusInternalServerError},
+	)
+
+	_, err := s.Client().RetrieveStudyParallel("1.2.1", dicomweb.ParallelOption{Workers: 1})
+	if assert.IsType(t, dicomweb.RetrieveErrors{}, err) {
+		assert.EqualError(t, err, "2 retrievals failed: "+
+			"SeriesRaw 1.2.1.1: 500 Internal Server Error; "+
+			"SeriesRaw 1.2.1.3: 500 Internal Server Error")
+	}
+
+	_, err = s.Client().RetrieveStudyParallel("1.2.9", dicomweb.ParallelOption{})
+	assert.EqualError(t, err, "study 1.2.9 not found")
+}
@@ -0,0 +1,92 @@
+package dicomweb
+
+import (
+	"io"
+	"strconv"
+	"sync"
+)
+
+// Progress describes how far a Retrieve or a Store transfer went.
+type Progress struct {
+	// Bytes number of bytes of the body received, or sent, so far.
+	Bytes int64
+	// TotalBytes expected number of bytes of the body, -1 if unknown.
+	TotalBytes int64
+	// Parts number of parts received, or sent, so far.
+	Parts int
+	// TotalParts expected number of parts, -1 if unknown.
+	TotalParts int
+}
+
+// ProgressFunc is called as a transfer progresses. It is called from the
+// goroutine reading or writing the body, so it should return quickly.
+type ProgressFunc func(Progress)
+
+// RelatedInstances returns the number of instances of the study, or series,
+// of the QIDO-RS search result, or -1 if the server did not return it. It is
+// meant for the ExpectedParts of a WADORequest.
+func (r QIDOResponse) RelatedInstances() int {
+	for _, t := range []Tag{r.NumberOfSeriesRelatedInstances, r.NumberOfStudyRelatedInstances} {
+		if n, ok := intValue(t); ok {
+			return n
+		}
+	}
+	return -1
+}
+
+// intValue returns the first value of the IS tag, which JSON decodes either as
+// a number or as a string.
+func intValue(t Tag) (int, bool) {
+	if len(t.Value) == 0 {
+		return 0, false
+	}
+	switch v := t.Value[0].(type) {
+	case float64:
+		return int(v), true
+	case string:
+		n, err := strconv.Atoi(v)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+// progressReader reports the bytes read through it, and the parts completed
+// once the bytes read reach their end offset, if the offsets are known.
+type progressReader struct {
+	io.Reader
+	mu       sync.Mutex
+	fn       ProgressFunc
+	progress Progress
+	ends     []int64
+}
+
+func newProgressReader(r io.Reader, fn ProgressFunc, totalBytes int64, totalParts int) *progressReader {
+	return &progressReader{
+		Reader:   r,
+		fn:       fn,
+		progress: Progress{TotalBytes: totalBytes, TotalParts: totalParts},
+	}
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	if n > 0 {
+		r.mu.Lock()
+		r.progress.Bytes += int64(n)
+		for len(r.ends) > 0 && r.progress.Bytes >= r.ends[0] {
+			r.ends = r.ends[1:]
+			r.progress.Parts++
+		}
+		r.fn(r.progress)
+		r.mu.Unlock()
+	}
+	return n, err
+}
+
+// part reports a part completed by the consumer of the reader.
+func (r *progressReader) part() {
+	r.mu.Lock()
+	r.progress.Parts++
+	r.fn(r.progress)
+	r.mu.Unlock()
+}
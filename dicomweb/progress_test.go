@@ -0,0 +1,98 @@
+package dicomweb_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/toastcheng/dicomweb-go/dicomweb"
+	"github.com/toastcheng/dicomweb-go/dicomweb/dicomwebtest"
+)
+
+func TestRetrieveProgress(t *testing.T) {
+	s := newParallelTestServer()
+	defer s.Close()
+	c := s.Client()
+
+	studies, err := c.Query(dicomweb.QIDORequest{Type: dicomweb.Study})
+	assert.NoError(t, err)
+	if !assert.Len(t, studies, 1) {
+		return
+	}
+	assert.Equal(t, 5, studies[0].RelatedInstances())
+
+	progress := []dicomweb.Progress{}
+	parts, err := c.Retrieve(dicomweb.WADORequest{
+		Type:             dicomweb.StudyRaw,
+		StudyInstanceUID: "1.2.1",
+		ExpectedParts:    studies[0].RelatedInstances(),
+		Progress: func(p dicomweb.Progress) {
+			progress = append(progress, p)
+		},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, parts, 5)
+
+	size := int64(0)
+	for _, p := range parts {
+		size += int64(len(p))
+	}
+	last := progress[len(progress)-1]
+	assert.Equal(t, 5, last.Parts)
+	assert.Equal(t, 5, last.TotalParts)
+	assert.True(t, last.Bytes > size)
+	for i := 1; i < len(progress); i++ {
+		assert.True(t, progress[i].Bytes >= progress[i-1].Bytes)
+		assert.True(t, progress[i].Parts >= progress[i-1].Parts)
+	}
+
+	progress = nil
+	_, err = c.Retrieve(dicomweb.WADORequest{
+		Type:              dicomweb.InstanceRaw,
+		StudyInstanceUID:  "1.2.1",
+		SeriesInstanceUID: "1.2.1.2",
+		SOPInstanceUID:    "1.2.1.2.1",
+		Progress: func(p dicomweb.Progress) {
+			progress = append(progress, p)
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, progress[len(progress)-1].Parts)
+	assert.Equal(t, 1, progress[len(progress)-1].TotalParts)
+}
+
+func TestStoreProgress(t *testing.T) {
+	s := dicomwebtest.NewServer()
+	defer s.Close()
+
+	parts := [][]byte{}
+	for _, sop := range []string{"1.2.1.1.1", "1.2.1.1.2"} {
+		parts = append(parts, dicomwebtest.NewInstance(dicomwebtest.Instance{
+			StudyInstanceUID:  "1.2.1",
+			SeriesInstanceUID: "1.2.1.1",
+			SOPInstanceUID:    sop,
+		}))
+	}
+	progress := []dicomweb.Progress{}
+	_, err := s.Client().Store(dicomweb.STOWRequest{
+		StudyInstanceUID: "1.2.1",
+		Parts:            parts,
+		Progress: func(p dicomweb.Progress) {
+			progress = append(progress, p)
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, s.Len())
+
+	last := progress[len(progress)-1]
+	assert.Equal(t, last.TotalBytes, last.Bytes)
+	assert.True(t, last.Bytes > int64(len(parts[0])+len(parts[1])))
+	assert.Equal(t, 2, last.Parts)
+	assert.Equal(t, 2, last.TotalParts)
+	// the first part is sent before the second one.
+	for _, p := range progress {
+		if p.Parts == 1 {
+			assert.True(t, p.Bytes >= int64(len(parts[0])))
+			assert.True(t, p.Bytes < int64(len(parts[0])+len(parts[1])))
+		}
+	}
+}
@@ -1,5 +1,10 @@
 package dicomweb
 
+import (
+	"strings"
+	"sync"
+)
+
 // Tag defines the dicom tag.
 type Tag struct {
 	Value []interface{} `json:"value"`
@@ -25,6 +30,9 @@ type QIDORequest struct {
 	AccessionNumber      string `json:"00080050,omitempty"`
 	Limit                int    `json:"limit,omitempty"`
 	Offset               int    `json:"offset,omitempty"`
+	// Filters other matching attributes, keyed by tag or keyword, e.g. the
+	// attributes of the scheduled procedure steps of a modality worklist.
+	Filters map[string]string `json:"-"`
 }
 
 // QIDOType defines the object to query.
@@ -37,8 +45,58 @@ const (
 	Series
 	// Instance DICOM instance.
 	Instance
+	// Patient DICOM patient, for the archives supporting patient-level search.
+	Patient
+	// ModalityWorklist scheduled procedure step of the modality worklist, for
+	// the archives exposing it, see ScheduledProcedureSteps.
+	ModalityWorklist
+)
+
+// qidoResource describes the resource a query type searches.
+type qidoResource struct {
+	name string
+	path string
+}
+
+var (
+	qidoMu sync.RWMutex
+	// qidoResources resources of the query types. The Series and Instance
+	// resources are searched under their study and series, if given.
+	qidoResources = map[QIDOType]qidoResource{
+		Study:            {"Study", "studies"},
+		Series:           {"Series", "series"},
+		Instance:         {"Instance", "instances"},
+		Patient:          {"Patient", "patients"},
+		ModalityWorklist: {"ModalityWorklist", "mwlitems"},
+	}
 )
 
+// RegisterQIDOType registers a query type searching the resource at the given
+// path of the QIDO endpoint, e.g. a search resource specific to an archive.
+func RegisterQIDOType(name, path string) QIDOType {
+	qidoMu.Lock()
+	defer qidoMu.Unlock()
+	t := QIDOType(len(qidoResources) + 1)
+	qidoResources[t] = qidoResource{name: name, path: strings.Trim(path, "/")}
+	return t
+}
+
+// resource returns the resource the query type searches.
+func (t QIDOType) resource() (qidoResource, bool) {
+	qidoMu.RLock()
+	defer qidoMu.RUnlock()
+	r, ok := qidoResources[t]
+	return r, ok
+}
+
+// String returns the name of the query type.
+func (t QIDOType) String() string {
+	if r, ok := t.resource(); ok {
+		return r.name
+	}
+	return "unknown"
+}
+
 // QIDORawResponse defines the response from QIDO api with neumerical field.
 type QIDORawResponse = map[string]Tag
 
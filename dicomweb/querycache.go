@@ -0,0 +1,210 @@
+package dicomweb
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// QueryCacheOption specifies the in-memory cache of QIDO-RS responses.
+type QueryCacheOption struct {
+	// TTL duration a cached response is served without contacting the server. Past it, the response is revalidated
+	// with If-None-Match or If-Modified-Since if the server sent an ETag or Last-Modified. Always revalidated otherwise
+	TTL time.Duration
+	// MaxEntries maximum number of cached responses, the least recently used being evicted first. Uses 1000 otherwise
+	MaxEntries int
+}
+
+// WithQueryCache configures the client to cache the responses of Query in memory.
+func (c *Client) WithQueryCache(option QueryCacheOption) *Client {
+	return c.WithMiddleware(QueryCache(option))
+}
+
+// QueryCache returns a middleware caching the successful QIDO-RS responses in
+// memory, keyed by the normalized query: the order of the query parameters and
+// the case of the tags do not matter. The responses are also keyed by the
+// Authorization header. Once a Delete succeeds, all the responses are evicted,
+// as any search may have matched the deleted resource. Other requests pass
+// through.
+func QueryCache(option QueryCacheOption) Middleware {
+	maxEntries := option.MaxEntries
+	if maxEntries <= 0 {
+		maxEntries = 1000
+	}
+	c := &queryCache{
+		ttl:        option.TTL,
+		maxEntries: maxEntries,
+		entries:    map[string]*list.Element{},
+		lru:        list.New(),
+	}
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			op, ok := OperationFromContext(r.Context())
+			switch {
+			case !ok:
+				return next.RoundTrip(r)
+			case r.Method == http.MethodGet && op.Service == QIDOService:
+				return c.roundTrip(next, r)
+			case r.Method == http.MethodDelete && op.Service == WADOService:
+				resp, err := next.RoundTrip(r)
+				if err == nil && resp.StatusCode/100 == 2 {
+					c.clear()
+				}
+				return resp, err
+			}
+			return next.RoundTrip(r)
+		})
+	}
+}
+
+type queryCache struct {
+	ttl        time.Duration
+	maxEntries int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	// lru holds the entries, the most recently used first.
+	lru *list.List
+}
+
+type queryCacheEntry struct {
+	key    string
+	header http.Header
+	body   []byte
+	stored time.Time
+}
+
+// queryKey normalizes the URL and the Accept header of the request, along with
+// a hash of its Authorization header, so that a client shared by several
+// principals does not serve one's results to another.
+func queryKey(r *http.Request) string {
+	values := url.Values{}
+	for k, v := range r.URL.Query() {
+		// tags are hexadecimal, 0020000d is 0020000D.
+		if len(k) == 8 && strings.Trim(strings.ToUpper(k), "0123456789ABCDEF") == "" {
+			k = strings.ToUpper(k)
+		}
+		values[k] = append(values[k], v...)
+	}
+	u := *r.URL
+	u.RawQuery = values.Encode()
+	key := u.String() + "\n" + r.Header.Get("Accept")
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		sum := sha256.Sum256([]byte(auth))
+		key += "\n" + hex.EncodeToString(sum[:])
+	}
+	return key
+}
+
+func (c *queryCache) get(key string) *queryCacheEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	c.lru.MoveToFront(el)
+	return el.Value.(*queryCacheEntry)
+}
+
+func (c *queryCache) put(e *queryCacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[e.key]; ok {
+		el.Value = e
+		c.lru.MoveToFront(el)
+		return
+	}
+	c.entries[e.key] = c.lru.PushFront(e)
+	for c.lru.Len() > c.maxEntries {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*queryCacheEntry).key)
+	}
+}
+
+// clear evicts all the entries.
+func (c *queryCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = map[string]*list.Element{}
+	c.lru.Init()
+}
+
+func (c *queryCache) roundTrip(next http.RoundTripper, r *http.Request) (*http.Response, error) {
+	key := queryKey(r)
+	e := c.get(key)
+	if e != nil && time.Since(e.stored) < c.ttl {
+		return e.response(r), nil
+	}
+
+	req := r
+	if e != nil {
+		etag, modified := e.header.Get("ETag"), e.header.Get("Last-Modified")
+		if etag != "" || modified != "" {
+			req = r.WithContext(r.Context())
+			req.Header = http.Header{}
+			for k, v := range r.Header {
+				req.Header[k] = v
+			}
+			if etag != "" {
+				req.Header.Set("If-None-Match", etag)
+			}
+			if modified != "" {
+				req.Header.Set("If-Modified-Since", modified)
+			}
+		}
+	}
+
+	resp, err := next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if e != nil && resp.StatusCode == http.StatusNotModified {
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+		refreshed := &queryCacheEntry{key: key, header: e.header, body: e.body, stored: time.Now()}
+		c.put(refreshed)
+		return refreshed.response(r), nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return resp, nil
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	c.put(&queryCacheEntry{key: key, header: resp.Header, body: body, stored: time.Now()})
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return resp, nil
+}
+
+func (e *queryCacheEntry) response(r *http.Request) *http.Response {
+	header := http.Header{}
+	for k, v := range e.header {
+		header[k] = v
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", http.StatusOK, http.StatusText(http.StatusOK)),
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(e.body)),
+		ContentLength: int64(len(e.body)),
+		Request:       r,
+	}
+}
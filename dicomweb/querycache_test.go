@@ -0,0 +1,117 @@
+package dicomweb
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryKey(t *testing.T) {
+	a, _ := http.NewRequest("GET", "http://pacs/studies?00100020=PAT&0020000d=1.2&limit=10", nil)
+	b, _ := http.NewRequest("GET", "http://pacs/studies?limit=10&0020000D=1.2&00100020=PAT", nil)
+	c, _ := http.NewRequest("GET", "http://pacs/studies?limit=10&0020000D=1.2&00100020=PAT2", nil)
+	assert.Equal(t, queryKey(a), queryKey(b))
+	assert.NotEqual(t, queryKey(a), queryKey(c))
+}
+
+func TestQueryCache(t *testing.T) {
+	var requests, notModified int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			atomic.AddInt32(&notModified, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write([]byte(`[{"0020000D":{"vr":"UI","Value":["1.2"]}}]`))
+	}))
+	defer ts.Close()
+
+	c := NewClient(ClientOption{QIDOEndpoint: ts.URL}).WithQueryCache(QueryCacheOption{TTL: time.Hour})
+	req := QIDORequest{Type: Study, PatientID: "PAT", StudyDate: "20200101"}
+	for i := 0; i < 3; i++ {
+		resp, err := c.Query(req)
+		assert.NoError(t, err)
+		if assert.Len(t, resp, 1) {
+			assert.Equal(t, []interface{}{"1.2"}, resp[0].StudyInstanceUID.Value)
+		}
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+
+	c = NewClient(ClientOption{QIDOEndpoint: ts.URL}).WithQueryCache(QueryCacheOption{})
+	for i := 0; i < 3; i++ {
+		resp, err := c.Query(req)
+		assert.NoError(t, err)
+		assert.Len(t, resp, 1)
+	}
+	assert.Equal(t, int32(4), atomic.LoadInt32(&requests))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&notModified))
+}
+
+func TestQueryCacheAuthorization(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Write([]byte(`[{"0020000D":{"vr":"UI","Value":["` + r.Header.Get("Authorization") + `"]}}]`))
+	}))
+	defer ts.Close()
+
+	c := NewClient(ClientOption{QIDOEndpoint: ts.URL}).WithQueryCache(QueryCacheOption{TTL: time.Hour})
+	for _, token := range []string{"alice", "bob", "alice"} {
+		c.authorization = "Bearer " + token
+		resp, err := c.Query(QIDORequest{Type: Study, PatientID: "PAT"})
+		assert.NoError(t, err)
+		if assert.Len(t, resp, 1) {
+			assert.Equal(t, []interface{}{"Bearer " + token}, resp[0].StudyInstanceUID.Value)
+		}
+	}
+	// each principal searches once.
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+}
+
+func TestQueryCacheEviction(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Write([]byte(`[]`))
+	}))
+	defer ts.Close()
+
+	c := NewClient(ClientOption{QIDOEndpoint: ts.URL}).WithQueryCache(QueryCacheOption{TTL: time.Hour, MaxEntries: 2})
+	for _, id := range []string{"A", "B", "A", "C", "A", "B"} {
+		_, err := c.Query(QIDORequest{Type: Study, PatientID: id})
+		assert.NoError(t, err)
+	}
+	// B is evicted by C, being least recently used.
+	assert.Equal(t, int32(4), atomic.LoadInt32(&requests))
+}
+
+func TestQueryCacheDelete(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		atomic.AddInt32(&requests, 1)
+		w.Write([]byte(`[{"0020000D":{"vr":"UI","Value":["1.2"]}}]`))
+	}))
+	defer ts.Close()
+
+	c := NewClient(ClientOption{QIDOEndpoint: ts.URL, WADOEndpoint: ts.URL}).WithQueryCache(QueryCacheOption{TTL: time.Hour})
+	req := QIDORequest{Type: Study, PatientID: "PAT"}
+	c.Query(req)
+	c.Query(req)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+
+	// the search matched the deleted study without naming it.
+	err := c.Delete(DeleteRequest{Type: DeleteStudy, StudyInstanceUID: "1.2"})
+	assert.NoError(t, err)
+	c.Query(req)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+}
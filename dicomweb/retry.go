@@ -0,0 +1,185 @@
+package dicomweb
+
+import (
+	"io"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy defines how requests that failed transiently are retried.
+// A request is retried on network errors and on the status codes listed in
+// RetryStatus, waiting an exponentially growing backoff between attempts.
+//
+// STOW requests are not idempotent, they are only retried when the server
+// could not have stored anything: the connection was never established, or the
+// server explicitly refused the request with 429 Too Many Requests or
+// 503 Service Unavailable.
+type RetryPolicy struct {
+	// MaxAttempts maximum number of attempts, including the first one.
+	MaxAttempts int
+	// InitialBackoff backoff before the first retry. Uses 500ms otherwise.
+	InitialBackoff time.Duration
+	// MaxBackoff upper bound of the backoff, including the one given by Retry-After. Uses 30s otherwise.
+	MaxBackoff time.Duration
+	// Multiplier factor the backoff grows by after each attempt. Uses 2 otherwise.
+	Multiplier float64
+	// Jitter fraction of the backoff which is randomized, between 0 and 1.
+	Jitter float64
+	// RetryStatus status codes that are retried. Uses 429, 502, 503 and 504 otherwise.
+	RetryStatus []int
+}
+
+// WithRetry configures the client to retry requests that failed transiently.
+func (c *Client) WithRetry(policy RetryPolicy) *Client {
+	c.retryPolicy = &policy
+	return c
+}
+
+// send performs the request, retrying it according to the retry policy.
+func (c *Client) send(service Service, r *http.Request) (*http.Response, error) {
+	p := c.retryPolicy
+	for attempt := 1; ; attempt++ {
+		resp, err := c.roundTrip(service, r)
+		if p == nil || attempt >= p.MaxAttempts || !p.retryable(r, resp, err) {
+			return resp, err
+		}
+		if r.Body != nil && r.GetBody == nil {
+			return resp, err
+		}
+
+		wait := p.backoff(attempt, resp)
+		if resp != nil {
+			io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-r.Context().Done():
+			timer.Stop()
+			return nil, r.Context().Err()
+		case <-timer.C:
+		}
+
+		if r.GetBody != nil {
+			body, err := r.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			r.Body = body
+		}
+	}
+}
+
+// retryable reports whether the outcome of the request is worth another attempt.
+func (p *RetryPolicy) retryable(r *http.Request, resp *http.Response, err error) bool {
+	if err != nil {
+		if r.Context().Err() != nil {
+			return false
+		}
+		if idempotent(r.Method) {
+			return true
+		}
+		return notConnected(err)
+	}
+
+	if !idempotent(r.Method) {
+		return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable
+	}
+	status := p.RetryStatus
+	if status == nil {
+		status = []int{
+			http.StatusTooManyRequests,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		}
+	}
+	for _, s := range status {
+		if resp.StatusCode == s {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff returns the time to wait before the next attempt.
+func (p *RetryPolicy) backoff(attempt int, resp *http.Response) time.Duration {
+	initial, max, multiplier := p.InitialBackoff, p.MaxBackoff, p.Multiplier
+	if initial <= 0 {
+		initial = 500 * time.Millisecond
+	}
+	if max <= 0 {
+		max = 30 * time.Second
+	}
+	if multiplier < 1 {
+		multiplier = 2
+	}
+
+	if resp != nil {
+		if d, ok := retryAfter(resp.Header.Get("Retry-After")); ok {
+			if d > max {
+				d = max
+			}
+			return d
+		}
+	}
+
+	d := float64(initial) * math.Pow(multiplier, float64(attempt-1))
+	if d > float64(max) {
+		d = float64(max)
+	}
+	if p.Jitter > 0 {
+		jitter := math.Min(p.Jitter, 1)
+		d -= d * jitter * rand.Float64()
+	}
+	return time.Duration(d)
+}
+
+// retryAfter parses the Retry-After header, given either in seconds or as an HTTP date.
+func retryAfter(v string) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if s, err := strconv.Atoi(v); err == nil {
+		if s < 0 {
+			return 0, false
+		}
+		return time.Duration(s) * time.Second, true
+	}
+	t, err := http.ParseTime(v)
+	if err != nil {
+		return 0, false
+	}
+	d := time.Until(t)
+	if d < 0 {
+		d = 0
+	}
+	return d, true
+}
+
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// notConnected reports whether the error happened before the request reached
+// the server, so that retrying a non-idempotent request is safe.
+func notConnected(err error) bool {
+	if urlErr, ok := err.(*url.Error); ok {
+		err = urlErr.Err
+	}
+	if opErr, ok := err.(*net.OpError); ok {
+		return opErr.Op == "dial"
+	}
+	return false
+}
@@ -0,0 +1,170 @@
+package dicomweb
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryQueryOnServiceUnavailable(t *testing.T) {
+	var count int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&count, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("[]"))
+	}))
+	defer ts.Close()
+
+	c := NewClient(ClientOption{
+		QIDOEndpoint: ts.URL,
+		RetryPolicy: &RetryPolicy{
+			MaxAttempts:    3,
+			InitialBackoff: time.Millisecond,
+		},
+	})
+
+	_, err := c.Query(QIDORequest{Type: Study})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&count))
+}
+
+func TestRetryGiveUpAfterMaxAttempts(t *testing.T) {
+	var count int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&count, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer ts.Close()
+
+	c := NewClient(ClientOption{
+		WADOEndpoint: ts.URL,
+	}).WithRetry(RetryPolicy{
+		MaxAttempts:    2,
+		InitialBackoff: time.Millisecond,
+	})
+
+	_, err := c.Retrieve(WADORequest{Type: StudyRaw, StudyInstanceUID: "1.2.3"})
+	if assert.Error(t, err) {
+		assert.Equal(t, "502 Bad Gateway", err.Error())
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(&count))
+}
+
+func TestRetryNotOnClientError(t *testing.T) {
+	var count int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&count, 1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer ts.Close()
+
+	c := NewClient(ClientOption{
+		QIDOEndpoint: ts.URL,
+	}).WithRetry(RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+	})
+
+	_, err := c.Query(QIDORequest{Type: Study})
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&count))
+}
+
+func TestRetryStoreResendsBody(t *testing.T) {
+	var count int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		assert.Contains(t, string(b), "part: 0")
+		if atomic.AddInt32(&count, 1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte("{}"))
+	}))
+	defer ts.Close()
+
+	c := NewClient(ClientOption{
+		STOWEndpoint: ts.URL,
+	}).WithRetry(RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: time.Hour,
+	})
+
+	_, err := c.Store(STOWRequest{Parts: [][]byte{[]byte("part: 0")}})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&count))
+}
+
+func TestRetryStoreNotOnServerError(t *testing.T) {
+	var count int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&count, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer ts.Close()
+
+	c := NewClient(ClientOption{
+		STOWEndpoint: ts.URL,
+	}).WithRetry(RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+	})
+
+	c.Store(STOWRequest{Parts: [][]byte{[]byte("part: 0")}})
+	assert.Equal(t, int32(1), atomic.LoadInt32(&count))
+}
+
+func TestRetryStoreOnConnectionRefused(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	c := NewClient(ClientOption{
+		STOWEndpoint: "http://" + addr,
+	}).WithRetry(RetryPolicy{
+		MaxAttempts:    2,
+		InitialBackoff: time.Millisecond,
+	})
+	p := c.retryPolicy
+
+	_, err = c.Store(STOWRequest{Parts: [][]byte{[]byte("part: 0")}})
+	if assert.Error(t, err) {
+		r, _ := http.NewRequest("POST", "http://"+addr, nil)
+		assert.True(t, p.retryable(r, nil, err))
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	p := &RetryPolicy{
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     time.Second,
+	}
+	assert.Equal(t, 100*time.Millisecond, p.backoff(1, nil))
+	assert.Equal(t, 200*time.Millisecond, p.backoff(2, nil))
+	assert.Equal(t, 400*time.Millisecond, p.backoff(3, nil))
+	assert.Equal(t, time.Second, p.backoff(10, nil))
+
+	resp := &http.Response{Header: http.Header{}}
+	resp.Header.Set("Retry-After", "1")
+	assert.Equal(t, time.Second, p.backoff(1, resp))
+	resp.Header.Set("Retry-After", "120")
+	assert.Equal(t, time.Second, p.backoff(1, resp))
+
+	p.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		d := p.backoff(2, nil)
+		assert.True(t, d > 100*time.Millisecond && d <= 200*time.Millisecond)
+	}
+}
@@ -0,0 +1,288 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/toastcheng/dicomweb-go/dicomweb"
+	"github.com/toastcheng/dicomweb-go/dicomweb/dicom"
+)
+
+// Attributes returned for each level of QIDO-RS search, besides the computed ones.
+var (
+	studyAttributes = []dicom.Tag{
+		dicom.SpecificCharacterSet,
+		dicom.StudyDate,
+		dicom.StudyTime,
+		dicom.AccessionNumber,
+		0x00080090, // ReferringPhysicianName
+		dicom.StudyDescription,
+		dicom.PatientName,
+		dicom.PatientID,
+		dicom.PatientBirthDate,
+		dicom.PatientSex,
+		dicom.StudyInstanceUID,
+		dicom.StudyID,
+	}
+	seriesAttributes = []dicom.Tag{
+		dicom.SpecificCharacterSet,
+		dicom.SeriesDate,
+		dicom.SeriesTime,
+		dicom.Modality,
+		dicom.SeriesDescription,
+		dicom.StudyInstanceUID,
+		dicom.SeriesInstanceUID,
+		dicom.SeriesNumber,
+	}
+	instanceAttributes = []dicom.Tag{
+		dicom.SpecificCharacterSet,
+		dicom.SOPClassUID,
+		dicom.SOPInstanceUID,
+		dicom.StudyInstanceUID,
+		dicom.SeriesInstanceUID,
+		dicom.InstanceNumber,
+		dicom.Rows,
+		dicom.Columns,
+		dicom.BitsAllocated,
+		dicom.NumberOfFrames,
+	}
+	// matchingAttributes are the attributes QIDORequest filters on.
+	matchingAttributes = []dicom.Tag{
+		dicom.StudyInstanceUID,
+		dicom.SeriesInstanceUID,
+		dicom.SOPInstanceUID,
+		dicom.PatientID,
+		dicom.StudyDate,
+		dicom.StudyTime,
+		dicom.SeriesDate,
+		dicom.SeriesTime,
+		0x00080012, // InstanceCreationDate
+		0x00080013, // InstanceCreationTime
+		0x00181063, // FrameTime
+		0x00321041, // StudyArrivalTime
+		0x00321051, // StudyCompletionTime
+		dicom.AccessionNumber,
+	}
+)
+
+// SearchAttributes returns the attributes of the instances Search matches and
+// returns, so that a Storage can index these only.
+func SearchAttributes() []dicom.Tag {
+	tags := []dicom.Tag{}
+	seen := map[dicom.Tag]bool{}
+	for _, list := range [][]dicom.Tag{studyAttributes, seriesAttributes, instanceAttributes, matchingAttributes} {
+		for _, t := range list {
+			if !seen[t] {
+				seen[t] = true
+				tags = append(tags, t)
+			}
+		}
+	}
+	return tags
+}
+
+// ParseQIDORequest parses the query parameters of a QIDO-RS request. Attributes
+// can be given either by tag or by keyword; those which are not fields of
+// QIDORequest go to its Filters, keyed by tag. Other parameters, such as
+// includefield, are ignored.
+func ParseQIDORequest(t dicomweb.QIDOType, values url.Values) dicomweb.QIDORequest {
+	mp := map[string]interface{}{}
+	for k, v := range values {
+		if len(v) == 0 {
+			continue
+		}
+		switch k {
+		case "limit", "offset":
+			if n, err := strconv.Atoi(v[0]); err == nil {
+				mp[k] = n
+			}
+			continue
+		}
+		if _, err := dicom.ParseTag(k); err == nil {
+			mp[strings.ToUpper(k)] = v[0]
+		} else if tag, ok := dicom.LookupKeyword(k); ok {
+			mp[tag.Hex()] = v[0]
+		}
+	}
+
+	req := dicomweb.QIDORequest{}
+	b, _ := json.Marshal(mp)
+	json.Unmarshal(b, &req)
+	req.Type = t
+
+	fields := map[string]interface{}{}
+	b, _ = json.Marshal(req)
+	json.Unmarshal(b, &fields)
+	for k, v := range mp {
+		if s, ok := v.(string); ok && s != "" && fields[k] == nil {
+			if req.Filters == nil {
+				req.Filters = map[string]string{}
+			}
+			req.Filters[k] = s
+		}
+	}
+	return req
+}
+
+// RawResponse converts the data set to the representation QIDORequest matches against.
+func RawResponse(ds *dicom.Dataset) dicomweb.QIDORawResponse {
+	raw := dicomweb.QIDORawResponse{}
+	for k, a := range ds.JSON() {
+		raw[k] = dicomweb.Tag{VR: a.VR, Value: a.Value}
+	}
+	return raw
+}
+
+// Search answers the QIDO-RS request over the data sets of instances: it
+// matches the instances, groups them at the level of the request, with the
+// computed attributes such as ModalitiesInStudy and the number of related
+// instances, and applies the offset and limit.
+func Search(req dicomweb.QIDORequest, instances []*dicom.Dataset) []*dicom.Dataset {
+	level := dicom.StudyInstanceUID
+	switch req.Type {
+	case dicomweb.Series:
+		level = dicom.SeriesInstanceUID
+	case dicomweb.Instance:
+		level = dicom.SOPInstanceUID
+	}
+
+	keys := []string{}
+	matched := map[string]*dicom.Dataset{}
+	for _, ds := range instances {
+		if !req.Match(RawResponse(ds)) {
+			continue
+		}
+		key := ds.String(level)
+		if _, ok := matched[key]; !ok {
+			keys = append(keys, key)
+			matched[key] = ds
+		}
+	}
+
+	if req.Offset > 0 {
+		if req.Offset > len(keys) {
+			req.Offset = len(keys)
+		}
+		keys = keys[req.Offset:]
+	}
+	if req.Limit > 0 && req.Limit < len(keys) {
+		keys = keys[:req.Limit]
+	}
+
+	result := []*dicom.Dataset{}
+	for _, key := range keys {
+		ds := matched[key]
+		switch req.Type {
+		case dicomweb.Series:
+			result = append(result, seriesDataset(ds, instances))
+		case dicomweb.Instance:
+			result = append(result, pick(ds, instanceAttributes))
+		default:
+			result = append(result, studyDataset(ds, instances))
+		}
+	}
+	return result
+}
+
+func pick(from *dicom.Dataset, tags []dicom.Tag) *dicom.Dataset {
+	ds := &dicom.Dataset{}
+	for _, t := range tags {
+		if e := from.Get(t); e != nil {
+			ds.Set(e)
+		}
+	}
+	return ds
+}
+
+func studyDataset(study *dicom.Dataset, instances []*dicom.Dataset) *dicom.Dataset {
+	ds := pick(study, studyAttributes)
+	uid := study.String(dicom.StudyInstanceUID)
+	modalities := []string{}
+	series := map[string]bool{}
+	n := 0
+	for _, i := range instances {
+		if i.String(dicom.StudyInstanceUID) != uid {
+			continue
+		}
+		n++
+		series[i.String(dicom.SeriesInstanceUID)] = true
+		m := i.String(dicom.Modality)
+		found := false
+		for _, existing := range modalities {
+			found = found || existing == m
+		}
+		if m != "" && !found {
+			modalities = append(modalities, m)
+		}
+	}
+	ds.Set(dicom.NewString(dicom.ModalitiesInStudy, "CS", modalities...))
+	ds.Set(dicom.NewString(0x00201206, "IS", strconv.Itoa(len(series))))
+	ds.Set(dicom.NewString(0x00201208, "IS", strconv.Itoa(n)))
+	return ds
+}
+
+func seriesDataset(series *dicom.Dataset, instances []*dicom.Dataset) *dicom.Dataset {
+	ds := pick(series, seriesAttributes)
+	uid := series.String(dicom.SeriesInstanceUID)
+	n := 0
+	for _, i := range instances {
+		if i.String(dicom.SeriesInstanceUID) == uid {
+			n++
+		}
+	}
+	ds.Set(dicom.NewString(0x00201209, "IS", strconv.Itoa(n)))
+	return ds
+}
+
+func (h *Handler) search(w http.ResponseWriter, r *http.Request, t dicomweb.QIDOType, study, series string) {
+	req := ParseQIDORequest(t, r.URL.Query())
+	if study != "" {
+		req.StudyInstanceUID = study
+	}
+	if series != "" {
+		req.SeriesInstanceUID = series
+	}
+
+	datasets, err := h.storage.Search(r.Context(), req)
+	if err != nil {
+		storageError(w, r, err)
+		return
+	}
+
+	result := []map[string]dicom.Attribute{}
+	for _, ds := range datasets {
+		if ds.Get(0x00081190) == nil {
+			ds = ds.Copy()
+			url := h.retrieveURL(r, ds.String(dicom.StudyInstanceUID), "", "")
+			switch t {
+			case dicomweb.Series:
+				url = h.retrieveURL(r, ds.String(dicom.StudyInstanceUID), ds.String(dicom.SeriesInstanceUID), "")
+			case dicomweb.Instance:
+				url = h.retrieveURL(r, ds.String(dicom.StudyInstanceUID), ds.String(dicom.SeriesInstanceUID), ds.String(dicom.SOPInstanceUID))
+			}
+			ds.Set(dicom.NewString(0x00081190, "UR", url))
+		}
+		result = append(result, ds.JSON())
+	}
+
+	body, err := json.Marshal(result)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// the ETag lets clients revalidate cached search results.
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	w.Header().Set("ETag", etag)
+	if match := r.Header.Get("If-None-Match"); match != "" && strings.Contains(match, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "application/dicom+json")
+	w.Write(body)
+}
@@ -0,0 +1,179 @@
+// Package server implements the server side of DICOMweb: an http.Handler which
+// parses QIDO-RS, WADO-RS and STOW-RS requests into the request types of the
+// dicomweb client, delegates them to a Storage and renders the DICOM JSON and
+// multipart responses. DELETE requests are served if the Storage is a Deleter.
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/toastcheng/dicomweb-go/dicomweb"
+	"github.com/toastcheng/dicomweb-go/dicomweb/dicom"
+)
+
+// ErrNotFound is returned by a Storage when no instance matches the request.
+var ErrNotFound = errors.New("not found")
+
+// Storage is the archive served by a Handler.
+type Storage interface {
+	// Search returns the result of the QIDO-RS search at the level of the
+	// request, see Search for an implementation over instances.
+	Search(ctx context.Context, req dicomweb.QIDORequest) ([]*dicom.Dataset, error)
+	// Retrieve returns the DICOM Part 10 instances of the study, series or
+	// instance, the request type being one of StudyRaw, SeriesRaw and InstanceRaw.
+	Retrieve(ctx context.Context, req dicomweb.WADORequest) ([][]byte, error)
+	// Store stores the parts of the request and returns the error of each part,
+	// nil if it was stored. The error fails the whole request.
+	Store(ctx context.Context, req dicomweb.STOWRequest) ([]error, error)
+}
+
+// Deleter is implemented by the storages which can delete studies, series and
+// instances. The Handler answers 405 Method Not Allowed to the DELETE requests
+// otherwise.
+type Deleter interface {
+	// Delete deletes the study, series or instance of the request.
+	Delete(ctx context.Context, req dicomweb.DeleteRequest) error
+}
+
+// Handler serves DICOMweb requests from a Storage.
+type Handler struct {
+	storage Storage
+	baseURL string
+}
+
+// NewHandler creates a handler serving the storage.
+func NewHandler(storage Storage) *Handler {
+	return &Handler{storage: storage}
+}
+
+// WithBaseURL sets the URL the handler is reachable at, used for the retrieve
+// URLs of the responses. The scheme and host of the request are used otherwise.
+func (h *Handler) WithBaseURL(url string) *Handler {
+	h.baseURL = strings.TrimSuffix(url, "/")
+	return h
+}
+
+// ServeHTTP routes the request to the QIDO, WADO, STOW or delete handler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	seg := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if r.Method == http.MethodDelete {
+		switch {
+		case len(seg) == 2 && seg[0] == "studies":
+			h.delete(w, r, dicomweb.DeleteRequest{Type: dicomweb.DeleteStudy, StudyInstanceUID: seg[1]})
+		case len(seg) == 4 && seg[0] == "studies" && seg[2] == "series":
+			h.delete(w, r, dicomweb.DeleteRequest{Type: dicomweb.DeleteSeries, StudyInstanceUID: seg[1], SeriesInstanceUID: seg[3]})
+		case len(seg) == 6 && seg[0] == "studies" && seg[2] == "series" && seg[4] == "instances":
+			h.delete(w, r, dicomweb.DeleteRequest{Type: dicomweb.DeleteInstance, StudyInstanceUID: seg[1], SeriesInstanceUID: seg[3], SOPInstanceUID: seg[5]})
+		default:
+			http.NotFound(w, r)
+		}
+		return
+	}
+	if r.Method == http.MethodPost {
+		switch {
+		case len(seg) == 1 && seg[0] == "studies":
+			h.store(w, r, "")
+		case len(seg) == 2 && seg[0] == "studies":
+			h.store(w, r, seg[1])
+		default:
+			http.NotFound(w, r)
+		}
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	switch {
+	case len(seg) == 1 && seg[0] == "studies":
+		h.search(w, r, dicomweb.Study, "", "")
+	case len(seg) == 1 && seg[0] == "series":
+		h.search(w, r, dicomweb.Series, "", "")
+	case len(seg) == 1 && seg[0] == "instances":
+		h.search(w, r, dicomweb.Instance, "", "")
+	case len(seg) < 2 || seg[0] != "studies":
+		http.NotFound(w, r)
+	case len(seg) == 2:
+		h.retrieve(w, r, seg[1], "", "", "")
+	case len(seg) == 3 && seg[2] == "series":
+		h.search(w, r, dicomweb.Series, seg[1], "")
+	case len(seg) == 3 && seg[2] == "instances":
+		h.search(w, r, dicomweb.Instance, seg[1], "")
+	case len(seg) == 3:
+		h.retrieve(w, r, seg[1], "", "", seg[2])
+	case len(seg) < 4 || seg[2] != "series":
+		http.NotFound(w, r)
+	case len(seg) == 4:
+		h.retrieve(w, r, seg[1], seg[3], "", "")
+	case len(seg) == 5 && seg[4] == "instances":
+		h.search(w, r, dicomweb.Instance, seg[1], seg[3])
+	case len(seg) == 5:
+		h.retrieve(w, r, seg[1], seg[3], "", seg[4])
+	case len(seg) < 6 || seg[4] != "instances":
+		http.NotFound(w, r)
+	case len(seg) == 6:
+		h.retrieve(w, r, seg[1], seg[3], seg[5], "")
+	case len(seg) == 7:
+		h.retrieve(w, r, seg[1], seg[3], seg[5], seg[6])
+	case len(seg) == 8 && seg[6] == "frames":
+		h.frames(w, r, seg[1], seg[3], seg[5], seg[7])
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// delete deletes the study, series or instance if the storage is a Deleter.
+func (h *Handler) delete(w http.ResponseWriter, r *http.Request, req dicomweb.DeleteRequest) {
+	d, ok := h.storage.(Deleter)
+	if !ok {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := d.Delete(r.Context(), req); err != nil {
+		storageError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// base returns the URL the handler is reachable at.
+func (h *Handler) base(r *http.Request) string {
+	if h.baseURL != "" {
+		return h.baseURL
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s", scheme, r.Host)
+}
+
+// retrieveURL returns the URL of the study, series or instance.
+func (h *Handler) retrieveURL(r *http.Request, study, series, sop string) string {
+	url := h.base(r) + "/studies/" + study
+	if series != "" {
+		url += "/series/" + series
+	}
+	if sop != "" {
+		url += "/instances/" + sop
+	}
+	return url
+}
+
+// storageError writes the response of an error returned by the storage.
+func storageError(w http.ResponseWriter, r *http.Request, err error) {
+	if err == ErrNotFound {
+		http.NotFound(w, r)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
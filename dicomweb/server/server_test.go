@@ -0,0 +1,185 @@
+package server_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/toastcheng/dicomweb-go/dicomweb"
+	"github.com/toastcheng/dicomweb-go/dicomweb/dicom"
+	"github.com/toastcheng/dicomweb-go/dicomweb/dicomwebtest"
+	"github.com/toastcheng/dicomweb-go/dicomweb/server"
+)
+
+type testStorage struct {
+	instances [][]byte
+	searched  dicomweb.QIDORequest
+	retrieved dicomweb.WADORequest
+	storeErrs []error
+}
+
+func (s *testStorage) Search(ctx context.Context, req dicomweb.QIDORequest) ([]*dicom.Dataset, error) {
+	s.searched = req
+	datasets := []*dicom.Dataset{}
+	for _, data := range s.instances {
+		ds, _ := dicom.Parse(data)
+		datasets = append(datasets, ds)
+	}
+	return server.Search(req, datasets), nil
+}
+
+func (s *testStorage) Retrieve(ctx context.Context, req dicomweb.WADORequest) ([][]byte, error) {
+	s.retrieved = req
+	if len(s.instances) == 0 {
+		return nil, server.ErrNotFound
+	}
+	return s.instances, nil
+}
+
+func (s *testStorage) Store(ctx context.Context, req dicomweb.STOWRequest) ([]error, error) {
+	return s.storeErrs, nil
+}
+
+func TestParseQIDORequest(t *testing.T) {
+	req := server.ParseQIDORequest(dicomweb.Series, url.Values{
+		"PatientID":    {"PAT-1"},
+		"00080020":     {"20200101-"},
+		"PatientName":  {"Doe*"},
+		"00080060":     {"CT"},
+		"limit":        {"10"},
+		"offset":       {"x"},
+		"includefield": {"all"},
+	})
+	assert.Equal(t, dicomweb.QIDORequest{
+		Type:      dicomweb.Series,
+		PatientID: "PAT-1",
+		StudyDate: "20200101-",
+		Limit:     10,
+		Filters:   map[string]string{"00100010": "Doe*", "00080060": "CT"},
+	}, req)
+}
+
+func TestHandlerSearch(t *testing.T) {
+	storage := &testStorage{instances: [][]byte{
+		dicomwebtest.NewInstance(dicomwebtest.Instance{
+			StudyInstanceUID:  "1.2.1",
+			SeriesInstanceUID: "1.2.1.1",
+			SOPInstanceUID:    "1.2.1.1.1",
+		}),
+	}}
+	ts := httptest.NewServer(server.NewHandler(storage).WithBaseURL("https://pacs.example.com/dicomweb/"))
+	defer ts.Close()
+	c := dicomweb.NewClient(dicomweb.ClientOption{QIDOEndpoint: ts.URL})
+
+	resp, err := c.Query(dicomweb.QIDORequest{Type: dicomweb.Instance, StudyInstanceUID: "1.2.1"})
+	assert.NoError(t, err)
+	assert.Equal(t, dicomweb.QIDORequest{Type: dicomweb.Instance, StudyInstanceUID: "1.2.1"}, storage.searched)
+	if assert.Len(t, resp, 1) {
+		assert.Equal(t, []interface{}{"https://pacs.example.com/dicomweb/studies/1.2.1/series/1.2.1.1/instances/1.2.1.1.1"}, resp[0].RetrieveURL.Value)
+	}
+}
+
+func TestHandlerRetrieve(t *testing.T) {
+	storage := &testStorage{}
+	ts := httptest.NewServer(server.NewHandler(storage))
+	defer ts.Close()
+	c := dicomweb.NewClient(dicomweb.ClientOption{WADOEndpoint: ts.URL})
+
+	_, err := c.Retrieve(dicomweb.WADORequest{
+		Type:              dicomweb.InstanceMetadata,
+		StudyInstanceUID:  "1.2.1",
+		SeriesInstanceUID: "1.2.1.1",
+		SOPInstanceUID:    "1.2.1.1.1",
+	})
+	assert.EqualError(t, err, "404 Not Found")
+	assert.Equal(t, dicomweb.WADORequest{
+		Type:              dicomweb.InstanceRaw,
+		StudyInstanceUID:  "1.2.1",
+		SeriesInstanceUID: "1.2.1.1",
+		SOPInstanceUID:    "1.2.1.1.1",
+	}, storage.retrieved)
+
+	resp, err := http.Get(ts.URL + "/studies/1.2.1/rendered")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotAcceptable, resp.StatusCode)
+}
+
+func TestHandlerStore(t *testing.T) {
+	storage := &testStorage{storeErrs: []error{
+		nil,
+		&server.StoreError{Reason: 0xA700, Err: errors.New("out of resources")},
+		errors.New("failed"),
+	}}
+	ts := httptest.NewServer(server.NewHandler(storage))
+	defer ts.Close()
+	c := dicomweb.NewClient(dicomweb.ClientOption{STOWEndpoint: ts.URL})
+
+	parts := [][]byte{}
+	for _, uid := range []string{"1.2.1.1.1", "1.2.1.1.2", "1.2.1.1.3"} {
+		parts = append(parts, dicomwebtest.NewInstance(dicomwebtest.Instance{
+			StudyInstanceUID:  "1.2.1",
+			SeriesInstanceUID: "1.2.1.1",
+			SOPInstanceUID:    uid,
+		}))
+	}
+	resp, err := c.Store(dicomweb.STOWRequest{Parts: parts})
+	assert.NoError(t, err)
+
+	b, _ := json.Marshal(resp)
+	result := map[string]dicom.Attribute{}
+	json.Unmarshal(b, &result)
+	assert.Len(t, result["00081199"].Value, 1)
+	if assert.Len(t, result["00081198"].Value, 2) {
+		reasons := []interface{}{}
+		for _, item := range result["00081198"].Value {
+			reasons = append(reasons, item.(map[string]interface{})["00081197"].(map[string]interface{})["Value"].([]interface{})[0])
+		}
+		assert.Equal(t, []interface{}{float64(0xA700), float64(server.FailureProcessing)}, reasons)
+	}
+}
+
+func TestHandlerSearchNotModified(t *testing.T) {
+	ts := httptest.NewServer(server.NewHandler(&testStorage{}))
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/studies")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	etag := resp.Header.Get("ETag")
+	assert.NotEmpty(t, etag)
+
+	r, _ := http.NewRequest("GET", ts.URL+"/studies", nil)
+	r.Header.Set("If-None-Match", etag)
+	resp, err = http.DefaultClient.Do(r)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+}
+
+func TestHandlerDeleteNotSupported(t *testing.T) {
+	ts := httptest.NewServer(server.NewHandler(&testStorage{}))
+	defer ts.Close()
+	c := dicomweb.NewClient(dicomweb.ClientOption{WADOEndpoint: ts.URL})
+
+	err := c.Delete(dicomweb.DeleteRequest{Type: dicomweb.DeleteStudy, StudyInstanceUID: "1.2.3"})
+	assert.EqualError(t, err, "405 Method Not Allowed")
+}
+
+func TestExtractFramesEncapsulated(t *testing.T) {
+	ds := &dicom.Dataset{}
+	ds.Set(dicom.NewString(dicom.NumberOfFrames, "IS", "2"))
+	ds.Set(&dicom.Element{Tag: dicom.PixelData, VR: "OB", Fragments: [][]byte{{}, {0xFF, 0xD8}, {0xFF, 0xD9}}})
+	frames, err := server.ExtractFrames(ds)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]byte{{0xFF, 0xD8}, {0xFF, 0xD9}}, frames)
+
+	ds.Set(&dicom.Element{Tag: dicom.PixelData, VR: "OB", Fragments: [][]byte{}})
+	_, err = server.ExtractFrames(ds)
+	assert.Error(t, err)
+}
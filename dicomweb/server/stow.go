@@ -0,0 +1,144 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
+
+	"github.com/toastcheng/dicomweb-go/dicomweb"
+	"github.com/toastcheng/dicomweb-go/dicomweb/dicom"
+)
+
+// Failure reasons of the FailedSOPSequence of a STOW-RS response.
+const (
+	// FailureProcessing the instance is missing a UID or belongs to another study than requested.
+	FailureProcessing = 0x0110
+	// FailureCannotUnderstand the part is not a DICOM Part 10 instance.
+	FailureCannotUnderstand = 0xC000
+)
+
+// StoreError is the error of a part a Storage failed to store, with the reason
+// reported in the STOW-RS response.
+type StoreError struct {
+	Reason uint16
+	Err    error
+}
+
+func (e *StoreError) Error() string {
+	return e.Err.Error()
+}
+
+// CheckInstance parses the part of a STOW-RS request and checks it is an
+// instance of the study, if any, with its Study, Series and SOP Instance UIDs.
+func CheckInstance(study string, data []byte) (*dicom.Dataset, error) {
+	ds, err := dicom.Parse(data)
+	if err != nil {
+		return nil, &StoreError{Reason: FailureCannotUnderstand, Err: err}
+	}
+	if ds.String(dicom.StudyInstanceUID) == "" || ds.String(dicom.SeriesInstanceUID) == "" || ds.String(dicom.SOPInstanceUID) == "" {
+		return nil, &StoreError{Reason: FailureProcessing, Err: errors.New("instance without Study, Series or SOP Instance UID")}
+	}
+	if study != "" && ds.String(dicom.StudyInstanceUID) != study {
+		return nil, &StoreError{Reason: FailureProcessing, Err: errors.New("instance of another study")}
+	}
+	return ds, nil
+}
+
+func (h *Handler) store(w http.ResponseWriter, r *http.Request, study string) {
+	mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || !strings.HasPrefix(mediaType, "multipart/") {
+		http.Error(w, "Content-Type should be multipart/related", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	req := dicomweb.STOWRequest{StudyInstanceUID: study}
+	mr := multipart.NewReader(r.Body, params["boundary"])
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		data, err := ioutil.ReadAll(p)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		req.Parts = append(req.Parts, data)
+	}
+
+	errs, err := h.storage.Store(r.Context(), req)
+	if err != nil {
+		storageError(w, r, err)
+		return
+	}
+
+	referenced := []*dicom.Dataset{}
+	failed := []*dicom.Dataset{}
+	for i, data := range req.Parts {
+		var err error
+		if i < len(errs) {
+			err = errs[i]
+		}
+		ds, perr := dicom.Parse(data)
+		if err == nil && perr != nil {
+			err = &StoreError{Reason: FailureCannotUnderstand, Err: perr}
+		}
+		if err != nil {
+			reason := uint16(FailureProcessing)
+			if e, ok := err.(*StoreError); ok {
+				reason = e.Reason
+			}
+			failed = append(failed, storeResult(ds, reason, ""))
+			continue
+		}
+		url := h.retrieveURL(r, ds.String(dicom.StudyInstanceUID), ds.String(dicom.SeriesInstanceUID), ds.String(dicom.SOPInstanceUID))
+		referenced = append(referenced, storeResult(ds, 0, url))
+	}
+
+	resp := &dicom.Dataset{}
+	if study != "" {
+		resp.Set(dicom.NewString(0x00081190, "UR", h.retrieveURL(r, study, "", "")))
+	}
+	if len(failed) > 0 {
+		resp.Set(dicom.NewSequence(0x00081198, failed...))
+	}
+	if len(referenced) > 0 {
+		resp.Set(dicom.NewSequence(0x00081199, referenced...))
+	}
+
+	status := http.StatusOK
+	if len(failed) > 0 {
+		status = http.StatusAccepted
+		if len(referenced) == 0 {
+			status = http.StatusConflict
+		}
+	}
+	w.Header().Set("Content-Type", "application/dicom+json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// storeResult creates an item of the ReferencedSOPSequence, or of the
+// FailedSOPSequence when reason is not zero.
+func storeResult(ds *dicom.Dataset, reason uint16, url string) *dicom.Dataset {
+	item := &dicom.Dataset{}
+	if ds != nil {
+		item.Set(dicom.NewString(0x00081150, "UI", ds.String(dicom.SOPClassUID)))
+		item.Set(dicom.NewString(0x00081155, "UI", ds.String(dicom.SOPInstanceUID)))
+	}
+	if reason != 0 {
+		item.Set(dicom.NewUint16(0x00081197, reason))
+	}
+	if url != "" {
+		item.Set(dicom.NewString(0x00081190, "UR", url))
+	}
+	return item
+}
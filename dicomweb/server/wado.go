@@ -0,0 +1,162 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+
+	"github.com/toastcheng/dicomweb-go/dicomweb"
+	"github.com/toastcheng/dicomweb-go/dicomweb/dicom"
+)
+
+// rawRequest returns the request retrieving the instances of the study, series
+// or instance.
+func rawRequest(study, series, sop string) dicomweb.WADORequest {
+	req := dicomweb.WADORequest{
+		Type:              dicomweb.StudyRaw,
+		StudyInstanceUID:  study,
+		SeriesInstanceUID: series,
+		SOPInstanceUID:    sop,
+	}
+	if sop != "" {
+		req.Type = dicomweb.InstanceRaw
+	} else if series != "" {
+		req.Type = dicomweb.SeriesRaw
+	}
+	return req
+}
+
+func (h *Handler) retrieve(w http.ResponseWriter, r *http.Request, study, series, sop, suffix string) {
+	switch suffix {
+	case "", "metadata":
+	case "rendered":
+		http.Error(w, "rendered resources are not supported", http.StatusNotAcceptable)
+		return
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	instances, err := h.storage.Retrieve(r.Context(), rawRequest(study, series, sop))
+	if err != nil {
+		storageError(w, r, err)
+		return
+	}
+	if len(instances) == 0 {
+		http.NotFound(w, r)
+		return
+	}
+
+	if suffix == "" {
+		writeMultipart(w, "application/dicom", instances)
+		return
+	}
+	result := []map[string]dicom.Attribute{}
+	for _, data := range instances {
+		ds, err := dicom.Parse(data)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		result = append(result, ds.JSON())
+	}
+	w.Header().Set("Content-Type", "application/dicom+json")
+	json.NewEncoder(w).Encode(result)
+}
+
+func (h *Handler) frames(w http.ResponseWriter, r *http.Request, study, series, sop, list string) {
+	instances, err := h.storage.Retrieve(r.Context(), rawRequest(study, series, sop))
+	if err != nil {
+		storageError(w, r, err)
+		return
+	}
+	if len(instances) == 0 {
+		http.NotFound(w, r)
+		return
+	}
+	ds, err := dicom.Parse(instances[0])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	frames, err := ExtractFrames(ds)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	parts := [][]byte{}
+	for _, v := range strings.Split(list, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil || n < 1 || n > len(frames) {
+			http.Error(w, fmt.Sprintf("frame %q does not exist", v), http.StatusNotFound)
+			return
+		}
+		parts = append(parts, frames[n-1])
+	}
+	writeMultipart(w, "application/octet-stream", parts)
+}
+
+// ExtractFrames splits the pixel data of the data set into frames. Encapsulated
+// pixel data is expected to hold one fragment per frame.
+func ExtractFrames(ds *dicom.Dataset) ([][]byte, error) {
+	pixelData := ds.Get(dicom.PixelData)
+	if pixelData == nil {
+		return nil, fmt.Errorf("instance has no pixel data")
+	}
+	n := ds.Int(dicom.NumberOfFrames)
+	if n == 0 {
+		n = 1
+	}
+
+	if pixelData.Fragments != nil {
+		if len(pixelData.Fragments) == 0 {
+			return nil, fmt.Errorf("encapsulated pixel data has no basic offset table")
+		}
+		fragments := pixelData.Fragments[1:]
+		if len(fragments) == n {
+			return fragments, nil
+		}
+		if n == 1 {
+			frame := []byte{}
+			for _, f := range fragments {
+				frame = append(frame, f...)
+			}
+			return [][]byte{frame}, nil
+		}
+		return nil, fmt.Errorf("%d fragments for %d frames", len(fragments), n)
+	}
+
+	samples := ds.Int(dicom.SamplesPerPixel)
+	if samples == 0 {
+		samples = 1
+	}
+	size := ds.Int(dicom.Rows) * ds.Int(dicom.Columns) * samples * ds.Int(dicom.BitsAllocated) / 8
+	if size == 0 || size*n > len(pixelData.Value) {
+		return nil, fmt.Errorf("pixel data does not hold %d frames", n)
+	}
+	frames := [][]byte{}
+	for i := 0; i < n; i++ {
+		frames = append(frames, pixelData.Value[i*size:(i+1)*size])
+	}
+	return frames, nil
+}
+
+func writeMultipart(w http.ResponseWriter, contentType string, parts [][]byte) {
+	mw := multipart.NewWriter(w)
+	w.Header().Set("Content-Type", fmt.Sprintf("multipart/related; type=%q; boundary=%s", contentType, mw.Boundary()))
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", contentType)
+	for _, p := range parts {
+		pw, err := mw.CreatePart(header)
+		if err != nil {
+			return
+		}
+		pw.Write(p)
+	}
+	mw.Close()
+}
@@ -4,4 +4,6 @@ package dicomweb
 type STOWRequest struct {
 	StudyInstanceUID string
 	Parts            [][]byte
+	// Progress is called as the request is sent, if set
+	Progress ProgressFunc
 }
@@ -0,0 +1,144 @@
+package dicomweb
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"golang.org/x/crypto/pkcs12"
+)
+
+// TLSOption specifies the TLS configuration used to connect to the DICOMweb server.
+// CA certificates and client certificates can be given either as file paths or
+// as in-memory data; when both are set, the in-memory data wins.
+type TLSOption struct {
+	// CAFile path to a PEM encoded CA bundle used to verify the server.
+	CAFile string
+	// CAPEM PEM encoded CA bundle used to verify the server.
+	CAPEM []byte
+	// CertFile path to a PEM encoded client certificate.
+	CertFile string
+	// KeyFile path to the PEM encoded private key of CertFile.
+	KeyFile string
+	// CertPEM PEM encoded client certificate.
+	CertPEM []byte
+	// KeyPEM PEM encoded private key of CertPEM.
+	KeyPEM []byte
+	// PKCS12File path to a PKCS#12 (.p12/.pfx) bundle holding the client certificate and key.
+	PKCS12File string
+	// PKCS12Data PKCS#12 bundle holding the client certificate and key.
+	PKCS12Data []byte
+	// PKCS12Password password of the PKCS#12 bundle.
+	PKCS12Password string
+	// MinVersion minimum TLS version, e.g. tls.VersionTLS12. Uses the Go default otherwise.
+	MinVersion uint16
+	// ServerName overrides the host name used to verify the server certificate.
+	ServerName string
+	// InsecureSkipVerify skips verifying the server, do not use it in production.
+	InsecureSkipVerify bool
+}
+
+// Config builds the tls.Config described by the option.
+func (o TLSOption) Config() (*tls.Config, error) {
+	cfg := &tls.Config{
+		MinVersion:         o.MinVersion,
+		ServerName:         o.ServerName,
+		InsecureSkipVerify: o.InsecureSkipVerify,
+	}
+
+	caPEM := o.CAPEM
+	if caPEM == nil && o.CAFile != "" {
+		b, err := ioutil.ReadFile(o.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %v", err)
+		}
+		caPEM = b
+	}
+	if caPEM != nil {
+		pool := x509.NewCertPool()
+		if ok := pool.AppendCertsFromPEM(caPEM); !ok {
+			return nil, errors.New("failed to parse CA certificates: no PEM certificate found")
+		}
+		cfg.RootCAs = pool
+	}
+
+	certs, err := o.clientCertificates()
+	if err != nil {
+		return nil, err
+	}
+	cfg.Certificates = certs
+
+	return cfg, nil
+}
+
+func (o TLSOption) clientCertificates() ([]tls.Certificate, error) {
+	certs := []tls.Certificate{}
+
+	certPEM, keyPEM := o.CertPEM, o.KeyPEM
+	if certPEM == nil && o.CertFile != "" {
+		b, err := ioutil.ReadFile(o.CertFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client certificate: %v", err)
+		}
+		certPEM = b
+	}
+	if keyPEM == nil && o.KeyFile != "" {
+		b, err := ioutil.ReadFile(o.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client key: %v", err)
+		}
+		keyPEM = b
+	}
+	if (certPEM == nil) != (keyPEM == nil) {
+		return nil, errors.New("client certificate and key must be given together")
+	}
+	if certPEM != nil {
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client key pair: %v", err)
+		}
+		certs = append(certs, cert)
+	}
+
+	p12 := o.PKCS12Data
+	if p12 == nil && o.PKCS12File != "" {
+		b, err := ioutil.ReadFile(o.PKCS12File)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read PKCS#12 file: %v", err)
+		}
+		p12 = b
+	}
+	if p12 != nil {
+		key, leaf, err := pkcs12.Decode(p12, o.PKCS12Password)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode PKCS#12 bundle: %v", err)
+		}
+		certs = append(certs, tls.Certificate{
+			Certificate: [][]byte{leaf.Raw},
+			PrivateKey:  key,
+			Leaf:        leaf,
+		})
+	}
+
+	return certs, nil
+}
+
+// WithTLS configures the client to connect with the given TLS option, such as
+// a private CA or client certificates for mutual TLS.
+func (c *Client) WithTLS(option TLSOption) (*Client, error) {
+	cfg, err := option.Config()
+	if err != nil {
+		return nil, err
+	}
+	tr := &http.Transport{
+		Proxy:           http.ProxyFromEnvironment,
+		TLSClientConfig: cfg,
+	}
+	client := *c.httpClient
+	client.Transport = tr
+	c.httpClient = &client
+	return c, nil
+}
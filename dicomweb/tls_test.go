@@ -0,0 +1,194 @@
+package dicomweb
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testCert struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+func newTestCert(t *testing.T, template *x509.Certificate, parent *testCert) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, signerKey := template, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+	return &testCert{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+func newTestPKI(t *testing.T) (ca, server, client *testCert) {
+	now := time.Now()
+	ca = newTestCert(t, &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, nil)
+	server = newTestCert(t, &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "pacs.hospital.local"},
+		DNSNames:     []string{"pacs.hospital.local"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, ca)
+	client = newTestCert(t, &x509.Certificate{
+		SerialNumber: big.NewInt(3),
+		Subject:      pkix.Name{CommonName: "dicomweb-go"},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, ca)
+	return ca, server, client
+}
+
+func newMutualTLSServer(t *testing.T, ca, server *testCert) *httptest.Server {
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.PeerCertificates) == 0 {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		assert.Equal(t, "dicomweb-go", r.TLS.PeerCertificates[0].Subject.CommonName)
+	}))
+	serverCert, err := tls.X509KeyPair(server.certPEM, server.keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	ts.TLS = &tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}
+	ts.StartTLS()
+	return ts
+}
+
+func TestClientWithMutualTLS(t *testing.T) {
+	ca, server, client := newTestPKI(t)
+	ts := newMutualTLSServer(t, ca, server)
+	defer ts.Close()
+
+	c, err := NewClient(ClientOption{
+		QIDOEndpoint: ts.URL,
+	}).WithTLS(TLSOption{
+		CAPEM:      ca.certPEM,
+		CertPEM:    client.certPEM,
+		KeyPEM:     client.keyPEM,
+		MinVersion: tls.VersionTLS12,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = c.Query(QIDORequest{Type: Study})
+	assert.NoError(t, err)
+}
+
+func TestClientWithTLSServerName(t *testing.T) {
+	ca, server, client := newTestPKI(t)
+	ts := newMutualTLSServer(t, ca, server)
+	defer ts.Close()
+
+	c, err := NewClient(ClientOption{
+		QIDOEndpoint: ts.URL,
+	}).WithTLS(TLSOption{
+		CAPEM:      ca.certPEM,
+		CertPEM:    client.certPEM,
+		KeyPEM:     client.keyPEM,
+		ServerName: "pacs.hospital.local",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "pacs.hospital.local", c.httpClient.Transport.(*http.Transport).TLSClientConfig.ServerName)
+
+	_, err = c.Query(QIDORequest{Type: Study})
+	assert.NoError(t, err)
+}
+
+func TestClientWithTLSWithoutClientCertificate(t *testing.T) {
+	ca, server, _ := newTestPKI(t)
+	ts := newMutualTLSServer(t, ca, server)
+	defer ts.Close()
+
+	c, err := NewClient(ClientOption{
+		QIDOEndpoint: ts.URL,
+	}).WithTLS(TLSOption{
+		CAPEM: ca.certPEM,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = c.Query(QIDORequest{Type: Study})
+	assert.Error(t, err)
+}
+
+func TestTLSOptionInvalid(t *testing.T) {
+	_, server, client := newTestPKI(t)
+
+	_, err := TLSOption{CAPEM: []byte("not a certificate")}.Config()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "failed to parse CA certificates")
+	}
+
+	_, err = TLSOption{CertPEM: client.certPEM}.Config()
+	if assert.Error(t, err) {
+		assert.Equal(t, "client certificate and key must be given together", err.Error())
+	}
+
+	_, err = TLSOption{CertPEM: client.certPEM, KeyPEM: server.keyPEM}.Config()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "failed to load client key pair")
+	}
+
+	_, err = TLSOption{CAFile: "/nonexistent/ca.pem"}.Config()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "failed to read CA file")
+	}
+
+	_, err = TLSOption{PKCS12Data: []byte("garbage")}.Config()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "failed to decode PKCS#12 bundle")
+	}
+}
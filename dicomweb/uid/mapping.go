@@ -0,0 +1,97 @@
+package uid
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Mapping maps UIDs to the new UIDs replacing them, e.g. to remap the UIDs of
+// a study consistently across its instances. It can be saved and loaded, so
+// that the same UIDs are remapped the same way over several runs. It is safe
+// for concurrent use.
+type Mapping struct {
+	mu   sync.Mutex
+	uids map[string]string
+	gen  *Generator
+	key  []byte
+}
+
+// NewMapping creates a mapping whose new UIDs are generated by g, under the
+// 2.25 root if g is nil. They are derived from the old UIDs with the key if
+// key is not empty, so that the mapping is deterministic, and random otherwise.
+func NewMapping(g *Generator, key []byte) *Mapping {
+	if g == nil {
+		g = &Generator{root: UUIDRoot}
+	}
+	return &Mapping{uids: map[string]string{}, gen: g, key: key}
+}
+
+// Map returns the UID replacing the given one, generating it on first use.
+func (m *Mapping) Map(uid string) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if mapped, ok := m.uids[uid]; ok {
+		return mapped
+	}
+	var mapped string
+	if len(m.key) > 0 {
+		mapped = m.gen.Derive(m.key, uid)
+	} else {
+		mapped = m.gen.New()
+	}
+	m.uids[uid] = mapped
+	return mapped
+}
+
+// Lookup returns the UID replacing the given one, if mapped.
+func (m *Mapping) Lookup(uid string) (string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	mapped, ok := m.uids[uid]
+	return mapped, ok
+}
+
+// Len returns the number of UIDs mapped.
+func (m *Mapping) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.uids)
+}
+
+// UIDs returns the UIDs mapped, to the UID replacing them.
+func (m *Mapping) UIDs() map[string]string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	uids := make(map[string]string, len(m.uids))
+	for k, v := range m.uids {
+		uids[k] = v
+	}
+	return uids
+}
+
+// Save writes the mapping as a JSON object from the old UIDs to the new ones.
+func (m *Mapping) Save(w io.Writer) error {
+	return json.NewEncoder(w).Encode(m.UIDs())
+}
+
+// Load reads a mapping written by Save, adding its UIDs to the mapping. The
+// UIDs already mapped are replaced.
+func (m *Mapping) Load(r io.Reader) error {
+	uids := map[string]string{}
+	if err := json.NewDecoder(r).Decode(&uids); err != nil {
+		return fmt.Errorf("failed to read UID mapping: %v", err)
+	}
+	for old, mapped := range uids {
+		if err := Check(mapped); err != nil {
+			return fmt.Errorf("invalid UID %q mapped from %q: %v", mapped, old, err)
+		}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for old, mapped := range uids {
+		m.uids[old] = mapped
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+// Package uid generates, validates and remaps DICOM unique identifiers, see
+// PS3.5 Section 9 and Annex B.
+package uid
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// MaxLength maximum length of a UID.
+const MaxLength = 64
+
+// UUIDRoot root of the UIDs derived from a UUID, see PS3.5 B.2.
+const UUIDRoot = "2.25"
+
+// minSuffixDigits minimum number of digits of the component generated under a
+// root, about 100 bits, so that the generated UIDs do not collide.
+const minSuffixDigits = 30
+
+// Check returns an error describing why the UID is not valid, or nil. A valid
+// UID is at most 64 characters long and made of numeric components separated
+// by dots, none of which has a leading zero.
+func Check(uid string) error {
+	if uid == "" {
+		return errors.New("empty UID")
+	}
+	if len(uid) > MaxLength {
+		return fmt.Errorf("UID longer than %d characters", MaxLength)
+	}
+	for _, c := range strings.Split(uid, ".") {
+		if c == "" {
+			return errors.New("empty UID component")
+		}
+		for _, r := range c {
+			if r < '0' || r > '9' {
+				return fmt.Errorf("invalid character %q in UID", r)
+			}
+		}
+		if len(c) > 1 && c[0] == '0' {
+			return fmt.Errorf("UID component %s has a leading zero", c)
+		}
+	}
+	return nil
+}
+
+// Valid tells if the UID is valid, see Check.
+func Valid(uid string) bool {
+	return Check(uid) == nil
+}
+
+// New generates a UID under the 2.25 root from a random UUID.
+func New() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	// version 4, variant 1 UUID.
+	b[6] = b[6]&0x0F | 0x40
+	b[8] = b[8]&0x3F | 0x80
+	return UUIDRoot + "." + new(big.Int).SetBytes(b).String()
+}
+
+// Generator generates UIDs under a root.
+type Generator struct {
+	root string
+}
+
+// NewGenerator creates a generator of UIDs under the root, such as the root
+// registered by an organization. The 2.25 UUID form is used if root is empty.
+// The root must leave room for a component of at least 30 digits, so it is at
+// most 33 characters long.
+func NewGenerator(root string) (*Generator, error) {
+	if root == "" {
+		root = UUIDRoot
+	}
+	if err := Check(root); err != nil {
+		return nil, fmt.Errorf("invalid root %q: %v", root, err)
+	}
+	if len(root) > MaxLength-1-minSuffixDigits {
+		return nil, fmt.Errorf("root %q is too long, it must leave room for %d digits", root, minSuffixDigits)
+	}
+	return &Generator{root: root}, nil
+}
+
+// Root returns the root of the generated UIDs.
+func (g *Generator) Root() string {
+	return g.root
+}
+
+// New generates a random UID.
+func (g *Generator) New() string {
+	if g.root == UUIDRoot {
+		return New()
+	}
+	b := make([]byte, 16)
+	rand.Read(b)
+	return g.uid(b)
+}
+
+// Derive derives a UID from the given one and the key, so that the same UID
+// and key always give the same UID, while the UID cannot be recovered without
+// the key.
+func (g *Generator) Derive(key []byte, uid string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(uid))
+	b := mac.Sum(nil)[:16]
+	if g.root == UUIDRoot {
+		// version 8, variant 1 UUID, see RFC 9562.
+		b[6] = b[6]&0x0F | 0x80
+		b[8] = b[8]&0x3F | 0x80
+	}
+	return g.uid(b)
+}
+
+// uid returns the UID of the root and the number, truncated to MaxLength. The
+// truncated number keeps at least minSuffixDigits digits.
+func (g *Generator) uid(b []byte) string {
+	s := g.root + "." + new(big.Int).SetBytes(b).String()
+	if len(s) > MaxLength {
+		s = s[:MaxLength]
+	}
+	return s
+}
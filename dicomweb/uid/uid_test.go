@@ -0,0 +1,107 @@
+package uid
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheck(t *testing.T) {
+	for _, tc := range []struct {
+		uid string
+		err string
+	}{
+		{"1.2.840.10008.1.2.1", ""},
+		{"0.1", ""},
+		{"", "empty UID"},
+		{"1..2", "empty UID component"},
+		{".1.2", "empty UID component"},
+		{"1.2.03", "UID component 03 has a leading zero"},
+		{"1.2.a", "invalid character 'a' in UID"},
+		{"1.2/3", "invalid character '/' in UID"},
+		{"1." + strings.Repeat("1", 63), "UID longer than 64 characters"},
+	} {
+		err := Check(tc.uid)
+		if tc.err == "" {
+			assert.NoError(t, err, tc.uid)
+			assert.True(t, Valid(tc.uid))
+		} else {
+			assert.EqualError(t, err, tc.err, tc.uid)
+			assert.False(t, Valid(tc.uid))
+		}
+	}
+}
+
+func TestGenerator(t *testing.T) {
+	assert.Regexp(t, `^2\.25\.[1-9][0-9]*$`, New())
+	assert.NotEqual(t, New(), New())
+
+	g, err := NewGenerator("1.2.826.0.1.3680043.10.999")
+	assert.NoError(t, err)
+	assert.Equal(t, "1.2.826.0.1.3680043.10.999", g.Root())
+	for i := 0; i < 100; i++ {
+		u := g.New()
+		assert.True(t, strings.HasPrefix(u, "1.2.826.0.1.3680043.10.999."))
+		assert.NoError(t, Check(u))
+	}
+
+	key := []byte("secret")
+	assert.Equal(t, g.Derive(key, "1.2.3"), g.Derive(key, "1.2.3"))
+	assert.NotEqual(t, g.Derive(key, "1.2.3"), g.Derive(key, "1.2.4"))
+	assert.NotEqual(t, g.Derive(key, "1.2.3"), g.Derive([]byte("other"), "1.2.3"))
+	assert.NoError(t, Check(g.Derive(key, "1.2.3")))
+
+	g, err = NewGenerator("")
+	assert.NoError(t, err)
+	assert.Equal(t, UUIDRoot, g.Root())
+	assert.NoError(t, Check(g.Derive(key, "1.2.3")))
+
+	_, err = NewGenerator("1.2.x")
+	assert.EqualError(t, err, `invalid root "1.2.x": invalid character 'x' in UID`)
+	_, err = NewGenerator("1." + strings.Repeat("2", 60))
+	assert.Error(t, err)
+
+	// the longest root still leaves room for 30 digits.
+	root := "1." + strings.Repeat("2", 31)
+	g, err = NewGenerator(root)
+	if assert.NoError(t, err) {
+		u := g.Derive(key, "1.2.3")
+		assert.Len(t, u, MaxLength)
+		assert.NoError(t, Check(u))
+		assert.NotEqual(t, u, g.Derive(key, "1.2.4"))
+	}
+	_, err = NewGenerator(root + "2")
+	assert.EqualError(t, err, `root "`+root+`2" is too long, it must leave room for 30 digits`)
+}
+
+func TestMapping(t *testing.T) {
+	m := NewMapping(nil, nil)
+	a := m.Map("1.2.3")
+	assert.Equal(t, a, m.Map("1.2.3"))
+	assert.NotEqual(t, a, m.Map("1.2.4"))
+	assert.Equal(t, 2, m.Len())
+	mapped, ok := m.Lookup("1.2.3")
+	assert.True(t, ok)
+	assert.Equal(t, a, mapped)
+	_, ok = m.Lookup("1.2.5")
+	assert.False(t, ok)
+
+	buf := &bytes.Buffer{}
+	assert.NoError(t, m.Save(buf))
+	loaded := NewMapping(nil, nil)
+	assert.NoError(t, loaded.Load(buf))
+	assert.Equal(t, m.UIDs(), loaded.UIDs())
+	assert.Equal(t, a, loaded.Map("1.2.3"))
+
+	assert.EqualError(t, loaded.Load(strings.NewReader(`{"1.2.3": "x"}`)),
+		`invalid UID "x" mapped from "1.2.3": invalid character 'x' in UID`)
+	assert.Error(t, loaded.Load(strings.NewReader(`[]`)))
+
+	// deterministic mappings give the same UIDs without being saved.
+	g, _ := NewGenerator("1.2.999")
+	m1, m2 := NewMapping(g, []byte("key")), NewMapping(g, []byte("key"))
+	assert.Equal(t, m1.Map("1.2.3"), m2.Map("1.2.3"))
+	assert.True(t, strings.HasPrefix(m1.Map("1.2.3"), "1.2.999."))
+}
@@ -0,0 +1,283 @@
+package dicomweb
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/toastcheng/dicomweb-go/dicomweb/uid"
+)
+
+// UPSType defines the worklist transaction of a UPS-RS request.
+type UPSType int
+
+const (
+	// CreateWorkitem creates a SCHEDULED workitem.
+	CreateWorkitem UPSType = iota + 1
+	// SearchWorkitems searches the workitems matching the filters.
+	SearchWorkitems
+	// RetrieveWorkitem retrieves a workitem.
+	RetrieveWorkitem
+	// UpdateWorkitem updates the attributes of a workitem.
+	UpdateWorkitem
+	// ChangeWorkitemState changes the state of a workitem.
+	ChangeWorkitemState
+	// RequestWorkitemCancellation requests the cancellation of a workitem.
+	RequestWorkitemCancellation
+	// SubscribeWorklist subscribes an AE to the events of a workitem, or of the worklist.
+	SubscribeWorklist
+	// UnsubscribeWorklist unsubscribes an AE from the events of a workitem, or of the worklist.
+	UnsubscribeWorklist
+	// SuspendWorklistSubscription suspends the global subscription of an AE,
+	// keeping its subscriptions to the existing workitems.
+	SuspendWorklistSubscription
+)
+
+// String returns the name of the worklist transaction.
+func (t UPSType) String() string {
+	switch t {
+	case CreateWorkitem:
+		return "CreateWorkitem"
+	case SearchWorkitems:
+		return "SearchWorkitems"
+	case RetrieveWorkitem:
+		return "RetrieveWorkitem"
+	case UpdateWorkitem:
+		return "UpdateWorkitem"
+	case ChangeWorkitemState:
+		return "ChangeWorkitemState"
+	case RequestWorkitemCancellation:
+		return "RequestWorkitemCancellation"
+	case SubscribeWorklist:
+		return "SubscribeWorklist"
+	case UnsubscribeWorklist:
+		return "UnsubscribeWorklist"
+	case SuspendWorklistSubscription:
+		return "SuspendWorklistSubscription"
+	}
+	return "unknown"
+}
+
+// Well-known UIDs subscribing to the whole worklist instead of a workitem.
+const (
+	// GlobalSubscriptionUID subscribes to all the workitems.
+	GlobalSubscriptionUID = "1.2.840.10008.5.1.4.34.5"
+	// FilteredGlobalSubscriptionUID subscribes to the workitems matching the filters.
+	FilteredGlobalSubscriptionUID = "1.2.840.10008.5.1.4.34.5.1"
+)
+
+// UPSRequest defines the request of a UPS-RS worklist transaction.
+type UPSRequest struct {
+	// Type worklist transaction of the request.
+	Type UPSType
+	// WorkitemUID UID of the workitem. Generated on CreateWorkitem if empty,
+	// and the whole worklist is subscribed to on SubscribeWorklist.
+	WorkitemUID string
+	// Workitem workitem to create, or attributes to update.
+	Workitem *Workitem
+	// State state to change the workitem to, on ChangeWorkitemState.
+	State ProcedureStepState
+	// TransactionUID transaction the workitem is performed in, returned when
+	// it is changed to IN PROGRESS and required to update, complete or cancel
+	// it afterwards. Generated if empty when changing to IN PROGRESS.
+	TransactionUID string
+	// Reason reason for the cancellation, on RequestWorkitemCancellation.
+	Reason string
+	// Filters attributes to match on SearchWorkitems, keyed by tag or keyword,
+	// or to filter a global subscription on SubscribeWorklist.
+	Filters map[string]string
+	// Limit maximum number of workitems returned by SearchWorkitems.
+	Limit int
+	// Offset number of workitems skipped by SearchWorkitems.
+	Offset int
+	// AETitle AE title of the subscriber.
+	AETitle string
+	// DeletionLock keeps the completed or canceled workitems until the
+	// subscriber receives their last event, on SubscribeWorklist.
+	DeletionLock bool
+}
+
+// Worklist based on UPS-RS, performs a worklist transaction. It returns the
+// workitems found by SearchWorkitems, the workitem retrieved, and the workitem
+// created by CreateWorkitem or changed by ChangeWorkitemState, with its UID,
+// state and transaction UID. It returns no workitem otherwise.
+func (c *Client) Worklist(req UPSRequest) ([]Workitem, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	switch req.Type {
+	case CreateWorkitem:
+		return c.createWorkitem(req)
+	case SearchWorkitems:
+		q := url.Values{}
+		for k, v := range req.Filters {
+			q.Add(k, v)
+		}
+		if req.Limit > 0 {
+			q.Set("limit", strconv.Itoa(req.Limit))
+		}
+		if req.Offset > 0 {
+			q.Set("offset", strconv.Itoa(req.Offset))
+		}
+		return c.workitems(req, "GET", "/workitems", q, nil)
+	case RetrieveWorkitem:
+		return c.workitems(req, "GET", workitemPath(req.WorkitemUID), nil, nil)
+	case UpdateWorkitem:
+		q := url.Values{}
+		if req.TransactionUID != "" {
+			q.Set("transaction-uid", req.TransactionUID)
+		}
+		_, err := c.workitems(req, "POST", workitemPath(req.WorkitemUID), q, req.Workitem)
+		return nil, err
+	case ChangeWorkitemState:
+		return c.changeWorkitemState(req)
+	case RequestWorkitemCancellation:
+		w := &Workitem{}
+		if req.Reason != "" {
+			w.Attributes = map[string]Tag{
+				tagReasonForCancellation: {VR: "LT", Value: []interface{}{req.Reason}},
+			}
+		}
+		_, err := c.workitems(req, "POST", workitemPath(req.WorkitemUID)+"/cancelrequest", nil, w)
+		return nil, err
+	case SubscribeWorklist, UnsubscribeWorklist, SuspendWorklistSubscription:
+		return nil, c.subscription(req)
+	}
+	return nil, errors.New("failed to perform worklist transaction: need to specify transaction type")
+}
+
+func (c *Client) createWorkitem(req UPSRequest) ([]Workitem, error) {
+	w := *req.Workitem
+	if req.WorkitemUID != "" {
+		w.SOPInstanceUID = req.WorkitemUID
+	}
+	if w.SOPInstanceUID == "" {
+		w.SOPInstanceUID = uid.New()
+	}
+	if w.State == "" {
+		w.State = StateScheduled
+	}
+	req.WorkitemUID = w.SOPInstanceUID
+	q := url.Values{"AffectedSOPInstanceUID": {w.SOPInstanceUID}}
+	if _, err := c.workitems(req, "POST", "/workitems", q, w); err != nil {
+		return nil, err
+	}
+	return []Workitem{w}, nil
+}
+
+// changeWorkitemState checks that the workitem can change to the requested
+// state before changing it.
+func (c *Client) changeWorkitemState(req UPSRequest) ([]Workitem, error) {
+	current, err := c.workitems(req, "GET", workitemPath(req.WorkitemUID), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(current) == 0 {
+		return nil, fmt.Errorf("workitem %s not found", req.WorkitemUID)
+	}
+	w := current[0]
+	if err := w.State.Transition(req.State); err != nil {
+		return nil, err
+	}
+
+	transactionUID := req.TransactionUID
+	if transactionUID == "" {
+		transactionUID = uid.New()
+	}
+	body := &Workitem{Attributes: map[string]Tag{
+		tagProcedureStepState: {VR: "CS", Value: []interface{}{string(req.State)}},
+		tagTransactionUID:     {VR: "UI", Value: []interface{}{transactionUID}},
+	}}
+	if _, err := c.workitems(req, "PUT", workitemPath(req.WorkitemUID)+"/state", nil, body); err != nil {
+		return nil, err
+	}
+	w.State = req.State
+	w.TransactionUID = transactionUID
+	return []Workitem{w}, nil
+}
+
+// subscription subscribes, unsubscribes or suspends the subscription of the AE.
+func (c *Client) subscription(req UPSRequest) error {
+	target := req.WorkitemUID
+	q := url.Values{}
+	if target == "" {
+		target = GlobalSubscriptionUID
+		if len(req.Filters) > 0 && req.Type == SubscribeWorklist {
+			target = FilteredGlobalSubscriptionUID
+			for k, v := range req.Filters {
+				q.Add(k, v)
+			}
+		}
+	}
+	path := workitemPath(target) + "/subscribers/" + url.PathEscape(req.AETitle)
+
+	method := "POST"
+	switch req.Type {
+	case SubscribeWorklist:
+		if req.DeletionLock {
+			q.Set("deletionlock", "true")
+		}
+	case UnsubscribeWorklist:
+		method = "DELETE"
+	case SuspendWorklistSubscription:
+		path += "/suspend"
+	}
+	_, err := c.workitems(req, method, path, q, nil)
+	return err
+}
+
+// workitems sends a request to the worklist endpoint and decodes the
+// workitems of the response, if any.
+func (c *Client) workitems(req UPSRequest, method, path string, q url.Values, body interface{}) ([]Workitem, error) {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(b)
+	}
+	r, err := http.NewRequest(method, c.upsEndpoint+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	r.URL.RawQuery = q.Encode()
+	r.Header.Set("Accept", "application/dicom+json")
+	if body != nil {
+		r.Header.Set("Content-Type", "application/dicom+json")
+	}
+
+	resp, err := c.do(&Operation{
+		Service:        UPSService,
+		UPSType:        req.Type,
+		SOPInstanceUID: req.WorkitemUID,
+	}, r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return nil, statusError(resp)
+	}
+	if method != "GET" || resp.StatusCode == http.StatusNoContent {
+		return nil, nil
+	}
+
+	result := []Workitem{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to decode workitems: %v", err)
+	}
+	return result, nil
+}
+
+// workitemPath returns the path of the workitem.
+func workitemPath(workitemUID string) string {
+	return "/workitems/" + url.PathEscape(workitemUID)
+}
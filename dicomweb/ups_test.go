@@ -0,0 +1,203 @@
+package dicomweb
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newUPSTestServer serves a minimal worklist, recording the subscriptions.
+func newUPSTestServer(t *testing.T) (*httptest.Server, map[string]map[string]Tag, *[]string) {
+	var mu sync.Mutex
+	workitems := map[string]map[string]Tag{}
+	subscriptions := []string{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		path := strings.TrimPrefix(r.URL.Path, "/workitems")
+		segments := strings.Split(strings.Trim(path, "/"), "/")
+		if r.ContentLength > 0 {
+			assert.Equal(t, "application/dicom+json", r.Header.Get("Content-Type"))
+		}
+		body := map[string]Tag{}
+		json.NewDecoder(r.Body).Decode(&body)
+
+		switch {
+		case path == "" && r.Method == "POST":
+			workitems[r.URL.Query().Get("AffectedSOPInstanceUID")] = body
+			w.WriteHeader(http.StatusCreated)
+		case path == "" && r.Method == "GET":
+			result := []map[string]Tag{}
+			for _, item := range workitems {
+				label := r.URL.Query().Get("00741204")
+				if label == "" || len(item["00741204"].Value) > 0 && item["00741204"].Value[0] == label {
+					result = append(result, item)
+				}
+			}
+			if len(result) == 0 {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			json.NewEncoder(w).Encode(result)
+		case len(segments) > 1 && segments[1] == "subscribers":
+			subscriptions = append(subscriptions, r.Method+" "+r.URL.Path+"?"+r.URL.RawQuery)
+		default:
+			item, ok := workitems[segments[0]]
+			if !ok {
+				w.Header().Set("Warning", `299 test: "workitem not found"`)
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			switch {
+			case len(segments) == 1 && r.Method == "GET":
+				json.NewEncoder(w).Encode([]map[string]Tag{item})
+			case len(segments) == 1 && r.Method == "POST":
+				for k, v := range body {
+					item[k] = v
+				}
+			case segments[1] == "state" && r.Method == "PUT":
+				item["00741000"] = body["00741000"]
+			case segments[1] == "cancelrequest" && r.Method == "POST":
+				item["00741238"] = body["00741238"]
+				w.WriteHeader(http.StatusAccepted)
+			default:
+				w.WriteHeader(http.StatusBadRequest)
+			}
+		}
+	}))
+	return ts, workitems, &subscriptions
+}
+
+func TestWorklist(t *testing.T) {
+	ts, workitems, _ := newUPSTestServer(t)
+	defer ts.Close()
+	c := NewClient(ClientOption{UPSEndpoint: ts.URL})
+
+	created, err := c.Worklist(UPSRequest{
+		Type: CreateWorkitem,
+		Workitem: &Workitem{
+			Label:       "AI lung nodule detection",
+			PatientName: "Doe^John",
+			PatientID:   "PAT-1",
+			Attributes: map[string]Tag{
+				"00741202": {VR: "LO", Value: []interface{}{"AI"}},
+				"00404018": {VR: "SQ"},
+			},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, created, 1)
+	workitemUID := created[0].SOPInstanceUID
+	assert.Regexp(t, `^2\.25\.[0-9]+$`, workitemUID)
+	assert.Equal(t, StateScheduled, created[0].State)
+	assert.Equal(t, "SCHEDULED", workitems[workitemUID]["00741000"].Value[0])
+	assert.Equal(t, "PN", workitems[workitemUID]["00100010"].VR)
+
+	found, err := c.Worklist(UPSRequest{Type: SearchWorkitems, Filters: map[string]string{"00741204": "AI lung nodule detection"}})
+	assert.NoError(t, err)
+	assert.Len(t, found, 1)
+	assert.Equal(t, "Doe^John", found[0].PatientName)
+	assert.Equal(t, "AI lung nodule detection", found[0].Label)
+	assert.Equal(t, "AI", found[0].WorklistLabel)
+	assert.Equal(t, "SQ", found[0].Attributes["00404018"].VR)
+
+	found, err = c.Worklist(UPSRequest{Type: SearchWorkitems, Filters: map[string]string{"00741204": "other"}})
+	assert.NoError(t, err)
+	assert.Empty(t, found)
+
+	_, err = c.Worklist(UPSRequest{Type: UpdateWorkitem, WorkitemUID: workitemUID, Workitem: &Workitem{Priority: "HIGH"}})
+	assert.NoError(t, err)
+
+	// a SCHEDULED workitem cannot be completed before it is started.
+	_, err = c.Worklist(UPSRequest{Type: ChangeWorkitemState, WorkitemUID: workitemUID, State: StateCompleted, TransactionUID: "1.2.3"})
+	assert.EqualError(t, err, "workitem cannot change from SCHEDULED to COMPLETED")
+
+	started, err := c.Worklist(UPSRequest{Type: ChangeWorkitemState, WorkitemUID: workitemUID, State: StateInProgress})
+	assert.NoError(t, err)
+	assert.Equal(t, StateInProgress, started[0].State)
+	assert.Equal(t, "HIGH", started[0].Priority)
+	assert.NotEmpty(t, started[0].TransactionUID)
+
+	completed, err := c.Worklist(UPSRequest{Type: ChangeWorkitemState, WorkitemUID: workitemUID, State: StateCompleted, TransactionUID: started[0].TransactionUID})
+	assert.NoError(t, err)
+	assert.Equal(t, StateCompleted, completed[0].State)
+
+	retrieved, err := c.Worklist(UPSRequest{Type: RetrieveWorkitem, WorkitemUID: workitemUID})
+	assert.NoError(t, err)
+	assert.Equal(t, StateCompleted, retrieved[0].State)
+}
+
+func TestWorklistCancelAndSubscribe(t *testing.T) {
+	ts, workitems, subscriptions := newUPSTestServer(t)
+	defer ts.Close()
+	c := NewClient(ClientOption{UPSEndpoint: ts.URL})
+
+	_, err := c.Worklist(UPSRequest{Type: CreateWorkitem, WorkitemUID: "1.2.3", Workitem: &Workitem{}})
+	assert.NoError(t, err)
+	_, err = c.Worklist(UPSRequest{Type: RequestWorkitemCancellation, WorkitemUID: "1.2.3", Reason: "patient left"})
+	assert.NoError(t, err)
+	assert.Equal(t, "patient left", workitems["1.2.3"]["00741238"].Value[0])
+
+	_, err = c.Worklist(UPSRequest{Type: RetrieveWorkitem, WorkitemUID: "1.2.4"})
+	assert.EqualError(t, err, `404 Not Found: 299 test: "workitem not found"`)
+
+	for _, req := range []UPSRequest{
+		{Type: SubscribeWorklist, WorkitemUID: "1.2.3", AETitle: "AI_ENGINE", DeletionLock: true},
+		{Type: SubscribeWorklist, AETitle: "AI_ENGINE", Filters: map[string]string{"00741204": "lung"}},
+		{Type: SuspendWorklistSubscription, AETitle: "AI_ENGINE"},
+		{Type: UnsubscribeWorklist, AETitle: "AI_ENGINE"},
+	} {
+		_, err := c.Worklist(req)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, []string{
+		"POST /workitems/1.2.3/subscribers/AI_ENGINE?deletionlock=true",
+		"POST /workitems/1.2.840.10008.5.1.4.34.5.1/subscribers/AI_ENGINE?00741204=lung",
+		"POST /workitems/1.2.840.10008.5.1.4.34.5/subscribers/AI_ENGINE/suspend?",
+		"DELETE /workitems/1.2.840.10008.5.1.4.34.5/subscribers/AI_ENGINE?",
+	}, *subscriptions)
+}
+
+func TestProcedureStepStateTransition(t *testing.T) {
+	for _, tc := range []struct {
+		from, to ProcedureStepState
+		ok       bool
+	}{
+		{StateScheduled, StateInProgress, true},
+		{StateInProgress, StateCompleted, true},
+		{StateInProgress, StateCanceled, true},
+		{StateScheduled, StateCompleted, false},
+		{StateScheduled, StateCanceled, false},
+		{StateCompleted, StateInProgress, false},
+		{StateCanceled, StateCompleted, false},
+		{StateInProgress, StateInProgress, false},
+	} {
+		err := tc.from.Transition(tc.to)
+		assert.Equal(t, tc.ok, err == nil, "%s to %s", tc.from, tc.to)
+	}
+}
+
+func TestUPSRequestValidate(t *testing.T) {
+	assert.NoError(t, UPSRequest{Type: SearchWorkitems}.Validate())
+	assert.NoError(t, UPSRequest{Type: ChangeWorkitemState, WorkitemUID: "1.2.3", State: StateInProgress}.Validate())
+
+	err := UPSRequest{Type: ChangeWorkitemState, WorkitemUID: "1.2.x", State: StateCompleted}.Validate()
+	assert.EqualError(t, err, `invalid WorkitemUID "1.2.x": invalid character 'x' in UID; invalid TransactionUID: required to change to COMPLETED`)
+
+	err = UPSRequest{Type: CreateWorkitem, Workitem: &Workitem{State: StateInProgress}}.Validate()
+	assert.EqualError(t, err, `invalid Workitem.State "IN PROGRESS": a workitem is created SCHEDULED`)
+
+	err = UPSRequest{Type: SubscribeWorklist, AETitle: "AN_AE_TITLE_TOO_LONG"}.Validate()
+	assert.EqualError(t, err, `invalid AETitle "AN_AE_TITLE_TOO_LONG": longer than 16 characters`)
+
+	err = UPSRequest{Type: RetrieveWorkitem}.Validate()
+	assert.EqualError(t, err, `invalid WorkitemUID: required by RetrieveWorkitem`)
+
+	err = UPSRequest{}.Validate()
+	assert.EqualError(t, err, `invalid Type "0": unknown worklist transaction`)
+}
@@ -0,0 +1,358 @@
+package dicomweb
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/toastcheng/dicomweb-go/dicomweb/uid"
+)
+
+// ValidationError is returned when a field of a request is malformed.
+type ValidationError struct {
+	// Field name of the malformed field, e.g. "StudyInstanceUID".
+	Field string
+	// Value value of the field, empty if the field is missing.
+	Value string
+	// Err reason the value is malformed.
+	Err error
+}
+
+func (e *ValidationError) Error() string {
+	if e.Value == "" {
+		return fmt.Sprintf("invalid %s: %v", e.Field, e.Err)
+	}
+	return fmt.Sprintf("invalid %s %q: %v", e.Field, e.Value, e.Err)
+}
+
+// ValidationErrors lists the rules a request violates, each one as a
+// ValidationError.
+type ValidationErrors []error
+
+func (e ValidationErrors) Error() string {
+	msgs := []string{}
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+var (
+	// a date, or a range of dates, see PS3.18 8.3.4.
+	dateRange = regexp.MustCompile(`^(\d{8})?(-(\d{8})?)?$`)
+	// a time, or a range of times.
+	timeRange = regexp.MustCompile(`^(\d{2}(\d{2}(\d{2}(\.\d{1,6})?)?)?)?(-(\d{2}(\d{2}(\d{2}(\.\d{1,6})?)?)?)?)?$`)
+)
+
+// Validate checks the request, returning the rules it violates as
+// ValidationErrors, or nil.
+func (r QIDORequest) Validate() error {
+	errs := ValidationErrors{}
+	if _, ok := r.Type.resource(); !ok {
+		errs = append(errs, &ValidationError{Field: "Type", Value: strconv.Itoa(int(r.Type)), Err: errors.New("unknown query type")})
+	}
+	errs = append(errs, r.uidErrors()...)
+	for _, f := range []struct {
+		name, value string
+		format      *regexp.Regexp
+	}{
+		{"StudyDate", r.StudyDate, dateRange},
+		{"SeriesDate", r.SeriesDate, dateRange},
+		{"InstanceCreationDate", r.InstanceCreationDate, dateRange},
+		{"StudyTime", r.StudyTime, timeRange},
+		{"SeriesTime", r.SeriesTime, timeRange},
+		{"InstanceCreationTime", r.InstanceCreationTime, timeRange},
+		{"StudyArrivalTime", r.StudyArrivalTime, timeRange},
+		{"StudyCompletionTime", r.StudyCompletionTime, timeRange},
+	} {
+		if f.value != "" && (f.value == "-" || !f.format.MatchString(f.value)) {
+			errs = append(errs, &ValidationError{Field: f.name, Value: f.value, Err: errors.New("not a value nor a range")})
+		}
+	}
+	if r.Limit < 0 {
+		errs = append(errs, &ValidationError{Field: "Limit", Value: strconv.Itoa(r.Limit), Err: errors.New("negative")})
+	}
+	if r.Offset < 0 {
+		errs = append(errs, &ValidationError{Field: "Offset", Value: strconv.Itoa(r.Offset), Err: errors.New("negative")})
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// CheckUIDs checks the syntax of the UIDs of the request. A UID filter can
+// list several UIDs separated by commas or backslashes.
+func (r QIDORequest) CheckUIDs() error {
+	if errs := r.uidErrors(); len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+func (r QIDORequest) uidErrors() []error {
+	errs := []error{}
+	for _, f := range []struct{ name, value string }{
+		{"StudyInstanceUID", r.StudyInstanceUID},
+		{"SeriesInstanceUID", r.SeriesInstanceUID},
+		{"SOPInstanceUID", r.SOPInstanceUID},
+	} {
+		for _, v := range strings.FieldsFunc(f.value, func(r rune) bool { return r == ',' || r == '\\' }) {
+			if err := checkUID(f.name, v); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errs
+}
+
+// wadoLevels number of UIDs each retrieve type requires: the study, up to the
+// series, or up to the instance.
+var wadoLevels = map[WADOType]int{
+	StudyRaw:         1,
+	StudyRendered:    1,
+	SeriesRaw:        2,
+	SeriesRendered:   2,
+	SeriesMetadata:   2,
+	InstanceRaw:      3,
+	InstanceRendered: 3,
+	InstanceMetadata: 3,
+	Frame:            3,
+}
+
+// Validate checks the request, returning the rules it violates as
+// ValidationErrors, or nil: the UIDs the type requires, and only those, are
+// valid UIDs, the frame number of a Frame request starts at 1, and the
+// RetrieveURL of a URIReference request is an absolute HTTP URL.
+func (r WADORequest) Validate() error {
+	errs := ValidationErrors{}
+	level, ok := wadoLevels[r.Type]
+	switch {
+	case r.Type == URIReference:
+		if err := checkRetrieveURL(r.RetrieveURL); err != nil {
+			errs = append(errs, err)
+		}
+	case !ok:
+		errs = append(errs, &ValidationError{Field: "Type", Value: strconv.Itoa(int(r.Type)), Err: errors.New("unknown retrieve type")})
+	default:
+		errs = append(errs, levelErrors(r.Type, level, r.StudyInstanceUID, r.SeriesInstanceUID, r.SOPInstanceUID)...)
+		if r.Type == Frame && r.FrameID < 1 {
+			errs = append(errs, &ValidationError{Field: "FrameID", Value: strconv.Itoa(r.FrameID), Err: errors.New("frame numbers start at 1")})
+		}
+	}
+	if r.Quality != 0 && (r.Quality < 1 || r.Quality > 100) {
+		errs = append(errs, &ValidationError{Field: "Quality", Value: strconv.Itoa(r.Quality), Err: errors.New("not between 1 and 100")})
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// checkRetrieveURL returns a ValidationError if the URL is not an absolute HTTP URL.
+func checkRetrieveURL(v string) error {
+	if v == "" {
+		return &ValidationError{Field: "RetrieveURL", Err: fmt.Errorf("required by %s", URIReference)}
+	}
+	u, err := url.Parse(v)
+	if err != nil {
+		return &ValidationError{Field: "RetrieveURL", Value: v, Err: err}
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return &ValidationError{Field: "RetrieveURL", Value: v, Err: errors.New("not an absolute http or https URL")}
+	}
+	return nil
+}
+
+// CheckUIDs checks the syntax of the UIDs of the request.
+func (r WADORequest) CheckUIDs() error {
+	for _, f := range []struct{ name, value string }{
+		{"StudyInstanceUID", r.StudyInstanceUID},
+		{"SeriesInstanceUID", r.SeriesInstanceUID},
+		{"SOPInstanceUID", r.SOPInstanceUID},
+	} {
+		if f.value == "" {
+			continue
+		}
+		if err := checkUID(f.name, f.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteLevels number of UIDs each delete type requires.
+var deleteLevels = map[DeleteType]int{
+	DeleteStudy:    1,
+	DeleteSeries:   2,
+	DeleteInstance: 3,
+}
+
+// Validate checks the request, returning the rules it violates as
+// ValidationErrors, or nil: the UIDs the type requires, and only those, are
+// valid UIDs.
+func (r DeleteRequest) Validate() error {
+	errs := ValidationErrors{}
+	level, ok := deleteLevels[r.Type]
+	if !ok {
+		errs = append(errs, &ValidationError{Field: "Type", Value: strconv.Itoa(int(r.Type)), Err: errors.New("unknown delete type")})
+	} else {
+		errs = append(errs, levelErrors(r.Type, level, r.StudyInstanceUID, r.SeriesInstanceUID, r.SOPInstanceUID)...)
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// levelErrors checks that the UIDs up to the level of the request type are
+// set and valid, and that the others are not set.
+func levelErrors(typ fmt.Stringer, level int, study, series, sop string) []error {
+	errs := []error{}
+	for i, f := range []struct{ name, value string }{
+		{"StudyInstanceUID", study},
+		{"SeriesInstanceUID", series},
+		{"SOPInstanceUID", sop},
+	} {
+		switch {
+		case i < level && f.value == "":
+			errs = append(errs, &ValidationError{Field: f.name, Err: fmt.Errorf("required by %s", typ)})
+		case i >= level && f.value != "":
+			errs = append(errs, &ValidationError{Field: f.name, Value: f.value, Err: fmt.Errorf("not allowed by %s", typ)})
+		case f.value != "":
+			if err := checkUID(f.name, f.value); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errs
+}
+
+// Validate checks the request, returning the rules it violates as
+// ValidationErrors, or nil: the StudyInstanceUID is a valid UID if set, and
+// there is at least one part, none of which is empty.
+func (r STOWRequest) Validate() error {
+	errs := ValidationErrors{}
+	if r.StudyInstanceUID != "" {
+		if err := checkUID("StudyInstanceUID", r.StudyInstanceUID); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(r.Parts) == 0 {
+		errs = append(errs, &ValidationError{Field: "Parts", Err: errors.New("no part to store")})
+	}
+	for i, p := range r.Parts {
+		if len(p) == 0 {
+			errs = append(errs, &ValidationError{Field: fmt.Sprintf("Parts[%d]", i), Err: errors.New("empty part")})
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Validate checks the request, returning the rules it violates as
+// ValidationErrors, or nil: the WorkitemUID is set when the transaction
+// targets a workitem and is a valid UID, the workitem to create is SCHEDULED,
+// the state to change to is a state a workitem can change to, with the
+// transaction UID it is performed in once started, and a subscription names
+// its AE title.
+func (r UPSRequest) Validate() error {
+	errs := ValidationErrors{}
+	switch r.Type {
+	case CreateWorkitem, SubscribeWorklist, UnsubscribeWorklist:
+	case RetrieveWorkitem, UpdateWorkitem, ChangeWorkitemState, RequestWorkitemCancellation:
+		if r.WorkitemUID == "" {
+			errs = append(errs, &ValidationError{Field: "WorkitemUID", Err: fmt.Errorf("required by %s", r.Type)})
+		}
+	case SearchWorkitems, SuspendWorklistSubscription:
+		if r.WorkitemUID != "" {
+			errs = append(errs, &ValidationError{Field: "WorkitemUID", Value: r.WorkitemUID, Err: fmt.Errorf("not allowed by %s", r.Type)})
+		}
+	default:
+		errs = append(errs, &ValidationError{Field: "Type", Value: strconv.Itoa(int(r.Type)), Err: errors.New("unknown worklist transaction")})
+	}
+	if r.WorkitemUID != "" {
+		if err := checkUID("WorkitemUID", r.WorkitemUID); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if r.TransactionUID != "" {
+		if err := checkUID("TransactionUID", r.TransactionUID); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	switch r.Type {
+	case CreateWorkitem, UpdateWorkitem:
+		if r.Workitem == nil {
+			errs = append(errs, &ValidationError{Field: "Workitem", Err: fmt.Errorf("required by %s", r.Type)})
+			break
+		}
+		if r.Type == CreateWorkitem && r.Workitem.State != "" && r.Workitem.State != StateScheduled {
+			errs = append(errs, &ValidationError{Field: "Workitem.State", Value: string(r.Workitem.State), Err: errors.New("a workitem is created SCHEDULED")})
+		}
+		if r.Type == UpdateWorkitem && r.Workitem.State != "" {
+			errs = append(errs, &ValidationError{Field: "Workitem.State", Value: string(r.Workitem.State), Err: fmt.Errorf("not allowed by %s", r.Type)})
+		}
+	case ChangeWorkitemState:
+		switch r.State {
+		case StateInProgress:
+		case StateCompleted, StateCanceled:
+			if r.TransactionUID == "" {
+				errs = append(errs, &ValidationError{Field: "TransactionUID", Err: fmt.Errorf("required to change to %s", r.State)})
+			}
+		default:
+			errs = append(errs, &ValidationError{Field: "State", Value: string(r.State), Err: errors.New("not a state a workitem can change to")})
+		}
+	case SubscribeWorklist, UnsubscribeWorklist, SuspendWorklistSubscription:
+		if r.AETitle == "" {
+			errs = append(errs, &ValidationError{Field: "AETitle", Err: fmt.Errorf("required by %s", r.Type)})
+		} else if len(r.AETitle) > 16 {
+			errs = append(errs, &ValidationError{Field: "AETitle", Value: r.AETitle, Err: errors.New("longer than 16 characters")})
+		}
+	}
+	if r.Limit < 0 {
+		errs = append(errs, &ValidationError{Field: "Limit", Value: strconv.Itoa(r.Limit), Err: errors.New("negative")})
+	}
+	if r.Offset < 0 {
+		errs = append(errs, &ValidationError{Field: "Offset", Value: strconv.Itoa(r.Offset), Err: errors.New("negative")})
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// checkUID returns a ValidationError if the UID of the field is not valid.
+func checkUID(field, value string) error {
+	if err := uid.Check(value); err != nil {
+		return &ValidationError{Field: field, Value: value, Err: err}
+	}
+	return nil
+}
+
+// resourcePath returns the path of the study, series or instance of the given
+// UIDs, up to the first empty one. The UIDs are validated and escaped, so that
+// a malformed UID cannot target another resource.
+func resourcePath(study, series, sop string) (string, error) {
+	path := ""
+	for _, s := range []struct{ field, name, value string }{
+		{"StudyInstanceUID", "studies", study},
+		{"SeriesInstanceUID", "series", series},
+		{"SOPInstanceUID", "instances", sop},
+	} {
+		if s.value == "" {
+			break
+		}
+		if err := checkUID(s.field, s.value); err != nil {
+			return "", err
+		}
+		path += "/" + s.name + "/" + url.PathEscape(s.value)
+	}
+	return path, nil
+}
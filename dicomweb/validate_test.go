@@ -0,0 +1,117 @@
+package dicomweb
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQIDORequestCheckUIDs(t *testing.T) {
+	assert.NoError(t, QIDORequest{Type: Study}.CheckUIDs())
+	assert.NoError(t, QIDORequest{Type: Series, StudyInstanceUID: "1.2.3,4.5.6"}.CheckUIDs())
+	assert.EqualError(t, QIDORequest{Type: Instance, StudyInstanceUID: "1.2.3", SeriesInstanceUID: "1.2.3/../4"}.CheckUIDs(),
+		`invalid SeriesInstanceUID "1.2.3/../4": invalid character '/' in UID`)
+}
+
+func TestWADORequestCheckUIDs(t *testing.T) {
+	assert.NoError(t, WADORequest{Type: SeriesRaw, StudyInstanceUID: "1.2.3", SeriesInstanceUID: "1.2.3.4"}.CheckUIDs())
+	assert.EqualError(t, WADORequest{Type: StudyRaw, StudyInstanceUID: "1.02.3"}.CheckUIDs(),
+		`invalid StudyInstanceUID "1.02.3": UID component 02 has a leading zero`)
+	assert.EqualError(t, WADORequest{Type: InstanceRaw, StudyInstanceUID: "1.2", SeriesInstanceUID: "1.2.3", SOPInstanceUID: "1.2.3."}.CheckUIDs(),
+		`invalid SOPInstanceUID "1.2.3.": empty UID component`)
+}
+
+func TestURLValidation(t *testing.T) {
+	paths := []string{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.String())
+	}))
+	defer ts.Close()
+	c := NewClient(ClientOption{QIDOEndpoint: ts.URL, WADOEndpoint: ts.URL, STOWEndpoint: ts.URL})
+
+	_, err := c.Query(QIDORequest{Type: Instance, StudyInstanceUID: "1.2.3/../../4"})
+	if assert.IsType(t, ValidationErrors{}, err) {
+		assert.Equal(t, "StudyInstanceUID", err.(ValidationErrors)[0].(*ValidationError).Field)
+	}
+	_, err = c.Query(QIDORequest{Type: Series, StudyInstanceUID: "1.2.3,1.2.4"})
+	assert.EqualError(t, err, `invalid StudyInstanceUID "1.2.3,1.2.4": invalid character ',' in UID`)
+	_, err = c.Retrieve(WADORequest{Type: SeriesRaw, StudyInstanceUID: "1.2.3", SeriesInstanceUID: "4?x=1"})
+	assert.EqualError(t, err, `invalid SeriesInstanceUID "4?x=1": invalid character '?' in UID`)
+	_, err = c.Store(STOWRequest{StudyInstanceUID: "..", Parts: [][]byte{[]byte("part: 0")}})
+	assert.EqualError(t, err, `invalid StudyInstanceUID "..": empty UID component`)
+	assert.Empty(t, paths)
+
+	// only the UIDs in the path are not sent as query parameters.
+	c.Query(QIDORequest{Type: Study, StudyInstanceUID: "1.2.3,1.2.4", PatientID: "PAT-1"})
+	c.Query(QIDORequest{Type: Series, StudyInstanceUID: "1.2.3", SeriesInstanceUID: "1.2.3.4", SeriesDate: "20200101"})
+	c.Query(QIDORequest{Type: Series})
+	c.Query(QIDORequest{Type: Instance, StudyInstanceUID: "1.2.3", SeriesInstanceUID: "1.2.3.4", SOPInstanceUID: "1.2.3.4.5"})
+	c.Retrieve(WADORequest{Type: Frame, StudyInstanceUID: "1.2.3", SeriesInstanceUID: "1.2.3.4", SOPInstanceUID: "1.2.3.4.5", FrameID: 2})
+	assert.Equal(t, []string{
+		"/studies?00100020=PAT-1&0020000D=1.2.3%2C1.2.4",
+		"/studies/1.2.3/series?00080021=20200101&0020000E=1.2.3.4",
+		"/series",
+		"/studies/1.2.3/series/1.2.3.4/instances?00080018=1.2.3.4.5",
+		"/studies/1.2.3/series/1.2.3.4/instances/1.2.3.4.5/frames/2",
+	}, paths)
+}
+
+func TestQIDORequestValidate(t *testing.T) {
+	assert.NoError(t, QIDORequest{Type: Study, StudyDate: "20200101-20200131", StudyTime: "0800-", Limit: 10}.Validate())
+	assert.NoError(t, QIDORequest{Type: Series, SeriesDate: "-20200131", SeriesTime: "101010.123"}.Validate())
+
+	err := QIDORequest{
+		StudyInstanceUID: "1.2.3,1.2.x",
+		StudyDate:        "2020-01-01",
+		SeriesTime:       "-",
+		Limit:            -1,
+	}.Validate()
+	if assert.IsType(t, ValidationErrors{}, err) {
+		fields := []string{}
+		for _, e := range err.(ValidationErrors) {
+			fields = append(fields, e.(*ValidationError).Field)
+		}
+		assert.Equal(t, []string{"Type", "StudyInstanceUID", "StudyDate", "SeriesTime", "Limit"}, fields)
+	}
+	assert.EqualError(t, err, `invalid Type "0": unknown query type; `+
+		`invalid StudyInstanceUID "1.2.x": invalid character 'x' in UID; `+
+		`invalid StudyDate "2020-01-01": not a value nor a range; `+
+		`invalid SeriesTime "-": not a value nor a range; `+
+		`invalid Limit "-1": negative`)
+}
+
+func TestWADORequestValidate(t *testing.T) {
+	assert.NoError(t, WADORequest{Type: StudyRaw, StudyInstanceUID: "1.2.3"}.Validate())
+	assert.NoError(t, WADORequest{Type: Frame, StudyInstanceUID: "1.2.3", SeriesInstanceUID: "1.2.3.4", SOPInstanceUID: "1.2.3.4.5", FrameID: 1}.Validate())
+	assert.NoError(t, WADORequest{Type: URIReference, RetrieveURL: "https://example.com/studies/1.2.3"}.Validate())
+
+	assert.EqualError(t, WADORequest{Type: StudyRaw, StudyInstanceUID: "1.2.3", SOPInstanceUID: "1.2.3.4.5"}.Validate(),
+		`invalid SOPInstanceUID "1.2.3.4.5": not allowed by StudyRaw`)
+	assert.EqualError(t, WADORequest{Type: SeriesMetadata, StudyInstanceUID: "1.2.3", SeriesInstanceUID: "1.02"}.Validate(),
+		`invalid SeriesInstanceUID "1.02": UID component 02 has a leading zero`)
+	assert.EqualError(t, WADORequest{Type: Frame, StudyInstanceUID: "1.2.3", SeriesInstanceUID: "1.2.3.4", FrameID: -2}.Validate(),
+		`invalid SOPInstanceUID: required by Frame; invalid FrameID "-2": frame numbers start at 1`)
+	assert.EqualError(t, WADORequest{Type: InstanceRendered, StudyInstanceUID: "1.2.3", SeriesInstanceUID: "1.2.3.4", SOPInstanceUID: "1.2.3.4.5", Quality: 101}.Validate(),
+		`invalid Quality "101": not between 1 and 100`)
+
+	assert.EqualError(t, WADORequest{Type: URIReference}.Validate(), "invalid RetrieveURL: required by URIReference")
+	assert.EqualError(t, WADORequest{Type: URIReference, RetrieveURL: "/studies/1.2.3"}.Validate(),
+		`invalid RetrieveURL "/studies/1.2.3": not an absolute http or https URL`)
+	assert.EqualError(t, WADORequest{Type: URIReference, RetrieveURL: "ftp://example.com/x"}.Validate(),
+		`invalid RetrieveURL "ftp://example.com/x": not an absolute http or https URL`)
+	err := WADORequest{Type: URIReference, RetrieveURL: "http://[::1"}.Validate()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), `invalid RetrieveURL "http://[::1": parse`)
+	}
+}
+
+func TestSTOWRequestValidate(t *testing.T) {
+	assert.NoError(t, STOWRequest{Parts: [][]byte{[]byte("part")}}.Validate())
+	assert.NoError(t, STOWRequest{StudyInstanceUID: "1.2.3", Parts: [][]byte{[]byte("part")}}.Validate())
+
+	assert.EqualError(t, STOWRequest{}.Validate(), "invalid Parts: no part to store")
+	assert.EqualError(t, STOWRequest{StudyInstanceUID: "1.2/3", Parts: [][]byte{[]byte("part"), nil}}.Validate(),
+		`invalid StudyInstanceUID "1.2/3": invalid character '/' in UID; invalid Parts[1]: empty part`)
+}
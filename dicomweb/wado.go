@@ -13,33 +13,12 @@ type WADORequest struct {
 	Quality           int
 	Viewport          string
 	Window            string
-}
-
-// Validate validates if the request is valid.
-func (r WADORequest) Validate() bool {
-	switch r.Type {
-	case StudyRaw:
-		return r.StudyInstanceUID != "" && r.SeriesInstanceUID == "" && r.SOPInstanceUID == ""
-	case StudyRendered:
-		return r.StudyInstanceUID != "" && r.SeriesInstanceUID == "" && r.SOPInstanceUID == ""
-	case SeriesRaw:
-		return r.StudyInstanceUID != "" && r.SeriesInstanceUID != "" && r.SOPInstanceUID == ""
-	case SeriesRendered:
-		return r.StudyInstanceUID != "" && r.SeriesInstanceUID != "" && r.SOPInstanceUID == ""
-	case SeriesMetadata:
-		return r.StudyInstanceUID != "" && r.SeriesInstanceUID != "" && r.SOPInstanceUID == ""
-	case InstanceRaw:
-		return r.StudyInstanceUID != "" && r.SeriesInstanceUID != "" && r.SOPInstanceUID != ""
-	case InstanceRendered:
-		return r.StudyInstanceUID != "" && r.SeriesInstanceUID != "" && r.SOPInstanceUID != ""
-	case InstanceMetadata:
-		return r.StudyInstanceUID != "" && r.SeriesInstanceUID != "" && r.SOPInstanceUID != ""
-	case Frame:
-		return r.StudyInstanceUID != "" && r.SeriesInstanceUID != "" && r.SOPInstanceUID != "" && r.FrameID != 0
-	case URIReference:
-		return r.RetrieveURL != ""
-	}
-	return false
+	// Progress is called as the response is received, if set
+	Progress ProgressFunc
+	// ExpectedParts number of parts the response is expected to have, such as
+	// the RelatedInstances of a QIDO-RS search result. Unknown otherwise, but
+	// for the instance and frame retrievals
+	ExpectedParts int
 }
 
 // WADOType defines the object to query.
@@ -67,3 +46,43 @@ const (
 	// URIReference URI reference.
 	URIReference
 )
+
+// String returns the name of the retrieve type.
+func (t WADOType) String() string {
+	switch t {
+	case StudyRaw:
+		return "StudyRaw"
+	case StudyRendered:
+		return "StudyRendered"
+	case SeriesRaw:
+		return "SeriesRaw"
+	case SeriesRendered:
+		return "SeriesRendered"
+	case SeriesMetadata:
+		return "SeriesMetadata"
+	case InstanceRaw:
+		return "InstanceRaw"
+	case InstanceRendered:
+		return "InstanceRendered"
+	case InstanceMetadata:
+		return "InstanceMetadata"
+	case Frame:
+		return "Frame"
+	case URIReference:
+		return "URIReference"
+	}
+	return "unknown"
+}
+
+// expectedParts returns the number of parts the response to the request is
+// expected to have, or -1 if unknown.
+func (r WADORequest) expectedParts() int {
+	if r.ExpectedParts > 0 {
+		return r.ExpectedParts
+	}
+	switch r.Type {
+	case InstanceRaw, InstanceMetadata, InstanceRendered, Frame:
+		return 1
+	}
+	return -1
+}
@@ -0,0 +1,140 @@
+package dicomweb
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/toastcheng/dicomweb-go/dicomweb/dicom"
+)
+
+// ProcedureStepState defines the state of a workitem.
+type ProcedureStepState string
+
+const (
+	// StateScheduled the workitem is scheduled, its initial state.
+	StateScheduled ProcedureStepState = "SCHEDULED"
+	// StateInProgress the workitem is being performed.
+	StateInProgress ProcedureStepState = "IN PROGRESS"
+	// StateCompleted the workitem was performed, a final state.
+	StateCompleted ProcedureStepState = "COMPLETED"
+	// StateCanceled the workitem was canceled, a final state.
+	StateCanceled ProcedureStepState = "CANCELED"
+)
+
+// Transition returns an error if a workitem in the state cannot change to the
+// given state: a SCHEDULED workitem can only be started, becoming IN PROGRESS,
+// and an IN PROGRESS one can only be COMPLETED or CANCELED. A SCHEDULED
+// workitem is canceled by a cancellation request instead.
+func (s ProcedureStepState) Transition(to ProcedureStepState) error {
+	switch {
+	case s == StateScheduled && to == StateInProgress:
+		return nil
+	case s == StateInProgress && (to == StateCompleted || to == StateCanceled):
+		return nil
+	}
+	return fmt.Errorf("workitem cannot change from %s to %s", s, to)
+}
+
+// Tags of the workitem attributes with a field in Workitem.
+const (
+	tagSOPInstanceUID                      = "00080018"
+	tagPatientName                         = "00100010"
+	tagPatientID                           = "00100020"
+	tagScheduledProcedureStepStartDateTime = "00404005"
+	tagInputReadinessState                 = "00404041"
+	tagProcedureStepState                  = "00741000"
+	tagScheduledProcedureStepPriority      = "00741200"
+	tagWorklistLabel                       = "00741202"
+	tagProcedureStepLabel                  = "00741204"
+	tagTransactionUID                      = "00081195"
+	tagReasonForCancellation               = "00741238"
+)
+
+// Workitem defines a Unified Procedure Step workitem of the UPS-RS worklist
+// service. The attributes without a field are kept in Attributes, so that a
+// workitem retrieved from the server is encoded back unchanged.
+type Workitem struct {
+	// SOPInstanceUID UID of the workitem.
+	SOPInstanceUID string
+	// State state of the workitem.
+	State ProcedureStepState
+	// Priority scheduled priority, HIGH, MEDIUM or LOW.
+	Priority string
+	// Label label of the procedure step.
+	Label string
+	// WorklistLabel label of the worklist the workitem belongs to.
+	WorklistLabel string
+	// ScheduledStartDateTime date and time the procedure step is scheduled to start at.
+	ScheduledStartDateTime string
+	// InputReadinessState readiness of the input instances, READY, UNAVAILABLE or INCOMPLETE.
+	InputReadinessState string
+	// PatientName name of the patient.
+	PatientName string
+	// PatientID ID of the patient.
+	PatientID string
+	// TransactionUID transaction the workitem is performed in, known to its performer only. It is not encoded.
+	TransactionUID string
+	// Attributes other attributes of the workitem, keyed by tag.
+	Attributes map[string]Tag
+}
+
+// MarshalJSON encodes the workitem in the DICOM JSON model.
+func (w Workitem) MarshalJSON() ([]byte, error) {
+	m := map[string]dicom.Attribute{}
+	for k, t := range w.Attributes {
+		m[k] = dicom.Attribute{VR: t.VR, Value: t.Value}
+	}
+	for _, f := range []struct{ tag, vr, value string }{
+		{tagSOPInstanceUID, "UI", w.SOPInstanceUID},
+		{tagProcedureStepState, "CS", string(w.State)},
+		{tagScheduledProcedureStepPriority, "CS", w.Priority},
+		{tagProcedureStepLabel, "LO", w.Label},
+		{tagWorklistLabel, "LO", w.WorklistLabel},
+		{tagScheduledProcedureStepStartDateTime, "DT", w.ScheduledStartDateTime},
+		{tagInputReadinessState, "CS", w.InputReadinessState},
+		{tagPatientID, "LO", w.PatientID},
+	} {
+		if f.value != "" {
+			m[f.tag] = dicom.Attribute{VR: f.vr, Value: []interface{}{f.value}}
+		}
+	}
+	if w.PatientName != "" {
+		m[tagPatientName] = dicom.Attribute{VR: "PN", Value: []interface{}{map[string]string{"Alphabetic": w.PatientName}}}
+	}
+	return json.Marshal(m)
+}
+
+// UnmarshalJSON decodes the workitem from the DICOM JSON model.
+func (w *Workitem) UnmarshalJSON(b []byte) error {
+	attrs := map[string]Tag{}
+	if err := json.Unmarshal(b, &attrs); err != nil {
+		return err
+	}
+	*w = Workitem{}
+	for _, f := range []struct {
+		tag   string
+		value *string
+	}{
+		{tagSOPInstanceUID, &w.SOPInstanceUID},
+		{tagScheduledProcedureStepPriority, &w.Priority},
+		{tagProcedureStepLabel, &w.Label},
+		{tagWorklistLabel, &w.WorklistLabel},
+		{tagScheduledProcedureStepStartDateTime, &w.ScheduledStartDateTime},
+		{tagInputReadinessState, &w.InputReadinessState},
+		{tagPatientName, &w.PatientName},
+		{tagPatientID, &w.PatientID},
+	} {
+		if t, ok := attrs[f.tag]; ok && len(t.Value) > 0 {
+			*f.value = tagString(t.Value[0])
+		}
+		delete(attrs, f.tag)
+	}
+	if t, ok := attrs[tagProcedureStepState]; ok && len(t.Value) > 0 {
+		w.State = ProcedureStepState(tagString(t.Value[0]))
+	}
+	delete(attrs, tagProcedureStepState)
+	if len(attrs) > 0 {
+		w.Attributes = attrs
+	}
+	return nil
+}
@@ -3,6 +3,8 @@ module github.com/toastcheng/dicomweb-go
 go 1.12
 
 require (
+	github.com/gorilla/websocket v1.4.2
 	github.com/philippfranke/multipart-related v0.0.0-20170217130855-01d28b2a1769
 	github.com/stretchr/testify v1.6.1
+	golang.org/x/crypto v0.0.0-20200622213623-75b288015ac9
 )